@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sht/ed-journal/query"
+	"github.com/sht/ed-journal/store"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 1000
+)
+
+// eventPage is a page of recent events, newest first. Next is passed as the
+// before parameter to get the following page
+type eventPage struct {
+	Events []json.RawMessage `json:"events"`
+	Next   uint64            `json:"next,omitempty"`
+}
+
+// recentEvents answers GET /events with the stored events, newest first.
+// Parameters:
+//
+//	event      comma separated event names
+//	commander  commander name
+//	from, to   RFC 3339 time range, to excluded
+//	filter     filter expression, see query.Parse
+//	limit      page size, 50 by default
+//	before     only events stored before this ID, from a previous page
+func (s *Server) recentEvents(w http.ResponseWriter, r *http.Request) {
+	if s.events == nil {
+		writeError(w, http.StatusNotFound, "events are not stored")
+		return
+	}
+
+	q, expr, before, err := parseEventQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit := q.Limit
+	q.Limit = 0
+
+	// keep the last matches in a ring, the store iterates oldest first
+	ring := make([]store.Record, 0, limit)
+	next := 0
+	more := false
+	err = s.events.Iterate(q, func(rec store.Record) error {
+		if before > 0 && rec.ID >= before {
+			return store.ErrStop
+		}
+		if expr != nil {
+			ok, err := expr.Match(rec.Data)
+			if err != nil || !ok {
+				return nil
+			}
+		}
+		if len(ring) < limit {
+			ring = append(ring, rec)
+			return nil
+		}
+		more = true
+		ring[next] = rec
+		next = (next + 1) % limit
+		return nil
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	page := eventPage{Events: make([]json.RawMessage, 0, len(ring))}
+	for i := len(ring) - 1; i >= 0; i-- {
+		page.Events = append(page.Events, ring[(next+i)%len(ring)].Data)
+	}
+	if more {
+		page.Next = ring[next].ID
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func parseEventQuery(v url.Values) (store.Query, *query.Expr, uint64, error) {
+	q := store.Query{
+		Commander: v.Get("commander"),
+		Limit:     defaultLimit,
+	}
+	for _, name := range strings.Split(v.Get("event"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			q.Events = append(q.Events, name)
+		}
+	}
+
+	var err error
+	if from := v.Get("from"); from != "" {
+		q.From, err = time.Parse(time.RFC3339, from)
+		if err != nil {
+			return q, nil, 0, fmt.Errorf("invalid from: %w", err)
+		}
+	}
+	if to := v.Get("to"); to != "" {
+		q.To, err = time.Parse(time.RFC3339, to)
+		if err != nil {
+			return q, nil, 0, fmt.Errorf("invalid to: %w", err)
+		}
+	}
+	if limit := v.Get("limit"); limit != "" {
+		q.Limit, err = strconv.Atoi(limit)
+		if err != nil || q.Limit < 1 || q.Limit > maxLimit {
+			return q, nil, 0, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+		}
+	}
+
+	var before uint64
+	if b := v.Get("before"); b != "" {
+		before, err = strconv.ParseUint(b, 10, 64)
+		if err != nil {
+			return q, nil, 0, fmt.Errorf("invalid before: %w", err)
+		}
+	}
+
+	var expr *query.Expr
+	if filter := v.Get("filter"); filter != "" {
+		expr, err = query.Parse(filter)
+		if err != nil {
+			return q, nil, 0, err
+		}
+	}
+	return q, expr, before, nil
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/event"
+	"github.com/sht/ed-journal/session"
+	"github.com/sht/ed-journal/state"
+	"github.com/sht/ed-journal/store"
+)
+
+// Version is reported by the version endpoint. It is set at build time with
+// -ldflags "-X github.com/sht/ed-journal/api.Version=..."
+var Version = "dev"
+
+// DefaultAddr only accepts connections from the local machine
+const DefaultAddr = "127.0.0.1:8080"
+
+// Server serves the aggregated journal data as JSON over HTTP. Any of the
+// sources may be nil, in which case their endpoints answer 404
+type Server struct {
+	mu        sync.RWMutex
+	state     *state.Tracker
+	sessions  *session.Tracker
+	events    store.Store
+	mux       *http.ServeMux
+	started   time.Time
+	count     int
+	lastEvent event.Event
+}
+
+// NewServer returns a server for the given sources
+func NewServer(st *state.Tracker, sessions *session.Tracker, events store.Store) *Server {
+	s := &Server{
+		state:    st,
+		sessions: sessions,
+		events:   events,
+		mux:      http.NewServeMux(),
+		started:  time.Now(),
+	}
+
+	s.mux.HandleFunc("/", notFound)
+	s.mux.HandleFunc("/health", s.health)
+	s.mux.HandleFunc("/version", s.version)
+	s.mux.HandleFunc("/state", s.commanderState)
+	s.mux.HandleFunc("/state/location", s.location)
+	s.mux.HandleFunc("/state/ship", s.ship)
+	s.mux.HandleFunc("/events", s.recentEvents)
+	s.mux.HandleFunc("/sessions", s.allSessions)
+	s.mux.HandleFunc("/sessions/current", s.currentSession)
+	return s
+}
+
+// AddListeners subscribes the server to every event, which it uses to report
+// how far the journal has been read
+func (s *Server) AddListeners(d *dispatcher.Dispatcher) {
+	d.OnAllSync(s.seen)
+}
+
+// Handle adds a handler to the server, for endpoints served by other packages
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.mux.Handle(pattern, h)
+}
+
+// ServeHTTP implements the http.Handler interface. Requests for any host but
+// the local machine are refused, so that a web page cannot read the API by
+// pointing its own host name at a loopback address
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !localHost(r.Host) {
+		writeError(w, http.StatusForbidden, "host not allowed")
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe serves on addr until the server fails. Use a loopback
+// address such as DefaultAddr, the API has no authentication
+func (s *Server) ListenAndServe(addr string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return srv.ListenAndServe()
+}
+
+func (s *Server) seen(b []byte) {
+	var e event.Event
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.count++
+	s.lastEvent = e
+}
+
+// localHost reports whether the Host header of a request names the local
+// machine
+func localHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func notFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, "not found")
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	h := struct {
+		Status    string        `json:"status"`
+		Uptime    time.Duration `json:"uptime"`
+		Events    int           `json:"events"`
+		LastEvent *event.Event  `json:"lastEvent,omitempty"`
+	}{
+		Status: "ok",
+		Uptime: time.Since(s.started),
+		Events: s.count,
+	}
+	if s.count > 0 {
+		e := s.lastEvent
+		h.LastEvent = &e
+	}
+	writeJSON(w, http.StatusOK, h)
+}
+
+func (s *Server) version(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"version": Version,
+		"go":      runtime.Version(),
+	})
+}
+
+func (s *Server) commanderState(w http.ResponseWriter, r *http.Request) {
+	if s.state == nil {
+		writeError(w, http.StatusNotFound, "state is not tracked")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.state.State())
+}
+
+func (s *Server) location(w http.ResponseWriter, r *http.Request) {
+	if s.state == nil {
+		writeError(w, http.StatusNotFound, "state is not tracked")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.state.State().Location)
+}
+
+func (s *Server) ship(w http.ResponseWriter, r *http.Request) {
+	if s.state == nil {
+		writeError(w, http.StatusNotFound, "state is not tracked")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.state.State().Ship)
+}
+
+func (s *Server) allSessions(w http.ResponseWriter, r *http.Request) {
+	if s.sessions == nil {
+		writeError(w, http.StatusNotFound, "sessions are not tracked")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.sessions.Sessions())
+}
+
+func (s *Server) currentSession(w http.ResponseWriter, r *http.Request) {
+	if s.sessions == nil {
+		writeError(w, http.StatusNotFound, "sessions are not tracked")
+		return
+	}
+	cur, ok := s.sessions.Current()
+	if !ok {
+		writeError(w, http.StatusNotFound, "no session in progress")
+		return
+	}
+	writeJSON(w, http.StatusOK, cur)
+}
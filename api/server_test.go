@@ -0,0 +1,170 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sht/ed-journal/store"
+)
+
+// newTestServer returns a server over a store holding events 1 to n, which
+// alternate between Even and Odd events
+func newTestServer(t *testing.T, n int) (*Server, []uint64) {
+	t.Helper()
+
+	s, err := store.OpenFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	ids := make([]uint64, 0, n)
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= n; i++ {
+		name := "Odd"
+		if i%2 == 0 {
+			name = "Even"
+		}
+		ts := start.Add(time.Duration(i) * time.Minute).Format(time.RFC3339)
+		r, err := store.NewRecord("Bob", []byte(fmt.Sprintf(`{"timestamp":"%s","event":"%s","N":%d}`, ts, name, i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = s.Append(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, r.ID)
+	}
+	return NewServer(nil, nil, s), ids
+}
+
+// get serves a GET request for the local machine
+func get(srv *Server, target string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	r.Host = "localhost:8080"
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	return w
+}
+
+// page gets a page of events, returning the N of each and the next cursor
+func page(t *testing.T, srv *Server, target string) ([]int, uint64) {
+	t.Helper()
+
+	w := get(srv, target)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: %d %s", target, w.Code, w.Body)
+	}
+	var p struct {
+		Events []struct{ N int }
+		Next   uint64
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := make([]int, 0, len(p.Events))
+	for _, e := range p.Events {
+		ns = append(ns, e.N)
+	}
+	return ns, p.Next
+}
+
+func TestRecentEventsPages(t *testing.T) {
+	srv, ids := newTestServer(t, 7)
+
+	tests := []struct {
+		target string
+		want   []int
+		next   uint64
+	}{
+		{"/events", []int{7, 6, 5, 4, 3, 2, 1}, 0},
+		{"/events?limit=3", []int{7, 6, 5}, ids[4]},
+		{fmt.Sprintf("/events?limit=3&before=%d", ids[4]), []int{4, 3, 2}, ids[1]},
+		{fmt.Sprintf("/events?limit=3&before=%d", ids[1]), []int{1}, 0},
+		// a page that fills the ring exactly has nothing after it
+		{"/events?limit=7", []int{7, 6, 5, 4, 3, 2, 1}, 0},
+		{"/events?limit=2&event=Even", []int{6, 4}, ids[3]},
+		{fmt.Sprintf("/events?limit=2&event=Even&before=%d", ids[3]), []int{2}, 0},
+		{"/events?limit=2&filter=N+%3C+6", []int{5, 4}, ids[3]},
+		{"/events?from=2021-01-01T00:03:00Z&to=2021-01-01T00:05:00Z", []int{4, 3}, 0},
+	}
+	for _, tt := range tests {
+		got, next := page(t, srv, tt.target)
+		if !reflect.DeepEqual(got, tt.want) || next != tt.next {
+			t.Errorf("GET %s: events %v next %d, want %v next %d", tt.target, got, next, tt.want, tt.next)
+		}
+	}
+}
+
+func TestRecentEventsBadQuery(t *testing.T) {
+	srv, _ := newTestServer(t, 1)
+
+	for _, target := range []string{
+		"/events?limit=0",
+		"/events?limit=1001",
+		"/events?before=last",
+		"/events?from=yesterday",
+		"/events?filter=N+%3E",
+	} {
+		if w := get(srv, target); w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: %d, want 400", target, w.Code)
+		}
+	}
+}
+
+func TestLocalHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"localhost", true},
+		{"LOCALHOST:8080", true},
+		{"127.0.0.1:8080", true},
+		{"127.1.2.3", true},
+		{"[::1]:8080", true},
+		{"::1", true},
+		{"", false},
+		{"0.0.0.0:8080", false},
+		{"192.168.1.10:8080", false},
+		{"example.com", false},
+		{"localhost.example.com", false},
+	}
+	for _, tt := range tests {
+		if got := localHost(tt.host); got != tt.want {
+			t.Errorf("localHost(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestServeHTTPRefusals(t *testing.T) {
+	srv, _ := newTestServer(t, 1)
+
+	// a page rebinding its own name to a loopback address
+	r := httptest.NewRequest(http.MethodGet, "/events", nil)
+	r.Host = "attacker.example:8080"
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("foreign host: %d, want 403", w.Code)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/events", nil)
+	r.Host = "127.0.0.1:8080"
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, HEAD" {
+		t.Errorf("POST: %d, Allow %q, want 405", w.Code, w.Header().Get("Allow"))
+	}
+
+	// sources the server was not given answer 404
+	if w := get(srv, "/state"); w.Code != http.StatusNotFound {
+		t.Errorf("GET /state without a tracker: %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,479 @@
+package bgs
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/event"
+	"github.com/sht/ed-journal/events"
+)
+
+const (
+	FactionStateChanged = "FactionStateChanged"
+)
+
+// Phase is the stage of a faction state
+type Phase string
+
+const (
+	None       Phase = ""
+	Pending    Phase = "pending"
+	Active     Phase = "active"
+	Recovering Phase = "recovering"
+)
+
+// Sample is the influence and states of a faction in a system at the time
+// the commander arrived there
+type Sample struct {
+	Timestamp    time.Time `json:"timestamp"`
+	Influence    float64   `json:"influence"`
+	FactionState string    `json:"factionState"`
+	Happiness    string    `json:"happiness,omitempty"`
+	MyReputation float64   `json:"myReputation"`
+	Pending      []string  `json:"pending"`
+	Active       []string  `json:"active"`
+	Recovering   []string  `json:"recovering"`
+}
+
+// Transition is a faction state moving from one phase to another between two
+// samples. A state appearing has From None, one going away has To None
+type Transition struct {
+	Timestamp     time.Time `json:"timestamp"`
+	SystemAddress int       `json:"systemAddress"`
+	StarSystem    string    `json:"starSystem"`
+	Faction       string    `json:"faction"`
+	State         string    `json:"state"`
+	From          Phase     `json:"from"`
+	To            Phase     `json:"to"`
+}
+
+// Contribution is the influence effect of a completed mission on a faction in
+// a system, e.g. "+++"
+type Contribution struct {
+	Timestamp     time.Time `json:"timestamp"`
+	SystemAddress int       `json:"systemAddress"`
+	Faction       string    `json:"faction"`
+	MissionID     int       `json:"missionId"`
+	Mission       string    `json:"mission"`
+	Influence     string    `json:"influence"`
+	Trend         string    `json:"trend"`
+}
+
+// StateChangedEvent is triggered on the dispatcher for every transition
+type StateChangedEvent struct {
+	event.Event
+	StarSystem    string `json:"StarSystem"`
+	SystemAddress int    `json:"SystemAddress"`
+	Faction       string `json:"Faction"`
+	State         string `json:"State"`
+	From          Phase  `json:"From"`
+	To            Phase  `json:"To"`
+}
+
+type faction struct {
+	name          string
+	samples       []Sample
+	transitions   []Transition
+	contributions []Contribution
+}
+
+type system struct {
+	name     string
+	factions map[string]*faction
+}
+
+// History records the influence and states of the factions in every system
+// the commander arrives in. It is safe for concurrent use
+type History struct {
+	mu      sync.RWMutex
+	d       *dispatcher.Dispatcher
+	systems map[int]*system
+}
+
+func NewHistory() *History {
+	return &History{
+		systems: make(map[int]*system),
+	}
+}
+
+const SnapshotVersion = 1
+
+type savedFaction struct {
+	Name          string         `json:"name"`
+	Samples       []Sample       `json:"samples"`
+	Transitions   []Transition   `json:"transitions"`
+	Contributions []Contribution `json:"contributions"`
+}
+
+type savedSystem struct {
+	Name     string         `json:"name"`
+	Factions []savedFaction `json:"factions"`
+}
+
+// Snapshot saves the samples, transitions and contributions of every faction
+func (h *History) Snapshot() (json.RawMessage, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	systems := make(map[int]savedSystem, len(h.systems))
+	for address, sys := range h.systems {
+		s := savedSystem{Name: sys.name, Factions: make([]savedFaction, 0, len(sys.factions))}
+		for _, f := range sys.factions {
+			s.Factions = append(s.Factions, savedFaction{f.name, f.samples, f.transitions, f.contributions})
+		}
+		systems[address] = s
+	}
+	return json.Marshal(systems)
+}
+
+func (h *History) Restore(b json.RawMessage) error {
+	var systems map[int]savedSystem
+	err := json.Unmarshal(b, &systems)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.systems = make(map[int]*system, len(systems))
+	for address, s := range systems {
+		sys := &system{name: s.Name, factions: make(map[string]*faction, len(s.Factions))}
+		for _, f := range s.Factions {
+			sys.factions[strings.ToLower(f.Name)] = &faction{f.Name, f.Samples, f.Transitions, f.Contributions}
+		}
+		h.systems[address] = sys
+	}
+	return nil
+}
+
+// AddListeners subscribes the history to the arrival and mission events.
+// FactionStateChanged events are triggered on the same dispatcher
+func (h *History) AddListeners(d *dispatcher.Dispatcher) {
+	h.mu.Lock()
+	h.d = d
+	h.mu.Unlock()
+
+	// travel
+	{
+		d.OnSync(events.FSDJump, h.fsdJump)
+		d.OnSync(events.Location, h.location)
+	}
+
+	// station services
+	{
+		d.OnSync(events.MissionCompleted, h.missionCompleted)
+	}
+}
+
+// Systems returns the names of the systems with recorded factions by address
+func (h *History) Systems() map[int]string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	systems := make(map[int]string, len(h.systems))
+	for address, s := range h.systems {
+		systems[address] = s.name
+	}
+	return systems
+}
+
+// Factions returns the names of the factions recorded in a system, sorted
+func (h *History) Factions(address int) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	names := make([]string, 0)
+	s, ok := h.systems[address]
+	if !ok {
+		return names
+	}
+	for _, f := range s.factions {
+		names = append(names, f.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Samples returns the samples of a faction in a system in the given time
+// range, oldest first. A zero from or to leaves that side of the range open
+func (h *History) Samples(address int, name string, from, to time.Time) []Sample {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	samples := make([]Sample, 0)
+	f := h.faction(address, name)
+	if f == nil {
+		return samples
+	}
+	for _, s := range f.samples {
+		if inRange(s.Timestamp, from, to) {
+			samples = append(samples, s.copy())
+		}
+	}
+	return samples
+}
+
+// Transitions returns the state transitions of a faction in a system in the
+// given time range, oldest first
+func (h *History) Transitions(address int, name string, from, to time.Time) []Transition {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	transitions := make([]Transition, 0)
+	f := h.faction(address, name)
+	if f == nil {
+		return transitions
+	}
+	for _, t := range f.transitions {
+		if inRange(t.Timestamp, from, to) {
+			transitions = append(transitions, t)
+		}
+	}
+	return transitions
+}
+
+// Contributions returns the mission effects on a faction in a system in the
+// given time range, oldest first
+func (h *History) Contributions(address int, name string, from, to time.Time) []Contribution {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	contributions := make([]Contribution, 0)
+	f := h.faction(address, name)
+	if f == nil {
+		return contributions
+	}
+	for _, c := range f.contributions {
+		if inRange(c.Timestamp, from, to) {
+			contributions = append(contributions, c)
+		}
+	}
+	return contributions
+}
+
+// faction returns the recorded faction or nil. Must be called with the lock
+// held
+func (h *History) faction(address int, name string) *faction {
+	s, ok := h.systems[address]
+	if !ok {
+		return nil
+	}
+	return s.factions[strings.ToLower(name)]
+}
+
+func inRange(t, from, to time.Time) bool {
+	if !from.IsZero() && t.Before(from) {
+		return false
+	}
+	if !to.IsZero() && !t.Before(to) {
+		return false
+	}
+	return true
+}
+
+func (h *History) fsdJump(b []byte) {
+	var e events.FSDJumpEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	h.record(e.Timestamp, e.SystemAddress, e.StarSystem, e.Factions)
+}
+
+func (h *History) location(b []byte) {
+	var e events.LocationEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	h.record(e.Timestamp, e.SystemAddress, e.StarSystem, e.Factions)
+}
+
+func (h *History) missionCompleted(b []byte) {
+	var e events.MissionCompletedEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, fe := range e.FactionEffects {
+		for _, inf := range fe.Influence {
+			s := h.system(inf.SystemAddress, "")
+			f := s.faction(fe.Faction)
+			f.contributions = append(f.contributions, Contribution{
+				Timestamp:     e.Timestamp,
+				SystemAddress: inf.SystemAddress,
+				Faction:       fe.Faction,
+				MissionID:     e.MissionID,
+				Mission:       e.Name,
+				Influence:     inf.Influence,
+				Trend:         inf.Trend,
+			})
+		}
+	}
+}
+
+// record adds a sample for every faction and works out the state transitions
+// since the previous sample
+func (h *History) record(ts time.Time, address int, name string, factions []*events.Faction) {
+	if len(factions) == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	d := h.d
+	s := h.system(address, name)
+	changes := make([]Transition, 0)
+	for _, fac := range factions {
+		sample := Sample{
+			Timestamp:    ts,
+			Influence:    fac.Influence,
+			FactionState: fac.FactionState,
+			Happiness:    fac.HappinessLocalised,
+			MyReputation: fac.MyReputation,
+			Pending:      stateNames(fac.PendingStates),
+			Active:       stateNames(fac.ActiveStates),
+			Recovering:   stateNames(fac.RecoveringStates),
+		}
+		if sample.Happiness == "" {
+			sample.Happiness = fac.Happiness
+		}
+		// journals from before 3.3 only have the single FactionState
+		if fac.ActiveStates == nil && fac.FactionState != "" && fac.FactionState != "None" {
+			sample.Active = []string{fac.FactionState}
+		}
+
+		// the states seen on the first visit are where the history starts,
+		// not changes
+		f := s.faction(fac.Name)
+		if len(f.samples) == 0 {
+			f.samples = append(f.samples, sample)
+			continue
+		}
+		for _, t := range transitions(f.samples[len(f.samples)-1], sample) {
+			t.Timestamp = ts
+			t.SystemAddress = address
+			t.StarSystem = name
+			t.Faction = fac.Name
+			f.transitions = append(f.transitions, t)
+			changes = append(changes, t)
+		}
+		f.samples = append(f.samples, sample)
+	}
+	h.mu.Unlock()
+
+	if d == nil {
+		return
+	}
+	for _, t := range changes {
+		out, err := json.Marshal(StateChangedEvent{
+			Event:         event.Event{Event: FactionStateChanged, Timestamp: ts},
+			StarSystem:    t.StarSystem,
+			SystemAddress: t.SystemAddress,
+			Faction:       t.Faction,
+			State:         t.State,
+			From:          t.From,
+			To:            t.To,
+		})
+		if err != nil {
+			continue
+		}
+		_ = d.Trigger(FactionStateChanged, out)
+	}
+}
+
+// system returns the recorded system, creating it if needed. Must be called
+// with the lock held
+func (h *History) system(address int, name string) *system {
+	s, ok := h.systems[address]
+	if !ok {
+		s = &system{factions: make(map[string]*faction)}
+		h.systems[address] = s
+	}
+	if name != "" {
+		s.name = name
+	}
+	return s
+}
+
+func (s *system) faction(name string) *faction {
+	key := strings.ToLower(name)
+	f, ok := s.factions[key]
+	if !ok {
+		f = &faction{
+			name:          name,
+			samples:       make([]Sample, 0),
+			transitions:   make([]Transition, 0),
+			contributions: make([]Contribution, 0),
+		}
+		s.factions[key] = f
+	}
+	return f
+}
+
+// transitions returns the states whose phase differs between two samples,
+// sorted by state name
+func transitions(prev, next Sample) []Transition {
+	before := prev.phases()
+	after := next.phases()
+
+	names := make([]string, 0)
+	for state := range before {
+		names = append(names, state)
+	}
+	for state := range after {
+		if _, ok := before[state]; !ok {
+			names = append(names, state)
+		}
+	}
+	sort.Strings(names)
+
+	t := make([]Transition, 0)
+	for _, state := range names {
+		if before[state] != after[state] {
+			t = append(t, Transition{State: state, From: before[state], To: after[state]})
+		}
+	}
+	return t
+}
+
+// phases returns the phase of every state in the sample. A state listed in
+// more than one phase is taken to be in the latest one
+func (s Sample) phases() map[string]Phase {
+	p := make(map[string]Phase)
+	for _, state := range s.Pending {
+		p[state] = Pending
+	}
+	for _, state := range s.Active {
+		p[state] = Active
+	}
+	for _, state := range s.Recovering {
+		p[state] = Recovering
+	}
+	return p
+}
+
+func (s Sample) copy() Sample {
+	s.Pending = append([]string{}, s.Pending...)
+	s.Active = append([]string{}, s.Active...)
+	s.Recovering = append([]string{}, s.Recovering...)
+	return s
+}
+
+func stateNames(states []*events.FactionState) []string {
+	names := make([]string, 0, len(states))
+	for _, s := range states {
+		names = append(names, s.State)
+	}
+	return names
+}
@@ -0,0 +1,83 @@
+package bgs
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sht/ed-journal/dispatcher"
+)
+
+// arrive triggers an FSDJump into system 1 with a single faction
+func arrive(t *testing.T, d *dispatcher.Dispatcher, ts time.Time, pending, active, recovering string) {
+	t.Helper()
+
+	b := []byte(`{ "timestamp":"` + ts.Format(time.RFC3339) + `", "event":"FSDJump", "StarSystem":"Sol", "SystemAddress":1,
+		"Factions":[{ "Name":"Mother Gaia", "Influence":0.5,
+			"PendingStates":` + pending + `, "ActiveStates":` + active + `, "RecoveringStates":` + recovering + ` }] }`)
+	err := d.Trigger("FSDJump", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHistoryTransitions(t *testing.T) {
+	d := dispatcher.NewDispatcher()
+	h := NewHistory()
+	h.AddListeners(d)
+	changed := make([]StateChangedEvent, 0)
+	d.OnSync(FactionStateChanged, func(b []byte) {
+		var e StateChangedEvent
+		err := json.Unmarshal(b, &e)
+		if err != nil {
+			t.Error(err)
+		}
+		changed = append(changed, e)
+	})
+
+	// the states found on the first visit are not changes
+	first := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	arrive(t, d, first, `[{"State":"War"}]`, `[{"State":"Boom"}]`, `[]`)
+	if got := h.Transitions(1, "Mother Gaia", time.Time{}, time.Time{}); len(got) != 0 {
+		t.Errorf("first visit reported transitions %+v", got)
+	}
+	if len(changed) != 0 {
+		t.Errorf("first visit triggered %+v", changed)
+	}
+
+	second := first.Add(24 * time.Hour)
+	arrive(t, d, second, `[{"State":"Election"}]`, `[{"State":"War"}]`, `[{"State":"Boom"}]`)
+	want := []Transition{
+		{State: "Boom", From: Active, To: Recovering},
+		{State: "Election", From: None, To: Pending},
+		{State: "War", From: Pending, To: Active},
+	}
+	for i := range want {
+		want[i].Timestamp = second
+		want[i].SystemAddress = 1
+		want[i].StarSystem = "Sol"
+		want[i].Faction = "Mother Gaia"
+	}
+	got := h.Transitions(1, "Mother Gaia", time.Time{}, time.Time{})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("transitions %+v, want %+v", got, want)
+	}
+	if len(changed) != len(want) {
+		t.Fatalf("triggered %d FactionStateChanged events, want %d", len(changed), len(want))
+	}
+	for i, e := range changed {
+		if e.State != want[i].State || e.From != want[i].From || e.To != want[i].To || e.StarSystem != "Sol" {
+			t.Errorf("event %+v, want %+v", e, want[i])
+		}
+	}
+
+	// a visit without changes adds a sample only
+	arrive(t, d, second.Add(time.Hour), `[{"State":"Election"}]`, `[{"State":"War"}]`, `[{"State":"Boom"}]`)
+	if n := len(h.Transitions(1, "Mother Gaia", time.Time{}, time.Time{})); n != len(want) {
+		t.Errorf("got %d transitions after an unchanged visit, want %d", n, len(want))
+	}
+	if n := len(h.Samples(1, "Mother Gaia", time.Time{}, time.Time{})); n != 3 {
+		t.Errorf("got %d samples, want 3", n)
+	}
+}
@@ -0,0 +1,241 @@
+package bgs
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/event"
+	"github.com/sht/ed-journal/events"
+)
+
+const (
+	TickDetected = "TickDetected"
+)
+
+// influenceEpsilon is the smallest influence difference taken as a change.
+// The journal reports influence with six decimals
+const influenceEpsilon = 1e-6
+
+// Tick is a background simulation tick, known to have happened between From
+// and To
+type Tick struct {
+	From          time.Time `json:"from"`
+	To            time.Time `json:"to"`
+	StarSystem    string    `json:"starSystem"`
+	SystemAddress int       `json:"systemAddress"`
+}
+
+// TickDetectedEvent is triggered on the dispatcher when a new tick is detected
+type TickDetectedEvent struct {
+	event.Event
+	From          time.Time `json:"From"`
+	To            time.Time `json:"To"`
+	StarSystem    string    `json:"StarSystem"`
+	SystemAddress int       `json:"SystemAddress"`
+}
+
+type visit struct {
+	timestamp time.Time
+	influence map[string]float64
+}
+
+// TickDetector detects background simulation ticks from influence changes
+// between visits to the same system. Ticks that happen while no system is
+// visited both before and after them are missed. It is safe for concurrent
+// use
+type TickDetector struct {
+	mu     sync.RWMutex
+	d      *dispatcher.Dispatcher
+	visits map[int]visit
+	ticks  []Tick
+}
+
+func NewTickDetector() *TickDetector {
+	return &TickDetector{
+		visits: make(map[int]visit),
+		ticks:  make([]Tick, 0),
+	}
+}
+
+type savedVisit struct {
+	Timestamp time.Time          `json:"timestamp"`
+	Influence map[string]float64 `json:"influence"`
+}
+
+type tickSnapshot struct {
+	Visits map[int]savedVisit `json:"visits"`
+	Ticks  []Tick             `json:"ticks"`
+}
+
+// Snapshot saves the last visit to each system and the ticks detected
+func (t *TickDetector) Snapshot() (json.RawMessage, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	visits := make(map[int]savedVisit, len(t.visits))
+	for address, v := range t.visits {
+		visits[address] = savedVisit{v.timestamp, v.influence}
+	}
+	return json.Marshal(tickSnapshot{visits, t.ticks})
+}
+
+func (t *TickDetector) Restore(b json.RawMessage) error {
+	var s tickSnapshot
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.visits = make(map[int]visit, len(s.Visits))
+	for address, v := range s.Visits {
+		t.visits[address] = visit{v.Timestamp, v.Influence}
+	}
+	t.ticks = s.Ticks
+	return nil
+}
+
+// AddListeners subscribes the detector to the arrival events. TickDetected
+// events are triggered on the same dispatcher
+func (t *TickDetector) AddListeners(d *dispatcher.Dispatcher) {
+	t.mu.Lock()
+	t.d = d
+	t.mu.Unlock()
+
+	// travel
+	{
+		d.OnSync(events.FSDJump, t.fsdJump)
+		d.OnSync(events.Location, t.location)
+	}
+}
+
+// Ticks returns the detected ticks, oldest first. The window of a tick is
+// narrowed as more systems are seen to have changed
+func (t *TickDetector) Ticks() []Tick {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	ticks := make([]Tick, len(t.ticks))
+	copy(ticks, t.ticks)
+	return ticks
+}
+
+// Last returns the most recent tick
+func (t *TickDetector) Last() (Tick, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.ticks) == 0 {
+		return Tick{}, false
+	}
+	return t.ticks[len(t.ticks)-1], true
+}
+
+func (t *TickDetector) fsdJump(b []byte) {
+	var e events.FSDJumpEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	t.observe(e.Timestamp, e.SystemAddress, e.StarSystem, e.Factions)
+}
+
+func (t *TickDetector) location(b []byte) {
+	var e events.LocationEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	t.observe(e.Timestamp, e.SystemAddress, e.StarSystem, e.Factions)
+}
+
+// observe compares the influences in a system with the previous visit. A
+// change that the last known tick cannot explain is a new tick, one it can
+// explain narrows the window of that tick.
+//
+// A visit only tells that the influences changed at least once since the
+// previous one, not how many times. When the window between two visits spans
+// several ticks, the change is put down to a single one: the last known tick
+// when the window covers it, otherwise a new tick spanning the whole window.
+// The ticks in between are only detected from systems visited more often
+func (t *TickDetector) observe(ts time.Time, address int, name string, factions []*events.Faction) {
+	if len(factions) == 0 {
+		return
+	}
+
+	now := visit{
+		timestamp: ts,
+		influence: make(map[string]float64, len(factions)),
+	}
+	for _, f := range factions {
+		now.influence[strings.ToLower(f.Name)] = f.Influence
+	}
+
+	t.mu.Lock()
+	prev, ok := t.visits[address]
+	t.visits[address] = now
+	if !ok || !ts.After(prev.timestamp) || !changed(prev.influence, now.influence) {
+		t.mu.Unlock()
+		return
+	}
+
+	if n := len(t.ticks); n > 0 && prev.timestamp.Before(t.ticks[n-1].To) {
+		last := &t.ticks[n-1]
+		if prev.timestamp.After(last.From) {
+			last.From = prev.timestamp
+		}
+		if ts.Before(last.To) {
+			last.To = ts
+		}
+		t.mu.Unlock()
+		return
+	}
+
+	tick := Tick{
+		From:          prev.timestamp,
+		To:            ts,
+		StarSystem:    name,
+		SystemAddress: address,
+	}
+	t.ticks = append(t.ticks, tick)
+	d := t.d
+	t.mu.Unlock()
+
+	if d == nil {
+		return
+	}
+	out, err := json.Marshal(TickDetectedEvent{
+		Event:         event.Event{Event: TickDetected, Timestamp: ts},
+		From:          tick.From,
+		To:            tick.To,
+		StarSystem:    tick.StarSystem,
+		SystemAddress: tick.SystemAddress,
+	})
+	if err != nil {
+		return
+	}
+	_ = d.Trigger(TickDetected, out)
+}
+
+// changed reports whether any faction influence differs between two visits,
+// including factions that arrived or retreated
+func changed(a, b map[string]float64) bool {
+	if len(a) != len(b) {
+		return true
+	}
+	for name, inf := range a {
+		other, ok := b[name]
+		if !ok || math.Abs(inf-other) > influenceEpsilon {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,483 @@
+package credits
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/event"
+	"github.com/sht/ed-journal/events"
+)
+
+// Category groups ledger entries by the activity that moved the credits
+type Category string
+
+const (
+	Trade       Category = "trade"
+	Bounties    Category = "bounties"
+	Missions    Category = "missions"
+	Exploration Category = "exploration"
+	Repairs     Category = "repairs"
+	Rebuy       Category = "rebuy"
+	Outfitting  Category = "outfitting"
+	Shipyard    Category = "shipyard"
+	Fines       Category = "fines"
+	Carrier     Category = "carrier"
+	Fuel        Category = "fuel"
+	Supplies    Category = "supplies"
+	Crew        Category = "crew"
+	Powerplay   Category = "powerplay"
+)
+
+// Entry is a single movement of credits. Amount is positive for income and
+// negative for expenses, Balance is the running balance after the entry
+type Entry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Event     string    `json:"event"`
+	Category  Category  `json:"category"`
+	Amount    int       `json:"amount"`
+	Balance   int       `json:"balance"`
+}
+
+// Discrepancy is a difference between the running balance and the credits
+// reported by the game, caused by events the ledger does not model
+type Discrepancy struct {
+	Since      time.Time `json:"since"`
+	Timestamp  time.Time `json:"timestamp"`
+	Expected   int       `json:"expected"`
+	Actual     int       `json:"actual"`
+	Difference int       `json:"difference"`
+}
+
+// Ledger records every credit-affecting event and reconciles the running
+// balance on each LoadGame. It is safe for concurrent use
+type Ledger struct {
+	mu            sync.RWMutex
+	entries       []Entry
+	discrepancies []Discrepancy
+	balance       int
+	known         bool
+	since         time.Time
+}
+
+func NewLedger() *Ledger {
+	return &Ledger{
+		entries:       make([]Entry, 0),
+		discrepancies: make([]Discrepancy, 0),
+	}
+}
+
+// Balance returns the running balance and whether it has been established by
+// a LoadGame event
+func (l *Ledger) Balance() (int, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.balance, l.known
+}
+
+// Entries returns the entries recorded in the given time range. A zero from
+// or to leaves that side of the range open
+func (l *Ledger) Entries(from, to time.Time) []Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	entries := make([]Entry, 0)
+	for _, e := range l.entries {
+		if inRange(e.Timestamp, from, to) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Totals returns the net amount per category in the given time range
+func (l *Ledger) Totals(from, to time.Time) map[Category]int {
+	totals := make(map[Category]int)
+	for _, e := range l.Entries(from, to) {
+		totals[e.Category] += e.Amount
+	}
+	return totals
+}
+
+// Discrepancies returns the unexplained differences found so far
+func (l *Ledger) Discrepancies() []Discrepancy {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	d := make([]Discrepancy, len(l.discrepancies))
+	copy(d, l.discrepancies)
+	return d
+}
+
+func inRange(t, from, to time.Time) bool {
+	if !from.IsZero() && t.Before(from) {
+		return false
+	}
+	if !to.IsZero() && !t.Before(to) {
+		return false
+	}
+	return true
+}
+
+const SnapshotVersion = 1
+
+// Snapshot saves the entries, the discrepancies found and the running balance
+func (l *Ledger) Snapshot() (json.RawMessage, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return json.Marshal(l.saved())
+}
+
+func (l *Ledger) Restore(b json.RawMessage) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return json.Unmarshal(b, l.saved())
+}
+
+// saved points at the fields kept in snapshots
+func (l *Ledger) saved() interface{} {
+	return &struct {
+		Entries       *[]Entry       `json:"entries"`
+		Discrepancies *[]Discrepancy `json:"discrepancies"`
+		Balance       *int           `json:"balance"`
+		Known         *bool          `json:"known"`
+		Since         *time.Time     `json:"since"`
+	}{&l.entries, &l.discrepancies, &l.balance, &l.known, &l.since}
+}
+
+// AddListeners subscribes the ledger to every event that moves credits
+func (l *Ledger) AddListeners(d *dispatcher.Dispatcher) {
+	// startup
+	{
+		d.OnSync(events.LoadGame, l.loadGame)
+	}
+
+	// exploration
+	{
+		d.OnSync(events.BuyExplorationData, l.buyExplorationData)
+		d.OnSync(events.MultiSellExplorationData, l.multiSellExplorationData)
+		d.OnSync(events.SellExplorationData, l.sellExplorationData)
+		d.OnSync(events.SellOrganicData, l.sellOrganicData)
+	}
+
+	// trade
+	{
+		d.OnSync(events.BuyTradeData, l.buyTradeData)
+		d.OnSync(events.MarketBuy, l.marketBuy)
+		d.OnSync(events.MarketSell, l.marketSell)
+	}
+
+	// station services
+	{
+		d.OnSync(events.BuyAmmo, l.buyAmmo)
+		d.OnSync(events.BuyDrones, l.buyDrones)
+		d.OnSync(events.CommunityGoalReward, l.communityGoalReward)
+		d.OnSync(events.CrewHire, l.crewHire)
+		d.OnSync(events.FetchRemoteModule, l.fetchRemoteModule)
+		d.OnSync(events.MissionAbandoned, l.missionAbandoned)
+		d.OnSync(events.MissionCompleted, l.missionCompleted)
+		d.OnSync(events.MissionFailed, l.missionFailed)
+		d.OnSync(events.ModuleBuy, l.moduleBuy)
+		d.OnSync(events.ModuleRetrieve, l.moduleRetrieve)
+		d.OnSync(events.ModuleSell, l.moduleSell)
+		d.OnSync(events.ModuleSellRemote, l.moduleSellRemote)
+		d.OnSync(events.ModuleStore, l.moduleStore)
+		d.OnSync(events.PayBounties, l.payBounties)
+		d.OnSync(events.PayFines, l.payFines)
+		d.OnSync(events.RedeemVoucher, l.redeemVoucher)
+		d.OnSync(events.RefuelAll, l.refuelAll)
+		d.OnSync(events.RefuelPartial, l.refuelPartial)
+		d.OnSync(events.Repair, l.repair)
+		d.OnSync(events.RepairAll, l.repairAll)
+		d.OnSync(events.RestockVehicle, l.restockVehicle)
+		d.OnSync(events.SearchAndRescue, l.searchAndRescue)
+		d.OnSync(events.SellDrones, l.sellDrones)
+		d.OnSync(events.SellShipOnRebuy, l.sellShipOnRebuy)
+		d.OnSync(events.ShipyardBuy, l.shipyardBuy)
+		d.OnSync(events.ShipyardSell, l.shipyardSell)
+		d.OnSync(events.ShipyardTransfer, l.shipyardTransfer)
+	}
+
+	// powerplay
+	{
+		d.OnSync(events.PowerplayFastTrack, l.powerplayFastTrack)
+		d.OnSync(events.PowerplaySalary, l.powerplaySalary)
+	}
+
+	// fleet carriers
+	{
+		d.OnSync(events.CarrierBankTransfer, l.carrierBankTransfer)
+		d.OnSync(events.CarrierBuy, l.carrierBuy)
+	}
+
+	// other
+	{
+		d.OnSync(events.NpcCrewPaidWage, l.npcCrewPaidWage)
+		d.OnSync(events.Resurrect, l.resurrect)
+	}
+}
+
+// record decodes b into e and adds the amount returned by fn to the ledger
+func (l *Ledger) record(b []byte, e interface{}, category Category, fn func() int) {
+	err := json.Unmarshal(b, e)
+	if err != nil {
+		return
+	}
+
+	var ev event.Event
+	err = json.Unmarshal(b, &ev)
+	if err != nil {
+		return
+	}
+
+	amount := fn()
+	if amount == 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.balance += amount
+	l.entries = append(l.entries, Entry{
+		Timestamp: ev.Timestamp,
+		Event:     ev.Event,
+		Category:  category,
+		Amount:    amount,
+		Balance:   l.balance,
+	})
+}
+
+func (l *Ledger) loadGame(b []byte) {
+	var e events.LoadGameEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.known && l.balance != e.Credits {
+		l.discrepancies = append(l.discrepancies, Discrepancy{
+			Since:      l.since,
+			Timestamp:  e.Timestamp,
+			Expected:   l.balance,
+			Actual:     e.Credits,
+			Difference: e.Credits - l.balance,
+		})
+	}
+	l.balance = e.Credits
+	l.known = true
+	l.since = e.Timestamp
+}
+
+func (l *Ledger) buyExplorationData(b []byte) {
+	var e events.BuyExplorationDataEvent
+	l.record(b, &e, Exploration, func() int { return -e.Cost })
+}
+
+func (l *Ledger) multiSellExplorationData(b []byte) {
+	var e events.MultiSellExplorationDataEvent
+	l.record(b, &e, Exploration, func() int { return e.TotalEarnings })
+}
+
+func (l *Ledger) sellExplorationData(b []byte) {
+	var e events.SellExplorationDataEvent
+	l.record(b, &e, Exploration, func() int { return e.TotalEarnings })
+}
+
+func (l *Ledger) sellOrganicData(b []byte) {
+	var e events.SellOrganicDataEvent
+	l.record(b, &e, Exploration, func() int {
+		total := 0
+		for _, d := range e.BioData {
+			total += d.Value + d.Bonus
+		}
+		return total
+	})
+}
+
+func (l *Ledger) buyTradeData(b []byte) {
+	var e events.BuyTradeDataEvent
+	l.record(b, &e, Trade, func() int { return -e.Cost })
+}
+
+func (l *Ledger) marketBuy(b []byte) {
+	var e events.MarketBuyEvent
+	l.record(b, &e, Trade, func() int { return -e.TotalCost })
+}
+
+func (l *Ledger) marketSell(b []byte) {
+	var e events.MarketSellEvent
+	l.record(b, &e, Trade, func() int { return e.TotalSale })
+}
+
+func (l *Ledger) buyAmmo(b []byte) {
+	var e events.BuyAmmoEvent
+	l.record(b, &e, Supplies, func() int { return -e.Cost })
+}
+
+func (l *Ledger) buyDrones(b []byte) {
+	var e events.BuyDronesEvent
+	l.record(b, &e, Supplies, func() int { return -e.TotalCost })
+}
+
+func (l *Ledger) communityGoalReward(b []byte) {
+	var e events.CommunityGoalRewardEvent
+	l.record(b, &e, Missions, func() int { return e.Reward })
+}
+
+func (l *Ledger) crewHire(b []byte) {
+	var e events.CrewHireEvent
+	l.record(b, &e, Crew, func() int { return -e.Cost })
+}
+
+func (l *Ledger) fetchRemoteModule(b []byte) {
+	var e events.FetchRemoteModuleEvent
+	l.record(b, &e, Outfitting, func() int { return -e.TransferCost })
+}
+
+func (l *Ledger) missionAbandoned(b []byte) {
+	var e events.MissionAbandonedEvent
+	l.record(b, &e, Missions, func() int { return -e.Fine })
+}
+
+func (l *Ledger) missionCompleted(b []byte) {
+	var e events.MissionCompletedEvent
+	l.record(b, &e, Missions, func() int { return e.Reward - e.Donated })
+}
+
+func (l *Ledger) missionFailed(b []byte) {
+	var e events.MissionFailedEvent
+	l.record(b, &e, Missions, func() int { return -e.Fine })
+}
+
+func (l *Ledger) moduleBuy(b []byte) {
+	var e events.ModuleBuyEvent
+	l.record(b, &e, Outfitting, func() int { return e.SellPrice - e.BuyPrice })
+}
+
+func (l *Ledger) moduleRetrieve(b []byte) {
+	var e events.ModuleRetrieveEvent
+	l.record(b, &e, Outfitting, func() int { return -e.Cost })
+}
+
+func (l *Ledger) moduleSell(b []byte) {
+	var e events.ModuleSellEvent
+	l.record(b, &e, Outfitting, func() int { return e.SellPrice })
+}
+
+func (l *Ledger) moduleSellRemote(b []byte) {
+	var e events.ModuleSellRemoteEvent
+	l.record(b, &e, Outfitting, func() int { return e.SellPrice })
+}
+
+func (l *Ledger) moduleStore(b []byte) {
+	var e events.ModuleStoreEvent
+	l.record(b, &e, Outfitting, func() int { return -e.Cost })
+}
+
+func (l *Ledger) payBounties(b []byte) {
+	var e events.PayBountiesEvent
+	l.record(b, &e, Fines, func() int { return -e.Amount })
+}
+
+func (l *Ledger) payFines(b []byte) {
+	var e events.PayFinesEvent
+	l.record(b, &e, Fines, func() int { return -e.Amount })
+}
+
+func (l *Ledger) redeemVoucher(b []byte) {
+	var e events.RedeemVoucherEvent
+	l.record(b, &e, Bounties, func() int { return e.Amount })
+}
+
+func (l *Ledger) refuelAll(b []byte) {
+	var e events.RefuelAllEvent
+	l.record(b, &e, Fuel, func() int { return -e.Cost })
+}
+
+func (l *Ledger) refuelPartial(b []byte) {
+	var e events.RefuelPartialEvent
+	l.record(b, &e, Fuel, func() int { return -e.Cost })
+}
+
+func (l *Ledger) repair(b []byte) {
+	var e events.RepairEvent
+	l.record(b, &e, Repairs, func() int { return -e.Cost })
+}
+
+func (l *Ledger) repairAll(b []byte) {
+	var e events.RepairAllEvent
+	l.record(b, &e, Repairs, func() int { return -e.Cost })
+}
+
+func (l *Ledger) restockVehicle(b []byte) {
+	var e events.RestockVehicleEvent
+	l.record(b, &e, Supplies, func() int { return -e.Cost })
+}
+
+func (l *Ledger) searchAndRescue(b []byte) {
+	var e events.SearchAndRescueEvent
+	l.record(b, &e, Trade, func() int { return e.Reward })
+}
+
+func (l *Ledger) sellDrones(b []byte) {
+	var e events.SellDronesEvent
+	l.record(b, &e, Supplies, func() int { return e.TotalSale })
+}
+
+func (l *Ledger) sellShipOnRebuy(b []byte) {
+	var e events.SellShipOnRebuyEvent
+	l.record(b, &e, Shipyard, func() int { return e.ShipPrice })
+}
+
+func (l *Ledger) shipyardBuy(b []byte) {
+	var e events.ShipyardBuyEvent
+	l.record(b, &e, Shipyard, func() int { return e.SellPrice - e.ShipPrice })
+}
+
+func (l *Ledger) shipyardSell(b []byte) {
+	var e events.ShipyardSellEvent
+	l.record(b, &e, Shipyard, func() int { return e.ShipPrice })
+}
+
+func (l *Ledger) shipyardTransfer(b []byte) {
+	var e events.ShipyardTransferEvent
+	l.record(b, &e, Shipyard, func() int { return -e.TransferPrice })
+}
+
+func (l *Ledger) powerplayFastTrack(b []byte) {
+	var e events.PowerplayFastTrackEvent
+	l.record(b, &e, Powerplay, func() int { return -e.Cost })
+}
+
+func (l *Ledger) powerplaySalary(b []byte) {
+	var e events.PowerplaySalaryEvent
+	l.record(b, &e, Powerplay, func() int { return e.Amount })
+}
+
+func (l *Ledger) carrierBankTransfer(b []byte) {
+	var e events.CarrierBankTransferEvent
+	l.record(b, &e, Carrier, func() int { return e.Withdraw - e.Deposit })
+}
+
+func (l *Ledger) carrierBuy(b []byte) {
+	var e events.CarrierBuyEvent
+	l.record(b, &e, Carrier, func() int { return -e.Price })
+}
+
+func (l *Ledger) npcCrewPaidWage(b []byte) {
+	var e events.NpcCrewPaidWageEvent
+	l.record(b, &e, Crew, func() int { return -e.Amount })
+}
+
+func (l *Ledger) resurrect(b []byte) {
+	var e events.ResurrectEvent
+	l.record(b, &e, Rebuy, func() int { return -e.Cost })
+}
@@ -0,0 +1,65 @@
+package credits
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sht/ed-journal/dispatcher"
+)
+
+var start = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func trigger(t *testing.T, d *dispatcher.Dispatcher, at time.Duration, name, fields string) {
+	t.Helper()
+
+	b := []byte(`{ "timestamp":"` + start.Add(at).Format(time.RFC3339) + `", "event":"` + name + `", ` + fields + ` }`)
+	err := d.Trigger(name, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLedgerReconciliation(t *testing.T) {
+	d := dispatcher.NewDispatcher()
+	l := NewLedger()
+	l.AddListeners(d)
+
+	if _, ok := l.Balance(); ok {
+		t.Error("balance known before the game was loaded")
+	}
+	trigger(t, d, 0, "LoadGame", `"Commander":"Bob", "Credits":1000`)
+	trigger(t, d, time.Minute, "MarketSell", `"MarketID":1, "Type":"gold", "Count":10, "SellPrice":50, "TotalSale":500`)
+
+	// the game agrees with the ledger
+	trigger(t, d, time.Hour, "LoadGame", `"Commander":"Bob", "Credits":1500`)
+	if got := l.Discrepancies(); len(got) != 0 {
+		t.Errorf("discrepancies %+v, want none", got)
+	}
+
+	// credits spent on something the ledger does not model
+	trigger(t, d, 2*time.Hour, "MarketBuy", `"MarketID":1, "Type":"food", "Count":10, "BuyPrice":20, "TotalCost":200`)
+	trigger(t, d, 3*time.Hour, "LoadGame", `"Commander":"Bob", "Credits":1000`)
+	want := []Discrepancy{{
+		Since:      start.Add(time.Hour),
+		Timestamp:  start.Add(3 * time.Hour),
+		Expected:   1300,
+		Actual:     1000,
+		Difference: -300,
+	}}
+	if got := l.Discrepancies(); !reflect.DeepEqual(got, want) {
+		t.Errorf("discrepancies %+v, want %+v", got, want)
+	}
+
+	// the game's figure replaces the running balance
+	if balance, ok := l.Balance(); !ok || balance != 1000 {
+		t.Errorf("balance %d, %v, want 1000", balance, ok)
+	}
+	totals := map[Category]int{Trade: 300}
+	if got := l.Totals(time.Time{}, time.Time{}); !reflect.DeepEqual(got, totals) {
+		t.Errorf("totals %v, want %v", got, totals)
+	}
+	if got := l.Totals(start.Add(time.Hour), time.Time{}); got[Trade] != -200 {
+		t.Errorf("trade since the second LoadGame %d, want -200", got[Trade])
+	}
+}
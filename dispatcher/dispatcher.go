@@ -2,35 +2,73 @@ package dispatcher
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/sht/ed-journal/event"
 )
 
 type Dispatcher struct {
+	mu     sync.RWMutex
 	events map[string][]event.Handler
+	sync   map[string][]event.Handler
+	all    []event.Handler
 }
 
 func NewDispatcher() *Dispatcher {
 	return &Dispatcher{
 		events: make(map[string][]event.Handler),
+		sync:   make(map[string][]event.Handler),
 	}
 }
 
 func (d *Dispatcher) On(name string, h event.Handler) {
-	_, ok := d.events[name]
-	if !ok {
-		d.events[name] = make([]event.Handler, 0, 1)
-	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
 	d.events[name] = append(d.events[name], h)
 }
 
+// OnSync registers a handler that is called on the triggering goroutine, in
+// registration order. Handlers that need to observe events in journal order
+// (e.g. state trackers) should use OnSync instead of On
+func (d *Dispatcher) OnSync(name string, h event.Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.sync[name] = append(d.sync[name], h)
+}
+
+// OnAllSync registers a handler that is called on the triggering goroutine
+// for every event, after the handlers registered for the event name with
+// OnSync
+func (d *Dispatcher) OnAllSync(h event.Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.all = append(d.all, h)
+}
+
+// Trigger calls the handlers registered for the event name and the catch-all
+// handlers. It returns an error when no handler is registered for the event
+// name, even though the catch-all handlers are still called
 func (d *Dispatcher) Trigger(name string, b []byte) error {
+	d.mu.RLock()
 	handlers, ok := d.events[name]
-	if !ok {
-		return fmt.Errorf("%s event is not registered", name)
+	syncHandlers, syncOk := d.sync[name]
+	all := d.all
+	d.mu.RUnlock()
+
+	for _, h := range syncHandlers {
+		h(b)
+	}
+	for _, h := range all {
+		h(b)
 	}
 	for _, h := range handlers {
 		go h(b)
 	}
+	if !ok && !syncOk {
+		return fmt.Errorf("%s event is not registered", name)
+	}
 	return nil
 }
@@ -0,0 +1,214 @@
+package engineering
+
+import "strings"
+
+// Kind is the kind of module a blueprint applies to, e.g. "fsd"
+type Kind string
+
+const (
+	Armour               Kind = "armour"
+	AutoFieldMaintenance Kind = "afmu"
+	BeamLaser            Kind = "beamLaser"
+	BurstLaser           Kind = "burstLaser"
+	Cannon               Kind = "cannon"
+	ChaffLauncher        Kind = "chaffLauncher"
+	CollectorLimpet      Kind = "collectorLimpet"
+	ECM                  Kind = "ecm"
+	FragmentCannon       Kind = "fragmentCannon"
+	FSD                  Kind = "fsd"
+	FSDInterdictor       Kind = "fsdInterdictor"
+	FuelScoop            Kind = "fuelScoop"
+	FuelTransferLimpet   Kind = "fuelTransferLimpet"
+	HatchBreakerLimpet   Kind = "hatchBreakerLimpet"
+	HeatSinkLauncher     Kind = "heatSinkLauncher"
+	HullReinforcement    Kind = "hullReinforcement"
+	KillWarrantScanner   Kind = "killWarrantScanner"
+	LifeSupport          Kind = "lifeSupport"
+	ManifestScanner      Kind = "manifestScanner"
+	MineLauncher         Kind = "mineLauncher"
+	MissileRack          Kind = "missileRack"
+	MultiCannon          Kind = "multiCannon"
+	PlasmaAccelerator    Kind = "plasmaAccelerator"
+	PointDefence         Kind = "pointDefence"
+	PowerDistributor     Kind = "powerDistributor"
+	PowerPlant           Kind = "powerPlant"
+	ProspectorLimpet     Kind = "prospectorLimpet"
+	PulseLaser           Kind = "pulseLaser"
+	RailGun              Kind = "railGun"
+	Refinery             Kind = "refinery"
+	Sensors              Kind = "sensors"
+	ShieldBooster        Kind = "shieldBooster"
+	ShieldCellBank       Kind = "shieldCellBank"
+	ShieldGenerator      Kind = "shieldGenerator"
+	SurfaceScanner       Kind = "surfaceScanner"
+	Thrusters            Kind = "thrusters"
+	TorpedoPylon         Kind = "torpedoPylon"
+	WakeScanner          Kind = "wakeScanner"
+)
+
+// kinds maps the start of a module item name to its kind. Longer prefixes
+// are listed before the shorter ones they start with
+var kinds = []struct {
+	prefix string
+	kind   Kind
+}{
+	{"hpt_pulselaserburst", BurstLaser},
+	{"hpt_pulselaser", PulseLaser},
+	{"hpt_beamlaser", BeamLaser},
+	{"hpt_multicannon", MultiCannon},
+	{"hpt_cannon", Cannon},
+	{"hpt_slugshot", FragmentCannon},
+	{"hpt_railgun", RailGun},
+	{"hpt_plasmaaccelerator", PlasmaAccelerator},
+	{"hpt_basicmissilerack", MissileRack},
+	{"hpt_dumbfiremissilerack", MissileRack},
+	{"hpt_advancedtorppylon", TorpedoPylon},
+	{"hpt_minelauncher", MineLauncher},
+	{"hpt_chafflauncher", ChaffLauncher},
+	{"hpt_electroniccountermeasure", ECM},
+	{"hpt_heatsinklauncher", HeatSinkLauncher},
+	{"hpt_plasmapointdefence", PointDefence},
+	{"hpt_shieldbooster", ShieldBooster},
+	{"hpt_cloudscanner", WakeScanner},
+	{"hpt_crimescanner", KillWarrantScanner},
+	{"hpt_cargoscanner", ManifestScanner},
+	{"int_hyperdrive", FSD},
+	{"int_engine", Thrusters},
+	{"int_powerplant", PowerPlant},
+	{"int_powerdistributor", PowerDistributor},
+	{"int_shieldgenerator", ShieldGenerator},
+	{"int_shieldcellbank", ShieldCellBank},
+	{"int_sensors", Sensors},
+	{"int_lifesupport", LifeSupport},
+	{"int_fsdinterdictor", FSDInterdictor},
+	{"int_hullreinforcement", HullReinforcement},
+	{"int_fuelscoop", FuelScoop},
+	{"int_refinery", Refinery},
+	{"int_repairer", AutoFieldMaintenance},
+	{"int_detailedsurfacescanner", SurfaceScanner},
+	{"int_dronecontrol_collection", CollectorLimpet},
+	{"int_dronecontrol_fueltransfer", FuelTransferLimpet},
+	{"int_dronecontrol_prospector", ProspectorLimpet},
+	{"int_dronecontrol_resourcesiphon", HatchBreakerLimpet},
+}
+
+// KindOf returns the kind of a module from its item name, or false for
+// modules that cannot be engineered
+func KindOf(item string) (Kind, bool) {
+	item = strings.ToLower(item)
+	if strings.Contains(item, "_armour_") {
+		return Armour, true
+	}
+	for _, k := range kinds {
+		if strings.HasPrefix(item, k.prefix) {
+			return k.kind, true
+		}
+	}
+	return "", false
+}
+
+// Engineer is a ship engineer and the highest grade they offer for each kind
+// of module
+type Engineer struct {
+	ID     int
+	Name   string
+	Grades map[Kind]int
+}
+
+// Engineers lists the ship engineers by their journal ID
+var Engineers = map[int]Engineer{
+	300000: {ID: 300000, Name: "Didi Vatermann", Grades: map[Kind]int{
+		ShieldBooster: 5, ShieldGenerator: 3,
+	}},
+	300010: {ID: 300010, Name: "Bill Turner", Grades: map[Kind]int{
+		PlasmaAccelerator: 5, SurfaceScanner: 5, Sensors: 5, AutoFieldMaintenance: 3, FuelScoop: 3,
+		KillWarrantScanner: 3, LifeSupport: 3, ManifestScanner: 3, Refinery: 3, WakeScanner: 3,
+	}},
+	300030: {ID: 300030, Name: "Broo Tarquin", Grades: map[Kind]int{
+		BeamLaser: 5, BurstLaser: 5, PulseLaser: 5,
+	}},
+	300040: {ID: 300040, Name: "The Sarge", Grades: map[Kind]int{
+		Cannon: 5, CollectorLimpet: 5, FuelTransferLimpet: 5, HatchBreakerLimpet: 5, ProspectorLimpet: 5, RailGun: 3,
+	}},
+	300050: {ID: 300050, Name: "Zacariah Nemo", Grades: map[Kind]int{
+		FragmentCannon: 5, MultiCannon: 3, PlasmaAccelerator: 2,
+	}},
+	300080: {ID: 300080, Name: "Liz Ryder", Grades: map[Kind]int{
+		MissileRack: 5, TorpedoPylon: 5, MineLauncher: 3, Armour: 1, HullReinforcement: 1,
+	}},
+	300090: {ID: 300090, Name: "Hera Tani", Grades: map[Kind]int{
+		PowerPlant: 5, PowerDistributor: 3, Sensors: 3, SurfaceScanner: 3,
+	}},
+	300100: {ID: 300100, Name: "Felicity Farseer", Grades: map[Kind]int{
+		FSD: 5, SurfaceScanner: 3, Sensors: 3, Thrusters: 3, FSDInterdictor: 1, PowerPlant: 1, ShieldBooster: 1,
+	}},
+	300110: {ID: 300110, Name: "Ram Tah", Grades: map[Kind]int{
+		ChaffLauncher: 5, ECM: 5, HeatSinkLauncher: 5, PointDefence: 5, CollectorLimpet: 4,
+		FuelTransferLimpet: 4, ProspectorLimpet: 4, HatchBreakerLimpet: 3,
+	}},
+	300120: {ID: 300120, Name: "Lei Cheung", Grades: map[Kind]int{
+		ShieldGenerator: 5, SurfaceScanner: 5, Sensors: 5, ShieldBooster: 3,
+	}},
+	300130: {ID: 300130, Name: "Petra Olmanova", Grades: map[Kind]int{
+		Armour: 5, HullReinforcement: 5, ChaffLauncher: 5, ECM: 5, HeatSinkLauncher: 5, PointDefence: 5,
+		MineLauncher: 5, MissileRack: 5, TorpedoPylon: 5, AutoFieldMaintenance: 4,
+	}},
+	300140: {ID: 300140, Name: "Colonel Bris Dekker", Grades: map[Kind]int{
+		FSDInterdictor: 4, FSD: 3,
+	}},
+	300150: {ID: 300150, Name: "Marsha Hicks", Grades: map[Kind]int{
+		Cannon: 5, FragmentCannon: 5, MultiCannon: 5, CollectorLimpet: 5, FuelScoop: 5,
+		FuelTransferLimpet: 5, HatchBreakerLimpet: 5, ProspectorLimpet: 5, Refinery: 5,
+	}},
+	300160: {ID: 300160, Name: "Elvira Martuuk", Grades: map[Kind]int{
+		FSD: 5, ShieldGenerator: 3, Thrusters: 2, ShieldCellBank: 1,
+	}},
+	300180: {ID: 300180, Name: "The Dweller", Grades: map[Kind]int{
+		PowerDistributor: 5, PulseLaser: 4, BeamLaser: 3, BurstLaser: 3,
+	}},
+	300200: {ID: 300200, Name: "Marco Qwent", Grades: map[Kind]int{
+		PowerPlant: 4, PowerDistributor: 3,
+	}},
+	300210: {ID: 300210, Name: "Selene Jean", Grades: map[Kind]int{
+		Armour: 5, HullReinforcement: 5,
+	}},
+	300220: {ID: 300220, Name: "Professor Palin", Grades: map[Kind]int{
+		Thrusters: 5, FSD: 3,
+	}},
+	300230: {ID: 300230, Name: "Lori Jameson", Grades: map[Kind]int{
+		SurfaceScanner: 5, Sensors: 5, AutoFieldMaintenance: 4, FuelScoop: 4, LifeSupport: 4, Refinery: 4,
+		KillWarrantScanner: 3, ManifestScanner: 3, ShieldCellBank: 3, WakeScanner: 3,
+	}},
+	300250: {ID: 300250, Name: "Juri Ishmaak", Grades: map[Kind]int{
+		KillWarrantScanner: 5, ManifestScanner: 5, MineLauncher: 5, WakeScanner: 5, SurfaceScanner: 3, Sensors: 3,
+	}},
+	300260: {ID: 300260, Name: "Tod 'The Blaster' McQuinn", Grades: map[Kind]int{
+		MultiCannon: 5, RailGun: 5, FragmentCannon: 3, Cannon: 2,
+	}},
+	300270: {ID: 300270, Name: "Tiana Fortune", Grades: map[Kind]int{
+		CollectorLimpet: 5, HatchBreakerLimpet: 5, KillWarrantScanner: 5, ManifestScanner: 5,
+		ProspectorLimpet: 5, Sensors: 5, WakeScanner: 5, FSDInterdictor: 3,
+	}},
+	300280: {ID: 300280, Name: "Mel Brandon", Grades: map[Kind]int{
+		BeamLaser: 5, BurstLaser: 5, PulseLaser: 5, FSD: 5, FSDInterdictor: 5, ShieldBooster: 5,
+		ShieldGenerator: 5, Thrusters: 5, ShieldCellBank: 4,
+	}},
+	300290: {ID: 300290, Name: "Etienne Dorn", Grades: map[Kind]int{
+		PlasmaAccelerator: 5, RailGun: 5, PowerDistributor: 5, PowerPlant: 5, SurfaceScanner: 5, Sensors: 5,
+		KillWarrantScanner: 5, LifeSupport: 5, ManifestScanner: 5, WakeScanner: 5,
+	}},
+	300300: {ID: 300300, Name: "Chloe Sedesi", Grades: map[Kind]int{
+		Thrusters: 5, FSD: 3,
+	}},
+}
+
+// MaxGrade returns the highest grade any engineer offers for a kind of module
+func MaxGrade(k Kind) int {
+	max := 0
+	for _, e := range Engineers {
+		if g := e.Grades[k]; g > max {
+			max = g
+		}
+	}
+	return max
+}
@@ -0,0 +1,318 @@
+package engineering
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/events"
+)
+
+// Modifier is a module attribute changed by engineering. Improved is set when
+// the value beats the original one
+type Modifier struct {
+	Label         string  `json:"label"`
+	Value         float64 `json:"value"`
+	OriginalValue float64 `json:"originalValue"`
+	LessIsGood    bool    `json:"lessIsGood"`
+	Improved      bool    `json:"improved"`
+}
+
+// Option is an engineer that can take a module further. Available is set when
+// the engineer is unlocked and the commander's rank with them is high enough
+type Option struct {
+	EngineerID int    `json:"engineerId"`
+	Engineer   string `json:"engineer"`
+	MaxGrade   int    `json:"maxGrade"`
+	Progress   string `json:"progress,omitempty"`
+	Rank       int    `json:"rank"`
+	Available  bool   `json:"available"`
+}
+
+// Module is an engineered module fitted to one of the commander's ships
+type Module struct {
+	ShipID       int        `json:"shipId"`
+	Ship         string     `json:"ship"`
+	ShipName     string     `json:"shipName,omitempty"`
+	Slot         string     `json:"slot"`
+	Item         string     `json:"item"`
+	Kind         Kind       `json:"kind,omitempty"`
+	Blueprint    string     `json:"blueprint"`
+	Engineer     string     `json:"engineer"`
+	Grade        int        `json:"grade"`
+	Quality      float64    `json:"quality"`
+	Experimental string     `json:"experimental,omitempty"`
+	MaxGrade     int        `json:"maxGrade"`
+	Complete     bool       `json:"complete"`
+	Modifiers    []Modifier `json:"modifiers"`
+	Options      []Option   `json:"options,omitempty"`
+}
+
+// Inventory keeps the latest loadout of every ship and the commander's
+// progress with the engineers. It is safe for concurrent use
+type Inventory struct {
+	mu        sync.RWMutex
+	loadouts  map[int]*events.LoadoutEvent
+	engineers map[int]events.EngineerStatus
+}
+
+func NewInventory() *Inventory {
+	return &Inventory{
+		loadouts:  make(map[int]*events.LoadoutEvent),
+		engineers: make(map[int]events.EngineerStatus),
+	}
+}
+
+const SnapshotVersion = 1
+
+// Snapshot saves the last loadout of each ship and the engineer progress
+func (i *Inventory) Snapshot() (json.RawMessage, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return json.Marshal(i.saved())
+}
+
+func (i *Inventory) Restore(b json.RawMessage) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.loadouts = make(map[int]*events.LoadoutEvent)
+	i.engineers = make(map[int]events.EngineerStatus)
+	return json.Unmarshal(b, i.saved())
+}
+
+// saved points at the fields kept in snapshots
+func (i *Inventory) saved() interface{} {
+	return &struct {
+		Loadouts  *map[int]*events.LoadoutEvent  `json:"loadouts"`
+		Engineers *map[int]events.EngineerStatus `json:"engineers"`
+	}{&i.loadouts, &i.engineers}
+}
+
+// AddListeners subscribes the inventory to the loadout and engineer events
+func (i *Inventory) AddListeners(d *dispatcher.Dispatcher) {
+	// startup
+	{
+		d.OnSync(events.EngineerProgress, i.engineerProgress)
+		d.OnSync(events.Loadout, i.loadout)
+	}
+
+	// station services
+	{
+		d.OnSync(events.SellShipOnRebuy, i.sellShipOnRebuy)
+		d.OnSync(events.ShipyardSell, i.shipyardSell)
+	}
+}
+
+// Engineers returns the commander's progress with every engineer seen in the
+// journal, by engineer ID
+func (i *Inventory) Engineers() map[int]events.EngineerStatus {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	engineers := make(map[int]events.EngineerStatus, len(i.engineers))
+	for id, e := range i.engineers {
+		engineers[id] = e
+	}
+	return engineers
+}
+
+// Modules returns every engineered module across all ships, sorted by ship
+// and slot
+func (i *Inventory) Modules() []Module {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	modules := make([]Module, 0)
+	for _, l := range i.loadouts {
+		for _, m := range l.Modules {
+			if m.Engineering == nil {
+				continue
+			}
+			modules = append(modules, i.module(l, m))
+		}
+	}
+	sort.Slice(modules, func(a, b int) bool {
+		if modules[a].ShipID != modules[b].ShipID {
+			return modules[a].ShipID < modules[b].ShipID
+		}
+		return modules[a].Slot < modules[b].Slot
+	})
+	return modules
+}
+
+// Unfinished returns the engineered modules not yet at the highest grade and
+// quality
+func (i *Inventory) Unfinished() []Module {
+	modules := make([]Module, 0)
+	for _, m := range i.Modules() {
+		if !m.Complete {
+			modules = append(modules, m)
+		}
+	}
+	return modules
+}
+
+// module builds the report for an engineered module. Must be called with the
+// lock held
+func (i *Inventory) module(l *events.LoadoutEvent, m *events.LoadoutModule) Module {
+	eng := m.Engineering
+	mod := Module{
+		ShipID:       l.ShipID,
+		Ship:         strings.ToLower(l.Ship),
+		ShipName:     l.ShipName,
+		Slot:         m.Slot,
+		Item:         m.Item,
+		Blueprint:    eng.BlueprintName,
+		Engineer:     eng.Engineer,
+		Grade:        eng.Level,
+		Quality:      eng.Quality,
+		Experimental: eng.ExperimentalEffectLocalised,
+		MaxGrade:     5,
+		Modifiers:    make([]Modifier, 0, len(eng.Modifiers)),
+	}
+	if mod.Experimental == "" {
+		mod.Experimental = eng.ExperimentalEffect
+	}
+
+	for _, em := range eng.Modifiers {
+		if em.Value == nil {
+			continue
+		}
+		mm := Modifier{
+			Label:         em.Label,
+			Value:         *em.Value,
+			OriginalValue: em.OriginalValue,
+			LessIsGood:    em.LessIsGood == 1,
+		}
+		if mm.LessIsGood {
+			mm.Improved = mm.Value < mm.OriginalValue
+		} else {
+			mm.Improved = mm.Value > mm.OriginalValue
+		}
+		mod.Modifiers = append(mod.Modifiers, mm)
+	}
+
+	kind, ok := KindOf(m.Item)
+	if !ok {
+		mod.Complete = mod.Grade >= mod.MaxGrade && mod.Quality >= 1
+		return mod
+	}
+	mod.Kind = kind
+	if max := MaxGrade(kind); max > 0 {
+		mod.MaxGrade = max
+	}
+	mod.Complete = mod.Grade >= mod.MaxGrade && mod.Quality >= 1
+	if mod.Complete {
+		return mod
+	}
+
+	// the grade still to be rolled: the current one until it is at full
+	// quality, the next one after that
+	target := mod.Grade
+	if mod.Quality >= 1 {
+		target++
+	}
+	for id, e := range Engineers {
+		grade, ok := e.Grades[kind]
+		if !ok || grade < target {
+			continue
+		}
+		o := Option{
+			EngineerID: id,
+			Engineer:   e.Name,
+			MaxGrade:   grade,
+		}
+		if s, ok := i.engineers[id]; ok {
+			o.Progress = s.Progress
+			if s.Rank != nil {
+				o.Rank = *s.Rank
+			}
+		}
+		o.Available = o.Progress == "Unlocked" && o.Rank >= target
+		mod.Options = append(mod.Options, o)
+	}
+	sort.Slice(mod.Options, func(a, b int) bool {
+		oa, ob := mod.Options[a], mod.Options[b]
+		if oa.Available != ob.Available {
+			return oa.Available
+		}
+		if oa.MaxGrade != ob.MaxGrade {
+			return oa.MaxGrade > ob.MaxGrade
+		}
+		return oa.Engineer < ob.Engineer
+	})
+	return mod
+}
+
+func (i *Inventory) engineerProgress(b []byte) {
+	var e events.EngineerProgressEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, s := range e.Engineers {
+		i.engineers[s.EngineerID] = *s
+	}
+	if e.EngineerID != 0 {
+		s := i.engineers[e.EngineerID]
+		s.Engineer = e.Engineer
+		s.EngineerID = e.EngineerID
+		if e.Progress != "" {
+			s.Progress = e.Progress
+		}
+		if e.Rank != nil {
+			s.Rank = e.Rank
+		}
+		if e.RankProgress != nil {
+			s.RankProgress = e.RankProgress
+		}
+		i.engineers[e.EngineerID] = s
+	}
+}
+
+func (i *Inventory) loadout(b []byte) {
+	var e events.LoadoutEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.loadouts[e.ShipID] = &e
+}
+
+func (i *Inventory) sellShipOnRebuy(b []byte) {
+	var e events.SellShipOnRebuyEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	delete(i.loadouts, e.SellShipID)
+}
+
+func (i *Inventory) shipyardSell(b []byte) {
+	var e events.ShipyardSellEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	delete(i.loadouts, e.SellShipID)
+}
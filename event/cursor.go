@@ -0,0 +1,96 @@
+package event
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cursor is the position of the last journal line read. Offset is the byte
+// offset right after the line, and Hash the hash of the line, which is used to
+// check the file was not replaced before resuming from the cursor
+type Cursor struct {
+	File      string    `json:"file"`
+	Offset    int64     `json:"offset"`
+	Timestamp time.Time `json:"timestamp"`
+	Hash      string    `json:"hash"`
+}
+
+// LoadCursor reads a cursor saved with Save. A missing file returns a zero
+// cursor
+func LoadCursor(path string) (Cursor, error) {
+	var c Cursor
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+
+	err = json.Unmarshal(b, &c)
+	return c, err
+}
+
+// Save writes the cursor to path. The file is replaced atomically so a crash
+// never leaves a torn cursor behind
+func (c Cursor) Save(path string) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, b, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// IsZero reports whether the cursor points nowhere
+func (c Cursor) IsZero() bool {
+	return c.File == ""
+}
+
+// hashLine returns the hash stored in cursors for a journal line
+func hashLine(line []byte) string {
+	sum := sha256.Sum256(bytes.TrimSpace(line))
+	return hex.EncodeToString(sum[:])
+}
+
+// verify reports whether the line ending at the cursor offset in dir is
+// still the line the cursor was saved after
+func (c Cursor) verify(dir string) bool {
+	if c.Offset == 0 {
+		return c.Hash == ""
+	}
+
+	f, err := os.Open(filepath.Join(dir, c.File))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	// look back for the start of the last line
+	size := c.Offset
+	if size > maxLine {
+		size = maxLine
+	}
+	b := make([]byte, size)
+	// a short read means the file was truncated or replaced
+	_, err = f.ReadAt(b, c.Offset-size)
+	if err != nil {
+		return false
+	}
+	line := bytes.TrimRight(b, "\r\n")
+	if i := bytes.LastIndexByte(line, '\n'); i >= 0 {
+		line = line[i+1:]
+	}
+	return hashLine(line) == c.Hash
+}
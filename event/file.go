@@ -0,0 +1,63 @@
+package event
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileWatcher calls its handler with the content of a file the game rewrites
+// in place, such as Status.json, whenever the file changes. It is safe for
+// concurrent use
+type FileWatcher struct {
+	mu          sync.Mutex
+	path        string
+	handlerFunc Handler
+	modTime     time.Time
+	size        int64
+}
+
+// NewFileWatcher returns a watcher for the file at path. It does nothing
+// until polled
+func NewFileWatcher(path string, h Handler) *FileWatcher {
+	return &FileWatcher{
+		path:        path,
+		handlerFunc: h,
+	}
+}
+
+// Poll calls the handler when the file was modified since the last poll. A
+// missing file is not an error, the game only writes it while running. A file
+// caught while being rewritten is read again on the next poll
+func (f *FileWatcher) Poll() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return nil
+	}
+
+	b, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	// handlers get a single line, like journal events
+	var buf bytes.Buffer
+	if json.Compact(&buf, b) != nil {
+		return nil
+	}
+	f.modTime = info.ModTime()
+	f.size = info.Size()
+	f.handlerFunc(buf.Bytes())
+	return nil
+}
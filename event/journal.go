@@ -0,0 +1,81 @@
+package event
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// journal file names are Journal.yyMMddHHmmss.NN.log before Odyssey and
+// Journal.yyyy-MM-ddTHHmmss.NN.log since, both in local time
+var journalRex = regexp.MustCompile(`^Journal\.(\d{12}|\d{4}-\d{2}-\d{2}T\d{6})\.(\d{2})\.log$`)
+
+const (
+	legacyJournalLayout = "060102150405"
+	journalLayout       = "2006-01-02T150405"
+)
+
+// JournalFile is a journal file with the time and part number from its name
+type JournalFile struct {
+	Path string
+	Time time.Time
+	Part int
+}
+
+// ParseJournalName parses the time and part number of a journal file name.
+// It reports false when the name is not a journal file name
+func ParseJournalName(path string) (JournalFile, bool) {
+	m := journalRex.FindStringSubmatch(filepath.Base(path))
+	if m == nil {
+		return JournalFile{}, false
+	}
+
+	layout := journalLayout
+	if len(m[1]) == len(legacyJournalLayout) {
+		layout = legacyJournalLayout
+	}
+	t, err := time.ParseInLocation(layout, m[1], time.Local)
+	if err != nil {
+		return JournalFile{}, false
+	}
+	part, err := strconv.Atoi(m[2])
+	if err != nil {
+		return JournalFile{}, false
+	}
+	return JournalFile{Path: path, Time: t, Part: part}, true
+}
+
+// SortJournals sorts journal files in the order they were written
+func SortJournals(files []JournalFile) {
+	sort.SliceStable(files, func(i, j int) bool {
+		if !files[i].Time.Equal(files[j].Time) {
+			return files[i].Time.Before(files[j].Time)
+		}
+		return files[i].Part < files[j].Part
+	})
+}
+
+// FindJournals returns the journal files in dir in the order they were
+// written
+func FindJournals(dir string) ([]JournalFile, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]JournalFile, 0, len(infos))
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		f, ok := ParseJournalName(filepath.Join(dir, info.Name()))
+		if ok {
+			files = append(files, f)
+		}
+	}
+	SortJournals(files)
+	return files, nil
+}
@@ -1,57 +1,125 @@
 package event
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"regexp"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/radovskyb/watcher"
 )
 
+// maxLine is the longest journal line read
+const maxLine = 4 * 1024 * 1024
+
+// Watcher tails the journal files in a directory and calls its handler with
+// every new line, in order, following the game when it rolls to a new file.
+// Its cursor can be saved so that a restarted watcher carries on where the
+// last one stopped
 type Watcher struct {
+	mu          sync.Mutex
 	watcher     *watcher.Watcher
+	dir         string
 	interval    time.Duration
 	handlerFunc Handler
-	journalRex  *regexp.Regexp
+	cursor      Cursor
+	cursorPath  string
+	verified    bool
+
+	// OnError is called with errors reading the journal when set
+	OnError func(err error)
+	// OnBatch is called with the cursor after every batch of lines read,
+	// when the handler has seen every line up to the cursor and no other. It
+	// is called with the lock held, so it must not call back into the watcher
+	OnBatch func(c Cursor)
+	// OnTick is called every interval from the goroutine reading the
+	// journal, between batches, so that events it triggers are dispatched in
+	// order with the journal events. It is called with the lock held, so it
+	// must not call back into the watcher
+	OnTick func(now time.Time)
 }
 
-//func NewWatcher(h Handler, d time.Duration) (*Watcher, error) {
-//	return &Watcher{
-//		watcher:     watcher.New(),
-//		interval:    d,
-//		handlerFunc: h,
-//	}, nil
-//}
+// NewWatcher returns a watcher for the journal directory dir, checking for
+// new lines every interval
+func NewWatcher(dir string, h Handler, d time.Duration) (*Watcher, error) {
+	if d <= 0 {
+		return nil, fmt.Errorf("invalid interval %s", d)
+	}
+	w := watcher.New()
+	w.FilterOps(watcher.Create, watcher.Write)
+	w.AddFilterHook(watcher.RegexFilterHook(journalRex, false))
+	err := w.Add(dir)
+	if err != nil {
+		return nil, err
+	}
 
-func (w *Watcher) Watch(path string) error {
-	err := w.watcher.Add(path)
+	return &Watcher{
+		watcher:     w,
+		dir:         dir,
+		interval:    d,
+		handlerFunc: h,
+		verified:    true,
+	}, nil
+}
+
+// Resume loads the cursor saved at path, when there is one, and saves the
+// cursor there after every batch of lines read. It must be called before
+// Start. Without a cursor the watcher starts at the beginning of the latest
+// journal file
+func (w *Watcher) Resume(path string) error {
+	c, err := LoadCursor(path)
 	if err != nil {
 		return err
 	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.cursor = c
+	w.cursorPath = path
+	w.verified = c.IsZero()
 	return nil
 }
 
-func (w *Watcher) Read(path string) {
-	b, err := ioutil.ReadFile(path)
-	if err != nil {
-		return
-	}
+// SetCursor moves the watcher to cursor c, which is checked against the
+// journal like a saved cursor. It must be called before Start
+func (w *Watcher) SetCursor(c Cursor) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 
-	w.handlerFunc(b)
+	w.cursor = c
+	w.verified = c.IsZero()
+}
+
+// Cursor returns the position of the last line read
+func (w *Watcher) Cursor() Cursor {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.cursor
 }
 
+// Start reads the lines written since the cursor and keeps watching for new
+// ones in the background
 func (w *Watcher) Start() {
-	fmt.Println("starting watcher")
 	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		w.Poll()
 		for {
 			select {
-			case event := <-w.watcher.Event:
-				fmt.Println("READ")
-				go w.Read(event.Path) // Print the event's info.
+			case now := <-ticker.C:
+				w.tick(now)
+			case <-w.watcher.Event:
+				w.Poll()
 			case err := <-w.watcher.Error:
-				fmt.Println(err)
-				continue
+				w.error(err)
 			case <-w.watcher.Closed:
 				return
 			}
@@ -63,6 +131,205 @@ func (w *Watcher) Start() {
 	}()
 }
 
+// Stop stops watching. The cursor is saved one last time
 func (w *Watcher) Stop() {
 	w.watcher.Close()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.save()
+}
+
+// Poll reads the lines written since the cursor, moving on to newer journal
+// files when the current one has been read to the end
+func (w *Watcher) Poll() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	files, err := FindJournals(w.dir)
+	if err != nil {
+		w.error(err)
+		return
+	}
+	if len(files) == 0 {
+		return
+	}
+
+	pending := w.pending(files)
+	for i, f := range pending {
+		name := filepath.Base(f.Path)
+		if name != w.cursor.File {
+			w.cursor = Cursor{File: name, Timestamp: w.cursor.Timestamp}
+			w.verified = true
+		}
+		err = w.read(f.Path, i == len(pending)-1)
+		if err != nil {
+			w.error(err)
+			break
+		}
+	}
+	w.save()
+	if w.OnBatch != nil {
+		w.OnBatch(w.cursor)
+	}
+}
+
+func (w *Watcher) tick(now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.OnTick != nil {
+		w.OnTick(now)
+	}
+}
+
+// pending returns the journal files left to read, starting with the cursor
+// file. Must be called with the lock held
+func (w *Watcher) pending(files []JournalFile) []JournalFile {
+	if w.cursor.IsZero() {
+		return files[len(files)-1:]
+	}
+
+	current, ok := ParseJournalName(w.cursor.File)
+	if !ok {
+		return files[len(files)-1:]
+	}
+	for i, f := range files {
+		if filepath.Base(f.Path) == w.cursor.File {
+			return files[i:]
+		}
+		// the cursor file is gone, carry on with the files written after it
+		if f.Time.After(current.Time) || (f.Time.Equal(current.Time) && f.Part > current.Part) {
+			return files[i:]
+		}
+	}
+	return nil
+}
+
+// read calls the handler with the complete lines after the cursor. An
+// unterminated last line is left for later, unless the game has already moved
+// on to a newer file. The handler is called with the lock held, so it must not
+// call back into the watcher
+func (w *Watcher) read(path string, last bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// a cursor that no longer matches the file means it was replaced, so read
+	// it again skipping the events already seen
+	var skip *skipper
+	if !w.verified {
+		if !w.cursor.verify(w.dir) {
+			skip = &skipper{until: w.cursor.Timestamp, hash: w.cursor.Hash}
+			w.cursor.Offset = 0
+		}
+		w.verified = true
+	}
+
+	_, err = f.Seek(w.cursor.Offset, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReaderSize(f, 64*1024)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF && (last || len(line) == 0) {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(line) > maxLine {
+			return bufio.ErrTooLong
+		}
+
+		w.cursor.Offset += int64(len(line))
+		b := bytes.TrimSpace(line)
+		if len(b) > 0 {
+			var e Event
+			_ = json.Unmarshal(b, &e)
+			lines := [][]byte{b}
+			if skip != nil {
+				lines = skip.next(e.Timestamp, b)
+				if skip.done {
+					skip = nil
+				}
+			}
+			for _, l := range lines {
+				w.emit(l)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if skip != nil {
+		// the last line seen was not found, emit the lines that shared its
+		// timestamp rather than lose them
+		for _, l := range skip.held {
+			w.emit(l)
+		}
+	}
+	return nil
+}
+
+// emit moves the cursor past a line and calls the handler with it. Must be
+// called with the lock held
+func (w *Watcher) emit(b []byte) {
+	var e Event
+	_ = json.Unmarshal(b, &e)
+	w.cursor.Timestamp = e.Timestamp
+	w.cursor.Hash = hashLine(b)
+	w.handlerFunc(b)
+}
+
+// skipper skips the lines of a replaced journal file up to the last line
+// seen. Lines before its timestamp are skipped outright, lines sharing it are
+// held back until the last line seen turns up
+type skipper struct {
+	until time.Time
+	hash  string
+	held  [][]byte
+	done  bool
+}
+
+// next returns the lines to emit after reading a line
+func (s *skipper) next(ts time.Time, b []byte) [][]byte {
+	switch {
+	case ts.Before(s.until):
+		return nil
+	case ts.Equal(s.until):
+		if hashLine(b) == s.hash {
+			s.held = nil
+			s.done = true
+			return nil
+		}
+		s.held = append(s.held, b)
+		return nil
+	}
+	lines := append(s.held, b)
+	s.held = nil
+	s.done = true
+	return lines
+}
+
+// save writes the cursor when it is persisted. Must be called with the lock
+// held
+func (w *Watcher) save() {
+	if w.cursorPath == "" {
+		return
+	}
+	err := w.cursor.Save(w.cursorPath)
+	if err != nil {
+		w.error(err)
+	}
+}
+
+func (w *Watcher) error(err error) {
+	if w.OnError != nil {
+		w.OnError(err)
+	}
 }
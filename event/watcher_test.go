@@ -0,0 +1,231 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	firstJournal  = "Journal.2021-01-01T000000.01.log"
+	secondJournal = "Journal.2021-01-01T000000.02.log"
+	thirdJournal  = "Journal.2021-01-02T000000.01.log"
+)
+
+// line returns a journal line for event n. Events in the same second share
+// their timestamp
+func line(n int) string {
+	ts := time.Date(2021, 1, 1, 0, 0, n/2, 0, time.UTC)
+	return fmt.Sprintf(`{ "timestamp":%q, "event":"Test", "N":%d }`+"\r\n", ts.Format(time.RFC3339), n)
+}
+
+func lines(from, to int) string {
+	var b strings.Builder
+	for n := from; n < to; n++ {
+		b.WriteString(line(n))
+	}
+	return b.String()
+}
+
+func writeJournal(t *testing.T, dir, name, s string) {
+	t.Helper()
+
+	err := ioutil.WriteFile(filepath.Join(dir, name), []byte(s), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func appendJournal(t *testing.T, dir, name, s string) {
+	t.Helper()
+
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	_, err = f.WriteString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// poller polls the journal with a watcher resumed from a saved cursor,
+// recording the events read
+type poller struct {
+	t      *testing.T
+	dir    string
+	cursor string
+	got    []int
+}
+
+func newPoller(t *testing.T) *poller {
+	dir := t.TempDir()
+	return &poller{
+		t:      t,
+		dir:    dir,
+		cursor: filepath.Join(t.TempDir(), "cursor.json"),
+	}
+}
+
+// poll reads the journal with a new watcher, as a restarted program would,
+// and checks the events read since the last poll
+func (p *poller) poll(want ...int) {
+	p.t.Helper()
+
+	p.got = nil
+	w, err := NewWatcher(p.dir, func(b []byte) {
+		var e struct {
+			N int
+		}
+		err := json.Unmarshal(b, &e)
+		if err != nil {
+			p.t.Errorf("handler got %q: %v", b, err)
+		}
+		p.got = append(p.got, e.N)
+	}, time.Second)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	w.OnError = func(err error) {
+		p.t.Error(err)
+	}
+	err = w.Resume(p.cursor)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	w.Poll()
+	w.Stop()
+
+	if len(want) == 0 {
+		want = nil
+	}
+	if !reflect.DeepEqual(p.got, want) {
+		p.t.Errorf("read %v, want %v", p.got, want)
+	}
+}
+
+func seq(from, to int) []int {
+	s := make([]int, 0, to-from)
+	for n := from; n < to; n++ {
+		s = append(s, n)
+	}
+	return s
+}
+
+func TestWatcherResume(t *testing.T) {
+	p := newPoller(t)
+	writeJournal(t, p.dir, firstJournal, lines(0, 3))
+	p.poll(seq(0, 3)...)
+	p.poll()
+
+	appendJournal(t, p.dir, firstJournal, lines(3, 5))
+	p.poll(3, 4)
+}
+
+func TestWatcherStartsAtLatestFile(t *testing.T) {
+	p := newPoller(t)
+	writeJournal(t, p.dir, firstJournal, lines(0, 3))
+	writeJournal(t, p.dir, secondJournal, lines(3, 5))
+	p.poll(3, 4)
+}
+
+func TestWatcherRollover(t *testing.T) {
+	p := newPoller(t)
+	writeJournal(t, p.dir, firstJournal, lines(0, 2))
+	p.poll(0, 1)
+
+	// the game wrote the end of the first file and moved on to two more
+	// while nothing was watching
+	appendJournal(t, p.dir, firstJournal, lines(2, 4))
+	writeJournal(t, p.dir, secondJournal, lines(4, 6))
+	writeJournal(t, p.dir, thirdJournal, lines(6, 8))
+	p.poll(seq(2, 8)...)
+
+	appendJournal(t, p.dir, thirdJournal, lines(8, 9))
+	p.poll(8)
+}
+
+func TestWatcherCursorFileDeleted(t *testing.T) {
+	p := newPoller(t)
+	writeJournal(t, p.dir, firstJournal, lines(0, 2))
+	p.poll(0, 1)
+
+	err := os.Remove(filepath.Join(p.dir, firstJournal))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeJournal(t, p.dir, thirdJournal, lines(4, 6))
+	p.poll(4, 5)
+}
+
+func TestWatcherPartialLine(t *testing.T) {
+	p := newPoller(t)
+	partial := line(2)
+	writeJournal(t, p.dir, firstJournal, lines(0, 2)+partial[:10])
+	p.poll(0, 1)
+
+	// the rest of the line turns up later
+	appendJournal(t, p.dir, firstJournal, partial[10:])
+	p.poll(2)
+
+	// an unterminated last line is complete once the game moved on
+	appendJournal(t, p.dir, firstJournal, strings.TrimSpace(line(3)))
+	p.poll()
+	writeJournal(t, p.dir, secondJournal, lines(4, 5))
+	p.poll(3, 4)
+}
+
+func TestWatcherReplacedFile(t *testing.T) {
+	p := newPoller(t)
+	writeJournal(t, p.dir, firstJournal, lines(0, 5))
+	p.poll(seq(0, 5)...)
+
+	// the file was rewritten with different line endings, so the offset in
+	// the cursor no longer matches. Events 4 and 5 share a timestamp
+	content := strings.Replace(lines(0, 8), "\r\n", "\n", -1)
+	writeJournal(t, p.dir, firstJournal, content)
+	p.poll(5, 6, 7)
+}
+
+func TestWatcherReplacedFileLastLineGone(t *testing.T) {
+	p := newPoller(t)
+	writeJournal(t, p.dir, firstJournal, lines(0, 5))
+	p.poll(seq(0, 5)...)
+
+	// the last line read is not in the new file, so there is no telling
+	// which of the lines sharing its timestamp are new: they are all read
+	// rather than lost
+	content := strings.Replace(lines(0, 4)+lines(5, 7), "\r\n", "\n", -1)
+	writeJournal(t, p.dir, firstJournal, content)
+	p.poll(5, 6)
+}
+
+func TestCursorSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cursor.json")
+	c, err := LoadCursor(path)
+	if err != nil || !c.IsZero() {
+		t.Fatalf("LoadCursor() of a missing file = %+v, %v", c, err)
+	}
+
+	want := Cursor{
+		File:      firstJournal,
+		Offset:    42,
+		Timestamp: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		Hash:      hashLine([]byte(line(0))),
+	}
+	err = want.Save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err = LoadCursor(path)
+	if err != nil || c != want {
+		t.Errorf("LoadCursor() = %+v, %v, want %+v", c, err, want)
+	}
+}
@@ -0,0 +1,52 @@
+package events
+
+import (
+	"encoding/json"
+
+	"github.com/sht/ed-journal/event"
+)
+
+const (
+	CarrierBankTransfer = "CarrierBankTransfer"
+	CarrierBuy          = "CarrierBuy"
+)
+
+type CarrierBankTransferEvent struct {
+	event.Event
+	CarrierID      int64 `json:"CarrierID"`
+	Deposit        int   `json:"Deposit,omitempty"`
+	Withdraw       int   `json:"Withdraw,omitempty"`
+	PlayerBalance  int   `json:"PlayerBalance"`
+	CarrierBalance int   `json:"CarrierBalance"`
+}
+
+func CarrierBankTransferEventHandler(b []byte) {
+	var e CarrierBankTransferEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type CarrierBuyEvent struct {
+	event.Event
+	BoughtAtMarket int64  `json:"BoughtAtMarket"`
+	CarrierID      int64  `json:"CarrierID"`
+	Location       string `json:"Location"`
+	SystemAddress  int    `json:"SystemAddress"`
+	Price          int    `json:"Price"`
+	Variant        string `json:"Variant"`
+	Callsign       string `json:"Callsign"`
+}
+
+func CarrierBuyEventHandler(b []byte) {
+	var e CarrierBuyEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
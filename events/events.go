@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/event"
 )
 
 // Event represents an incoming event from the journal
@@ -15,6 +16,13 @@ type Event struct {
 	Timestamp time.Time `json:"timestamp"`
 }
 
+// OnMismatch is called with an event whose struct definition does not round
+// trip it, i.e. is missing or misnames fields. It prints both encodings by
+// default
+var OnMismatch = func(original, parsed []byte) {
+	fmt.Printf("\n invalid event struct definition\n\noriginal:\n%s\n\nparsed:\n%s\n\n", original, parsed)
+}
+
 func debug(b1 []byte, e interface{}) {
 	b2, err := json.Marshal(e)
 	if err != nil {
@@ -49,7 +57,7 @@ func debug(b1 []byte, e interface{}) {
 	}
 
 	if bytes.Compare(b1, b2) != 0 {
-		fmt.Printf("\n invalid event struct definition\n\noriginal:\n%s\n\nparsed:\n%s\n\n", b1, b2)
+		OnMismatch(b1, b2)
 		return
 	}
 
@@ -57,43 +65,146 @@ func debug(b1 []byte, e interface{}) {
 }
 
 func AddListeners(d *dispatcher.Dispatcher) {
+	addListeners(d.On)
+}
+
+// AddSyncListeners registers the same handlers as AddListeners with OnSync,
+// so that OnMismatch is called before Trigger returns
+func AddSyncListeners(d *dispatcher.Dispatcher) {
+	addListeners(d.OnSync)
+}
+
+func addListeners(on func(name string, h event.Handler)) {
 	// startup
 	{
-		d.On(Cargo, CargoEventHandler)
-		d.On(ClearSavedGame, ClearSavedGameEventHandler)
-		d.On(Commander, CommanderEventHandler)
-		d.On(Loadout, LoadoutEventHandler)
-		d.On(Materials, MaterialsEventHandler)
-		d.On(Missions, MissionsEventHandler)
-		d.On(NewCommander, NewCommanderEventHandler)
-		d.On(LoadGame, LoadGameEventHandler)
-		d.On(Passengers, PassengersEventHandler)
-		d.On(Powerplay, PowerplayEventHandler)
-		d.On(Progress, ProgressEventHandler)
-		d.On(Rank, RankEventHandler)
-		d.On(Reputation, ReputationEventHandler)
-		d.On(Statistics, StatisticsEventHandler)
+		on(Cargo, CargoEventHandler)
+		on(ClearSavedGame, ClearSavedGameEventHandler)
+		on(Commander, CommanderEventHandler)
+		on(EngineerProgress, EngineerProgressEventHandler)
+		on(Fileheader, FileheaderEventHandler)
+		on(Loadout, LoadoutEventHandler)
+		on(Materials, MaterialsEventHandler)
+		on(Missions, MissionsEventHandler)
+		on(NewCommander, NewCommanderEventHandler)
+		on(LoadGame, LoadGameEventHandler)
+		on(Passengers, PassengersEventHandler)
+		on(Powerplay, PowerplayEventHandler)
+		on(Progress, ProgressEventHandler)
+		on(Rank, RankEventHandler)
+		on(Reputation, ReputationEventHandler)
+		on(Statistics, StatisticsEventHandler)
 	}
 
 	// travel
 	{
-		d.On(ApproachBody, ApproachBodyEventHandler)
-		d.On(Docked, DockedEventHandler)
-		d.On(DockingCancelled, DockingCancelledEventHandler)
-		d.On(DockingDenied, DockingDeniedEventHandler)
-		d.On(DockingGranted, DockingGrantedEventHandler)
-		d.On(DockingRequested, DockingRequestedEventHandler)
-		d.On(DockingTimeout, DockingTimeoutEventHandler)
-		d.On(FSDJump, FSDJumpEventHandler)
-		d.On(FSDTarget, FSDTargetEventHandler)
-		d.On(LeaveBody, LeaveBodyEventHandler)
-		d.On(Liftoff, LiftoffEventHandler)
-		d.On(Location, LocationEventHandler)
-		d.On(StartJump, StartJumpEventHandler)
-		d.On(SupercruiseEntry, SupercruiseEntryEventHandler)
-		d.On(SupercruiseExit, SupercruiseExitEventHandler)
-		d.On(Touchdown, TouchdownEventHandler)
-		d.On(Undocked, UndockedEventHandler)
-		d.On(Route, RouteEventHandler)
+		on(ApproachBody, ApproachBodyEventHandler)
+		on(Docked, DockedEventHandler)
+		on(DockingCancelled, DockingCancelledEventHandler)
+		on(DockingDenied, DockingDeniedEventHandler)
+		on(DockingGranted, DockingGrantedEventHandler)
+		on(DockingRequested, DockingRequestedEventHandler)
+		on(DockingTimeout, DockingTimeoutEventHandler)
+		on(FSDJump, FSDJumpEventHandler)
+		on(FSDTarget, FSDTargetEventHandler)
+		on(LeaveBody, LeaveBodyEventHandler)
+		on(Liftoff, LiftoffEventHandler)
+		on(Location, LocationEventHandler)
+		on(NavRoute, NavRouteEventHandler)
+		on(NavRouteClear, NavRouteClearEventHandler)
+		on(StartJump, StartJumpEventHandler)
+		on(SupercruiseEntry, SupercruiseEntryEventHandler)
+		on(SupercruiseExit, SupercruiseExitEventHandler)
+		on(Touchdown, TouchdownEventHandler)
+		on(Undocked, UndockedEventHandler)
+		on(Route, RouteEventHandler)
+	}
+
+	// exploration
+	{
+		on(BuyExplorationData, BuyExplorationDataEventHandler)
+		on(FSSAllBodiesFound, FSSAllBodiesFoundEventHandler)
+		on(FSSDiscoveryScan, FSSDiscoveryScanEventHandler)
+		on(MaterialCollected, MaterialCollectedEventHandler)
+		on(MaterialDiscarded, MaterialDiscardedEventHandler)
+		on(MultiSellExplorationData, MultiSellExplorationDataEventHandler)
+		on(SAAScanComplete, SAAScanCompleteEventHandler)
+		on(Scan, ScanEventHandler)
+		on(SellExplorationData, SellExplorationDataEventHandler)
+		on(SellOrganicData, SellOrganicDataEventHandler)
+	}
+
+	// trade
+	{
+		on(BuyTradeData, BuyTradeDataEventHandler)
+		on(MarketBuy, MarketBuyEventHandler)
+		on(MarketSell, MarketSellEventHandler)
+	}
+
+	// station services
+	{
+		on(BuyAmmo, BuyAmmoEventHandler)
+		on(BuyDrones, BuyDronesEventHandler)
+		on(CommunityGoalReward, CommunityGoalRewardEventHandler)
+		on(CrewHire, CrewHireEventHandler)
+		on(EngineerCraft, EngineerCraftEventHandler)
+		on(FetchRemoteModule, FetchRemoteModuleEventHandler)
+		on(MaterialTrade, MaterialTradeEventHandler)
+		on(MissionAbandoned, MissionAbandonedEventHandler)
+		on(MissionAccepted, MissionAcceptedEventHandler)
+		on(MissionCompleted, MissionCompletedEventHandler)
+		on(MissionFailed, MissionFailedEventHandler)
+		on(MissionRedirected, MissionRedirectedEventHandler)
+		on(ModuleBuy, ModuleBuyEventHandler)
+		on(ModuleRetrieve, ModuleRetrieveEventHandler)
+		on(ModuleSell, ModuleSellEventHandler)
+		on(ModuleSellRemote, ModuleSellRemoteEventHandler)
+		on(ModuleStore, ModuleStoreEventHandler)
+		on(PayBounties, PayBountiesEventHandler)
+		on(PayFines, PayFinesEventHandler)
+		on(RedeemVoucher, RedeemVoucherEventHandler)
+		on(RefuelAll, RefuelAllEventHandler)
+		on(RefuelPartial, RefuelPartialEventHandler)
+		on(Repair, RepairEventHandler)
+		on(RepairAll, RepairAllEventHandler)
+		on(RestockVehicle, RestockVehicleEventHandler)
+		on(SearchAndRescue, SearchAndRescueEventHandler)
+		on(SellDrones, SellDronesEventHandler)
+		on(SellShipOnRebuy, SellShipOnRebuyEventHandler)
+		on(SetUserShipName, SetUserShipNameEventHandler)
+		on(ShipyardBuy, ShipyardBuyEventHandler)
+		on(ShipyardNew, ShipyardNewEventHandler)
+		on(ShipyardSell, ShipyardSellEventHandler)
+		on(ShipyardSwap, ShipyardSwapEventHandler)
+		on(ShipyardTransfer, ShipyardTransferEventHandler)
+		on(StoredShips, StoredShipsEventHandler)
+		on(TechnologyBroker, TechnologyBrokerEventHandler)
+	}
+
+	// powerplay
+	{
+		on(PowerplayFastTrack, PowerplayFastTrackEventHandler)
+		on(PowerplaySalary, PowerplaySalaryEventHandler)
+	}
+
+	// fleet carriers
+	{
+		on(CarrierBankTransfer, CarrierBankTransferEventHandler)
+		on(CarrierBuy, CarrierBuyEventHandler)
+	}
+
+	// status file
+	{
+		on(Status, StatusEventHandler)
+	}
+
+	// other
+	{
+		on(FuelScoop, FuelScoopEventHandler)
+		on(NpcCrewPaidWage, NpcCrewPaidWageEventHandler)
+		on(Promotion, PromotionEventHandler)
+		on(ReservoirReplenished, ReservoirReplenishedEventHandler)
+		on(Resurrect, ResurrectEventHandler)
+		on(Shutdown, ShutdownEventHandler)
+		on(Synthesis, SynthesisEventHandler)
 	}
 }
@@ -0,0 +1,270 @@
+package events
+
+import (
+	"encoding/json"
+
+	"github.com/sht/ed-journal/event"
+)
+
+const (
+	BuyExplorationData       = "BuyExplorationData"
+	FSSAllBodiesFound        = "FSSAllBodiesFound"
+	FSSDiscoveryScan         = "FSSDiscoveryScan"
+	MaterialCollected        = "MaterialCollected"
+	MaterialDiscarded        = "MaterialDiscarded"
+	MultiSellExplorationData = "MultiSellExplorationData"
+	SAAScanComplete          = "SAAScanComplete"
+	Scan                     = "Scan"
+	SellExplorationData      = "SellExplorationData"
+	SellOrganicData          = "SellOrganicData"
+)
+
+type BuyExplorationDataEvent struct {
+	event.Event
+	System string `json:"System"`
+	Cost   int    `json:"Cost"`
+}
+
+func BuyExplorationDataEventHandler(b []byte) {
+	var e BuyExplorationDataEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type FSSAllBodiesFoundEvent struct {
+	event.Event
+	SystemName    string `json:"SystemName"`
+	SystemAddress int    `json:"SystemAddress"`
+	Count         int    `json:"Count"`
+}
+
+func FSSAllBodiesFoundEventHandler(b []byte) {
+	var e FSSAllBodiesFoundEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type FSSDiscoveryScanEvent struct {
+	event.Event
+	Progress      float64 `json:"Progress"`
+	BodyCount     int     `json:"BodyCount"`
+	NonBodyCount  int     `json:"NonBodyCount"`
+	SystemName    string  `json:"SystemName"`
+	SystemAddress int     `json:"SystemAddress"`
+}
+
+func FSSDiscoveryScanEventHandler(b []byte) {
+	var e FSSDiscoveryScanEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type MaterialCollectedEvent struct {
+	event.Event
+	Category      string `json:"Category"`
+	Name          string `json:"Name"`
+	NameLocalised string `json:"Name_Localised,omitempty"`
+	Count         int    `json:"Count"`
+}
+
+func MaterialCollectedEventHandler(b []byte) {
+	var e MaterialCollectedEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type MaterialDiscardedEvent struct {
+	event.Event
+	Category      string `json:"Category"`
+	Name          string `json:"Name"`
+	NameLocalised string `json:"Name_Localised,omitempty"`
+	Count         int    `json:"Count"`
+}
+
+func MaterialDiscardedEventHandler(b []byte) {
+	var e MaterialDiscardedEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type MultiSellExplorationDataEvent struct {
+	event.Event
+	Discovered []*struct {
+		SystemName          string `json:"SystemName"`
+		SystemNameLocalised string `json:"SystemName_Localised,omitempty"`
+		NumBodies           int    `json:"NumBodies"`
+	} `json:"Discovered"`
+	BaseValue     int `json:"BaseValue"`
+	Bonus         int `json:"Bonus"`
+	TotalEarnings int `json:"TotalEarnings"`
+}
+
+func MultiSellExplorationDataEventHandler(b []byte) {
+	var e MultiSellExplorationDataEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type SAAScanCompleteEvent struct {
+	event.Event
+	BodyName         string `json:"BodyName"`
+	SystemAddress    int    `json:"SystemAddress"`
+	BodyID           int    `json:"BodyID"`
+	ProbesUsed       int    `json:"ProbesUsed"`
+	EfficiencyTarget int    `json:"EfficiencyTarget"`
+}
+
+func SAAScanCompleteEventHandler(b []byte) {
+	var e SAAScanCompleteEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type ScanEvent struct {
+	event.Event
+	ScanType              string           `json:"ScanType"`
+	BodyName              string           `json:"BodyName"`
+	BodyID                int              `json:"BodyID"`
+	Parents               []map[string]int `json:"Parents,omitempty"`
+	StarSystem            string           `json:"StarSystem"`
+	SystemAddress         int              `json:"SystemAddress"`
+	DistanceFromArrivalLS float64          `json:"DistanceFromArrivalLS"`
+
+	// stars
+	StarType          string  `json:"StarType,omitempty"`
+	Subclass          *int    `json:"Subclass,omitempty"`
+	StellarMass       float64 `json:"StellarMass,omitempty"`
+	AbsoluteMagnitude float64 `json:"AbsoluteMagnitude,omitempty"`
+	AgeMY             int     `json:"Age_MY,omitempty"`
+	Luminosity        string  `json:"Luminosity,omitempty"`
+
+	// planets
+	TidalLock             *bool   `json:"TidalLock,omitempty"`
+	TerraformState        *string `json:"TerraformState,omitempty"`
+	PlanetClass           string  `json:"PlanetClass,omitempty"`
+	Atmosphere            *string `json:"Atmosphere,omitempty"`
+	AtmosphereType        string  `json:"AtmosphereType,omitempty"`
+	AtmosphereComposition []*struct {
+		Name    string  `json:"Name"`
+		Percent float64 `json:"Percent"`
+	} `json:"AtmosphereComposition,omitempty"`
+	Volcanism       *string `json:"Volcanism,omitempty"`
+	MassEM          float64 `json:"MassEM,omitempty"`
+	SurfaceGravity  float64 `json:"SurfaceGravity,omitempty"`
+	SurfacePressure float64 `json:"SurfacePressure,omitempty"`
+	Landable        *bool   `json:"Landable,omitempty"`
+	Materials       []*struct {
+		Name    string  `json:"Name"`
+		Percent float64 `json:"Percent"`
+	} `json:"Materials,omitempty"`
+	Composition *struct {
+		Ice   float64 `json:"Ice"`
+		Rock  float64 `json:"Rock"`
+		Metal float64 `json:"Metal"`
+	} `json:"Composition,omitempty"`
+
+	// stars and planets
+	Radius             float64 `json:"Radius,omitempty"`
+	SurfaceTemperature float64 `json:"SurfaceTemperature,omitempty"`
+	SemiMajorAxis      float64 `json:"SemiMajorAxis,omitempty"`
+	Eccentricity       float64 `json:"Eccentricity,omitempty"`
+	OrbitalInclination float64 `json:"OrbitalInclination,omitempty"`
+	Periapsis          float64 `json:"Periapsis,omitempty"`
+	OrbitalPeriod      float64 `json:"OrbitalPeriod,omitempty"`
+	AscendingNode      float64 `json:"AscendingNode,omitempty"`
+	MeanAnomaly        float64 `json:"MeanAnomaly,omitempty"`
+	RotationPeriod     float64 `json:"RotationPeriod,omitempty"`
+	AxialTilt          float64 `json:"AxialTilt,omitempty"`
+	Rings              []*struct {
+		Name      string  `json:"Name"`
+		RingClass string  `json:"RingClass"`
+		MassMT    float64 `json:"MassMT"`
+		InnerRad  float64 `json:"InnerRad"`
+		OuterRad  float64 `json:"OuterRad"`
+	} `json:"Rings,omitempty"`
+	ReserveLevel  string `json:"ReserveLevel,omitempty"`
+	WasDiscovered bool   `json:"WasDiscovered"`
+	WasMapped     bool   `json:"WasMapped"`
+}
+
+func ScanEventHandler(b []byte) {
+	var e ScanEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type SellExplorationDataEvent struct {
+	event.Event
+	Systems       []string `json:"Systems"`
+	Discovered    []string `json:"Discovered"`
+	BaseValue     int      `json:"BaseValue"`
+	Bonus         int      `json:"Bonus"`
+	TotalEarnings int      `json:"TotalEarnings"`
+}
+
+func SellExplorationDataEventHandler(b []byte) {
+	var e SellExplorationDataEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type SellOrganicDataEvent struct {
+	event.Event
+	MarketID int `json:"MarketID"`
+	BioData  []*struct {
+		Genus            string `json:"Genus"`
+		GenusLocalised   string `json:"Genus_Localised,omitempty"`
+		Species          string `json:"Species"`
+		SpeciesLocalised string `json:"Species_Localised,omitempty"`
+		Variant          string `json:"Variant,omitempty"`
+		VariantLocalised string `json:"Variant_Localised,omitempty"`
+		Value            int    `json:"Value"`
+		Bonus            int    `json:"Bonus"`
+	} `json:"BioData"`
+}
+
+func SellOrganicDataEventHandler(b []byte) {
+	var e SellOrganicDataEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
@@ -0,0 +1,136 @@
+package events
+
+import (
+	"encoding/json"
+
+	"github.com/sht/ed-journal/event"
+)
+
+const (
+	FuelScoop            = "FuelScoop"
+	NpcCrewPaidWage      = "NpcCrewPaidWage"
+	Promotion            = "Promotion"
+	ReservoirReplenished = "ReservoirReplenished"
+	Resurrect            = "Resurrect"
+	Shutdown             = "Shutdown"
+	Synthesis            = "Synthesis"
+)
+
+type FuelScoopEvent struct {
+	event.Event
+	Scooped float64 `json:"Scooped"`
+	Total   float64 `json:"Total"`
+}
+
+func FuelScoopEventHandler(b []byte) {
+	var e FuelScoopEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type NpcCrewPaidWageEvent struct {
+	event.Event
+	NpcCrewName string `json:"NpcCrewName"`
+	NpcCrewID   int    `json:"NpcCrewId"`
+	Amount      int    `json:"Amount"`
+}
+
+func NpcCrewPaidWageEventHandler(b []byte) {
+	var e NpcCrewPaidWageEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+// PromotionEvent holds the new rank for the single category that was promoted
+type PromotionEvent struct {
+	event.Event
+	Combat       *int `json:"Combat,omitempty"`
+	Trade        *int `json:"Trade,omitempty"`
+	Explore      *int `json:"Explore,omitempty"`
+	Empire       *int `json:"Empire,omitempty"`
+	Federation   *int `json:"Federation,omitempty"`
+	CQC          *int `json:"CQC,omitempty"`
+	Soldier      *int `json:"Soldier,omitempty"`
+	Exobiologist *int `json:"Exobiologist,omitempty"`
+}
+
+func PromotionEventHandler(b []byte) {
+	var e PromotionEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type ReservoirReplenishedEvent struct {
+	event.Event
+	FuelMain      float64 `json:"FuelMain"`
+	FuelReservoir float64 `json:"FuelReservoir"`
+}
+
+func ReservoirReplenishedEventHandler(b []byte) {
+	var e ReservoirReplenishedEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type ResurrectEvent struct {
+	event.Event
+	Option   string `json:"Option"`
+	Cost     int    `json:"Cost"`
+	Bankrupt bool   `json:"Bankrupt"`
+}
+
+func ResurrectEventHandler(b []byte) {
+	var e ResurrectEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type ShutdownEvent struct {
+	event.Event
+}
+
+func ShutdownEventHandler(b []byte) {
+	var e ShutdownEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type SynthesisEvent struct {
+	event.Event
+	Name      string           `json:"Name"`
+	Materials []*MaterialCount `json:"Materials"`
+}
+
+func SynthesisEventHandler(b []byte) {
+	var e SynthesisEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
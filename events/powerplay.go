@@ -0,0 +1,44 @@
+package events
+
+import (
+	"encoding/json"
+
+	"github.com/sht/ed-journal/event"
+)
+
+const (
+	PowerplayFastTrack = "PowerplayFastTrack"
+	PowerplaySalary    = "PowerplaySalary"
+)
+
+type PowerplayFastTrackEvent struct {
+	event.Event
+	Power string `json:"Power"`
+	Cost  int    `json:"Cost"`
+}
+
+func PowerplayFastTrackEventHandler(b []byte) {
+	var e PowerplayFastTrackEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type PowerplaySalaryEvent struct {
+	event.Event
+	Power  string `json:"Power"`
+	Amount int    `json:"Amount"`
+}
+
+func PowerplaySalaryEventHandler(b []byte) {
+	var e PowerplaySalaryEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
@@ -8,20 +8,22 @@ import (
 )
 
 const (
-	Cargo          = "Cargo"
-	ClearSavedGame = "ClearSavedGame"
-	Commander      = "Commander"
-	Loadout        = "Loadout"
-	Materials      = "Materials"
-	Missions       = "Missions"
-	NewCommander   = "NewCommander"
-	LoadGame       = "LoadGame"
-	Passengers     = "Passengers"
-	Powerplay      = "Powerplay"
-	Progress       = "Progress"
-	Rank           = "Rank"
-	Reputation     = "Reputation"
-	Statistics     = "Statistics"
+	Cargo            = "Cargo"
+	ClearSavedGame   = "ClearSavedGame"
+	Commander        = "Commander"
+	EngineerProgress = "EngineerProgress"
+	Fileheader       = "Fileheader"
+	Loadout          = "Loadout"
+	Materials        = "Materials"
+	Missions         = "Missions"
+	NewCommander     = "NewCommander"
+	LoadGame         = "LoadGame"
+	Passengers       = "Passengers"
+	Powerplay        = "Powerplay"
+	Progress         = "Progress"
+	Rank             = "Rank"
+	Reputation       = "Reputation"
+	Statistics       = "Statistics"
 )
 
 type CargoEvent struct {
@@ -80,6 +82,58 @@ func CommanderEventHandler(b []byte) {
 	debug(b, e)
 }
 
+// EngineerProgressEvent is written with every engineer at startup, and with a
+// single engineer when the commander's progress with them changes
+type EngineerProgressEvent struct {
+	event.Event
+	Engineers    []*EngineerStatus `json:"Engineers,omitempty"`
+	Engineer     string            `json:"Engineer,omitempty"`
+	EngineerID   int               `json:"EngineerID,omitempty"`
+	Progress     string            `json:"Progress,omitempty"`
+	Rank         *int              `json:"Rank,omitempty"`
+	RankProgress *int              `json:"RankProgress,omitempty"`
+}
+
+// EngineerStatus is the commander's progress with an engineer. Rank is the
+// highest grade unlocked, only set once the engineer is unlocked
+type EngineerStatus struct {
+	Engineer     string `json:"Engineer"`
+	EngineerID   int    `json:"EngineerID"`
+	Progress     string `json:"Progress"`
+	Rank         *int   `json:"Rank,omitempty"`
+	RankProgress *int   `json:"RankProgress,omitempty"`
+}
+
+func EngineerProgressEventHandler(b []byte) {
+	var e EngineerProgressEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type FileheaderEvent struct {
+	event.Event
+	Part        int    `json:"part"`
+	Language    string `json:"language"`
+	Odyssey     *bool  `json:"Odyssey,omitempty"`
+	GameVersion string `json:"gameversion"`
+	Build       string `json:"build"`
+}
+
+func FileheaderEventHandler(b []byte) {
+	var e FileheaderEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	debug(b, e)
+}
+
 type LoadoutEvent struct {
 	event.Event
 	Ship         string  `json:"Ship"`
@@ -94,36 +148,48 @@ type LoadoutEvent struct {
 		Main    float64 `json:"Main"`
 		Reserve float64 `json:"Reserve"`
 	} `json:"FuelCapacity"`
-	CargoCapacity int     `json:"CargoCapacity"`
-	MaxJumpRange  float64 `json:"MaxJumpRange"`
-	Rebuy         int     `json:"Rebuy"`
-	Hot           bool    `json:"Hot,omitempty"`
-	Modules       []*struct {
-		Slot         string   `json:"Slot"`
-		Item         string   `json:"Item"`
-		On           bool     `json:"On"`
-		Priority     int      `json:"Priority"`
-		Health       float64  `json:"Health"`
-		Value        *float64 `json:"Value,omitempty"`
-		AmmoInClip   *int     `json:"AmmoInClip,omitempty"`
-		AmmoInHopper *int     `json:"AmmoInHopper,omitempty"`
-		Engineering  *struct {
-			Engineer                    string  `json:"Engineer"`
-			EngineerID                  uint    `json:"EngineerID"`
-			BlueprintName               string  `json:"BlueprintName"`
-			BlueprintID                 int     `json:"BlueprintID"`
-			Level                       int     `json:"Level"`
-			Quality                     float64 `json:"Quality"`
-			ExperimentalEffect          string  `json:"ExperimentalEffect,omitempty"`
-			ExperimentalEffectLocalised string  `json:"ExperimentalEffect_Localised,omitempty"`
-			Modifiers                   []*struct {
-				Label         string   `json:"Label"`
-				Value         *float64 `json:"Value,omitempty"`
-				OriginalValue float64  `json:"OriginalValue"`
-				LessIsGood    int      `json:"LessIsGood"`
-			} `json:"Modifiers"`
-		} `json:"Engineering,omitempty"`
-	} `json:"Modules"`
+	CargoCapacity int              `json:"CargoCapacity"`
+	MaxJumpRange  float64          `json:"MaxJumpRange"`
+	Rebuy         int              `json:"Rebuy"`
+	Hot           bool             `json:"Hot,omitempty"`
+	Modules       []*LoadoutModule `json:"Modules"`
+}
+
+// LoadoutModule is a module fitted to the ship
+type LoadoutModule struct {
+	Slot         string       `json:"Slot"`
+	Item         string       `json:"Item"`
+	On           bool         `json:"On"`
+	Priority     int          `json:"Priority"`
+	Health       float64      `json:"Health"`
+	Value        *float64     `json:"Value,omitempty"`
+	AmmoInClip   *int         `json:"AmmoInClip,omitempty"`
+	AmmoInHopper *int         `json:"AmmoInHopper,omitempty"`
+	Engineering  *Engineering `json:"Engineering,omitempty"`
+}
+
+// Engineering is the blueprint and experimental effect applied to a module
+type Engineering struct {
+	Engineer                    string                 `json:"Engineer"`
+	EngineerID                  uint                   `json:"EngineerID"`
+	BlueprintName               string                 `json:"BlueprintName"`
+	BlueprintID                 int                    `json:"BlueprintID"`
+	Level                       int                    `json:"Level"`
+	Quality                     float64                `json:"Quality"`
+	ExperimentalEffect          string                 `json:"ExperimentalEffect,omitempty"`
+	ExperimentalEffectLocalised string                 `json:"ExperimentalEffect_Localised,omitempty"`
+	Modifiers                   []*EngineeringModifier `json:"Modifiers"`
+}
+
+// EngineeringModifier is a module attribute changed by engineering. Value is
+// not set for modifiers with a text value
+type EngineeringModifier struct {
+	Label             string   `json:"Label"`
+	Value             *float64 `json:"Value,omitempty"`
+	OriginalValue     float64  `json:"OriginalValue"`
+	LessIsGood        int      `json:"LessIsGood"`
+	ValueStr          string   `json:"ValueStr,omitempty"`
+	ValueStrLocalised string   `json:"ValueStr_Localised,omitempty"`
 }
 
 func LoadoutEventHandler(b []byte) {
@@ -237,6 +303,10 @@ func LoadGameEventHandler(b []byte) {
 
 type PassengersEvent struct {
 	event.Event
+	Manifest []*Passenger `json:"Manifest"`
+}
+
+type Passenger struct {
 	MissionID int    `json:"MissionID"`
 	Type      string `json:"Type"`
 	VIP       bool   `json:"VIP"`
@@ -277,12 +347,14 @@ func PowerplayEventHandler(b []byte) {
 
 type ProgressEvent struct {
 	event.Event
-	Combat     int `json:"Combat"`
-	Trade      int `json:"Trade"`
-	Explore    int `json:"Explore"`
-	Empire     int `json:"Empire"`
-	Federation int `json:"Federation"`
-	CQC        int `json:"CQC"`
+	Combat       int  `json:"Combat"`
+	Trade        int  `json:"Trade"`
+	Explore      int  `json:"Explore"`
+	Empire       int  `json:"Empire"`
+	Federation   int  `json:"Federation"`
+	CQC          int  `json:"CQC"`
+	Soldier      *int `json:"Soldier,omitempty"`
+	Exobiologist *int `json:"Exobiologist,omitempty"`
 }
 
 func ProgressEventHandler(b []byte) {
@@ -298,12 +370,14 @@ func ProgressEventHandler(b []byte) {
 
 type RankEvent struct {
 	event.Event
-	Combat     int `json:"Combat"`
-	Trade      int `json:"Trade"`
-	Explore    int `json:"Explore"`
-	Empire     int `json:"Empire"`
-	Federation int `json:"Federation"`
-	CQC        int `json:"CQC"`
+	Combat       int  `json:"Combat"`
+	Trade        int  `json:"Trade"`
+	Explore      int  `json:"Explore"`
+	Empire       int  `json:"Empire"`
+	Federation   int  `json:"Federation"`
+	CQC          int  `json:"CQC"`
+	Soldier      *int `json:"Soldier,omitempty"`
+	Exobiologist *int `json:"Exobiologist,omitempty"`
 }
 
 func RankEventHandler(b []byte) {
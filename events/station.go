@@ -0,0 +1,862 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/sht/ed-journal/event"
+)
+
+const (
+	BuyAmmo             = "BuyAmmo"
+	BuyDrones           = "BuyDrones"
+	CommunityGoalReward = "CommunityGoalReward"
+	CrewHire            = "CrewHire"
+	EngineerCraft       = "EngineerCraft"
+	FetchRemoteModule   = "FetchRemoteModule"
+	MaterialTrade       = "MaterialTrade"
+	MissionAbandoned    = "MissionAbandoned"
+	MissionAccepted     = "MissionAccepted"
+	MissionCompleted    = "MissionCompleted"
+	MissionFailed       = "MissionFailed"
+	MissionRedirected   = "MissionRedirected"
+	ModuleBuy           = "ModuleBuy"
+	ModuleRetrieve      = "ModuleRetrieve"
+	ModuleSell          = "ModuleSell"
+	ModuleSellRemote    = "ModuleSellRemote"
+	ModuleStore         = "ModuleStore"
+	PayBounties         = "PayBounties"
+	PayFines            = "PayFines"
+	RedeemVoucher       = "RedeemVoucher"
+	RefuelAll           = "RefuelAll"
+	RefuelPartial       = "RefuelPartial"
+	Repair              = "Repair"
+	RepairAll           = "RepairAll"
+	RestockVehicle      = "RestockVehicle"
+	SearchAndRescue     = "SearchAndRescue"
+	SellDrones          = "SellDrones"
+	SellShipOnRebuy     = "SellShipOnRebuy"
+	SetUserShipName     = "SetUserShipName"
+	ShipyardBuy         = "ShipyardBuy"
+	ShipyardNew         = "ShipyardNew"
+	ShipyardSell        = "ShipyardSell"
+	ShipyardSwap        = "ShipyardSwap"
+	ShipyardTransfer    = "ShipyardTransfer"
+	StoredShips         = "StoredShips"
+	TechnologyBroker    = "TechnologyBroker"
+)
+
+// MaterialCount is a material name and quantity, as used in ingredient and
+// reward lists
+type MaterialCount struct {
+	Name              string `json:"Name"`
+	NameLocalised     string `json:"Name_Localised,omitempty"`
+	Category          string `json:"Category,omitempty"`
+	CategoryLocalised string `json:"Category_Localised,omitempty"`
+	Count             int    `json:"Count"`
+}
+
+type BuyAmmoEvent struct {
+	event.Event
+	Cost int `json:"Cost"`
+}
+
+func BuyAmmoEventHandler(b []byte) {
+	var e BuyAmmoEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type BuyDronesEvent struct {
+	event.Event
+	Type      string `json:"Type"`
+	Count     int    `json:"Count"`
+	BuyPrice  int    `json:"BuyPrice"`
+	TotalCost int    `json:"TotalCost"`
+}
+
+func BuyDronesEventHandler(b []byte) {
+	var e BuyDronesEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type CommunityGoalRewardEvent struct {
+	event.Event
+	CGID   int    `json:"CGID"`
+	Name   string `json:"Name"`
+	System string `json:"System"`
+	Reward int    `json:"Reward"`
+}
+
+func CommunityGoalRewardEventHandler(b []byte) {
+	var e CommunityGoalRewardEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type CrewHireEvent struct {
+	event.Event
+	Name       string `json:"Name"`
+	CrewID     int    `json:"CrewID"`
+	Faction    string `json:"Faction"`
+	Cost       int    `json:"Cost"`
+	CombatRank int    `json:"CombatRank"`
+}
+
+func CrewHireEventHandler(b []byte) {
+	var e CrewHireEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type EngineerCraftEvent struct {
+	event.Event
+	Slot                        string           `json:"Slot"`
+	Module                      string           `json:"Module"`
+	Ingredients                 []*MaterialCount `json:"Ingredients"`
+	Engineer                    string           `json:"Engineer,omitempty"`
+	EngineerID                  int              `json:"EngineerID"`
+	BlueprintID                 int              `json:"BlueprintID"`
+	BlueprintName               string           `json:"BlueprintName"`
+	Level                       int              `json:"Level"`
+	Quality                     float64          `json:"Quality"`
+	ApplyExperimentalEffect     string           `json:"ApplyExperimentalEffect,omitempty"`
+	ExperimentalEffect          string           `json:"ExperimentalEffect,omitempty"`
+	ExperimentalEffectLocalised string           `json:"ExperimentalEffect_Localised,omitempty"`
+	Modifiers                   []*struct {
+		Label         string   `json:"Label"`
+		Value         *float64 `json:"Value,omitempty"`
+		OriginalValue *float64 `json:"OriginalValue,omitempty"`
+		LessIsGood    int      `json:"LessIsGood"`
+		ValueStr      string   `json:"ValueStr,omitempty"`
+	} `json:"Modifiers"`
+}
+
+func EngineerCraftEventHandler(b []byte) {
+	var e EngineerCraftEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type FetchRemoteModuleEvent struct {
+	event.Event
+	StorageSlot         int    `json:"StorageSlot"`
+	StoredItem          string `json:"StoredItem"`
+	StoredItemLocalised string `json:"StoredItem_Localised,omitempty"`
+	ServerID            int    `json:"ServerId"`
+	TransferCost        int    `json:"TransferCost"`
+	TransferTime        int    `json:"TransferTime"`
+	Ship                string `json:"Ship"`
+	ShipID              int    `json:"ShipID"`
+}
+
+func FetchRemoteModuleEventHandler(b []byte) {
+	var e FetchRemoteModuleEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type MaterialTradeEvent struct {
+	event.Event
+	MarketID   int             `json:"MarketID"`
+	TraderType string          `json:"TraderType"`
+	Paid       *TradedMaterial `json:"Paid"`
+	Received   *TradedMaterial `json:"Received"`
+}
+
+type TradedMaterial struct {
+	Material          string `json:"Material"`
+	MaterialLocalised string `json:"Material_Localised,omitempty"`
+	Category          string `json:"Category"`
+	CategoryLocalised string `json:"Category_Localised,omitempty"`
+	Quantity          int    `json:"Quantity"`
+}
+
+func MaterialTradeEventHandler(b []byte) {
+	var e MaterialTradeEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type MissionAbandonedEvent struct {
+	event.Event
+	Name          string `json:"Name"`
+	LocalisedName string `json:"LocalisedName,omitempty"`
+	MissionID     int    `json:"MissionID"`
+	Fine          int    `json:"Fine,omitempty"`
+}
+
+func MissionAbandonedEventHandler(b []byte) {
+	var e MissionAbandonedEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type MissionAcceptedEvent struct {
+	event.Event
+	Faction               string     `json:"Faction"`
+	Name                  string     `json:"Name"`
+	LocalisedName         string     `json:"LocalisedName,omitempty"`
+	Commodity             string     `json:"Commodity,omitempty"`
+	CommodityLocalised    string     `json:"Commodity_Localised,omitempty"`
+	Count                 int        `json:"Count,omitempty"`
+	Donation              string     `json:"Donation,omitempty"`
+	Target                string     `json:"Target,omitempty"`
+	TargetLocalised       string     `json:"Target_Localised,omitempty"`
+	TargetType            string     `json:"TargetType,omitempty"`
+	TargetTypeLocalised   string     `json:"TargetType_Localised,omitempty"`
+	TargetFaction         string     `json:"TargetFaction,omitempty"`
+	KillCount             int        `json:"KillCount,omitempty"`
+	DestinationSystem     string     `json:"DestinationSystem,omitempty"`
+	DestinationStation    string     `json:"DestinationStation,omitempty"`
+	DestinationSettlement string     `json:"DestinationSettlement,omitempty"`
+	Expiry                *time.Time `json:"Expiry,omitempty"`
+	Wing                  bool       `json:"Wing"`
+	Influence             string     `json:"Influence"`
+	Reputation            string     `json:"Reputation"`
+	Reward                int        `json:"Reward,omitempty"`
+	PassengerCount        int        `json:"PassengerCount,omitempty"`
+	PassengerVIPs         bool       `json:"PassengerVIPs,omitempty"`
+	PassengerWanted       bool       `json:"PassengerWanted,omitempty"`
+	PassengerType         string     `json:"PassengerType,omitempty"`
+	MissionID             int        `json:"MissionID"`
+}
+
+func MissionAcceptedEventHandler(b []byte) {
+	var e MissionAcceptedEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type MissionCompletedEvent struct {
+	event.Event
+	Faction               string           `json:"Faction"`
+	Name                  string           `json:"Name"`
+	LocalisedName         string           `json:"LocalisedName,omitempty"`
+	MissionID             int              `json:"MissionID"`
+	Commodity             string           `json:"Commodity,omitempty"`
+	CommodityLocalised    string           `json:"Commodity_Localised,omitempty"`
+	Count                 int              `json:"Count,omitempty"`
+	Target                string           `json:"Target,omitempty"`
+	TargetType            string           `json:"TargetType,omitempty"`
+	TargetTypeLocalised   string           `json:"TargetType_Localised,omitempty"`
+	TargetFaction         string           `json:"TargetFaction,omitempty"`
+	DestinationSystem     string           `json:"DestinationSystem,omitempty"`
+	DestinationStation    string           `json:"DestinationStation,omitempty"`
+	DestinationSettlement string           `json:"DestinationSettlement,omitempty"`
+	NewDestinationSystem  string           `json:"NewDestinationSystem,omitempty"`
+	NewDestinationStation string           `json:"NewDestinationStation,omitempty"`
+	KillCount             int              `json:"KillCount,omitempty"`
+	Reward                int              `json:"Reward,omitempty"`
+	Donation              string           `json:"Donation,omitempty"`
+	Donated               int              `json:"Donated,omitempty"`
+	PermitsAwarded        []string         `json:"PermitsAwarded,omitempty"`
+	CommodityReward       []*MaterialCount `json:"CommodityReward,omitempty"`
+	MaterialsReward       []*MaterialCount `json:"MaterialsReward,omitempty"`
+	FactionEffects        []*struct {
+		Faction string `json:"Faction"`
+		Effects []*struct {
+			Effect          string `json:"Effect"`
+			EffectLocalised string `json:"Effect_Localised"`
+			Trend           string `json:"Trend"`
+		} `json:"Effects"`
+		Influence []*struct {
+			SystemAddress int    `json:"SystemAddress"`
+			Trend         string `json:"Trend"`
+			Influence     string `json:"Influence"`
+		} `json:"Influence"`
+		ReputationTrend string `json:"ReputationTrend"`
+		Reputation      string `json:"Reputation"`
+	} `json:"FactionEffects,omitempty"`
+}
+
+func MissionCompletedEventHandler(b []byte) {
+	var e MissionCompletedEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type MissionFailedEvent struct {
+	event.Event
+	Name          string `json:"Name"`
+	LocalisedName string `json:"LocalisedName,omitempty"`
+	MissionID     int    `json:"MissionID"`
+	Fine          int    `json:"Fine,omitempty"`
+}
+
+func MissionFailedEventHandler(b []byte) {
+	var e MissionFailedEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type MissionRedirectedEvent struct {
+	event.Event
+	MissionID             int    `json:"MissionID"`
+	Name                  string `json:"Name"`
+	LocalisedName         string `json:"LocalisedName,omitempty"`
+	NewDestinationStation string `json:"NewDestinationStation"`
+	NewDestinationSystem  string `json:"NewDestinationSystem"`
+	OldDestinationStation string `json:"OldDestinationStation"`
+	OldDestinationSystem  string `json:"OldDestinationSystem"`
+}
+
+func MissionRedirectedEventHandler(b []byte) {
+	var e MissionRedirectedEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type ModuleBuyEvent struct {
+	event.Event
+	Slot                string `json:"Slot"`
+	BuyItem             string `json:"BuyItem"`
+	BuyItemLocalised    string `json:"BuyItem_Localised,omitempty"`
+	MarketID            int    `json:"MarketID"`
+	BuyPrice            int    `json:"BuyPrice"`
+	SellItem            string `json:"SellItem,omitempty"`
+	SellItemLocalised   string `json:"SellItem_Localised,omitempty"`
+	SellPrice           int    `json:"SellPrice,omitempty"`
+	StoredItem          string `json:"StoredItem,omitempty"`
+	StoredItemLocalised string `json:"StoredItem_Localised,omitempty"`
+	Ship                string `json:"Ship"`
+	ShipID              int    `json:"ShipID"`
+}
+
+func ModuleBuyEventHandler(b []byte) {
+	var e ModuleBuyEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type ModuleRetrieveEvent struct {
+	event.Event
+	MarketID               int     `json:"MarketID"`
+	Slot                   string  `json:"Slot"`
+	RetrievedItem          string  `json:"RetrievedItem"`
+	RetrievedItemLocalised string  `json:"RetrievedItem_Localised,omitempty"`
+	Ship                   string  `json:"Ship"`
+	ShipID                 int     `json:"ShipID"`
+	Hot                    bool    `json:"Hot"`
+	EngineerModifications  string  `json:"EngineerModifications,omitempty"`
+	Level                  int     `json:"Level,omitempty"`
+	Quality                float64 `json:"Quality,omitempty"`
+	SwapOutItem            string  `json:"SwapOutItem,omitempty"`
+	SwapOutItemLocalised   string  `json:"SwapOutItem_Localised,omitempty"`
+	Cost                   int     `json:"Cost,omitempty"`
+}
+
+func ModuleRetrieveEventHandler(b []byte) {
+	var e ModuleRetrieveEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type ModuleSellEvent struct {
+	event.Event
+	MarketID          int    `json:"MarketID"`
+	Slot              string `json:"Slot"`
+	SellItem          string `json:"SellItem"`
+	SellItemLocalised string `json:"SellItem_Localised,omitempty"`
+	SellPrice         int    `json:"SellPrice"`
+	Ship              string `json:"Ship"`
+	ShipID            int    `json:"ShipID"`
+}
+
+func ModuleSellEventHandler(b []byte) {
+	var e ModuleSellEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type ModuleSellRemoteEvent struct {
+	event.Event
+	StorageSlot       int    `json:"StorageSlot"`
+	SellItem          string `json:"SellItem"`
+	SellItemLocalised string `json:"SellItem_Localised,omitempty"`
+	ServerID          int    `json:"ServerId"`
+	SellPrice         int    `json:"SellPrice"`
+	Ship              string `json:"Ship"`
+	ShipID            int    `json:"ShipID"`
+}
+
+func ModuleSellRemoteEventHandler(b []byte) {
+	var e ModuleSellRemoteEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type ModuleStoreEvent struct {
+	event.Event
+	MarketID                 int     `json:"MarketID"`
+	Slot                     string  `json:"Slot"`
+	StoredItem               string  `json:"StoredItem"`
+	StoredItemLocalised      string  `json:"StoredItem_Localised,omitempty"`
+	Ship                     string  `json:"Ship"`
+	ShipID                   int     `json:"ShipID"`
+	Hot                      bool    `json:"Hot"`
+	EngineerModifications    string  `json:"EngineerModifications,omitempty"`
+	Level                    int     `json:"Level,omitempty"`
+	Quality                  float64 `json:"Quality,omitempty"`
+	ReplacementItem          string  `json:"ReplacementItem,omitempty"`
+	ReplacementItemLocalised string  `json:"ReplacementItem_Localised,omitempty"`
+	Cost                     int     `json:"Cost,omitempty"`
+}
+
+func ModuleStoreEventHandler(b []byte) {
+	var e ModuleStoreEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type PayBountiesEvent struct {
+	event.Event
+	Amount           int     `json:"Amount"`
+	AllFines         bool    `json:"AllFines,omitempty"`
+	Faction          string  `json:"Faction,omitempty"`
+	FactionLocalised string  `json:"Faction_Localised,omitempty"`
+	ShipID           int     `json:"ShipID"`
+	BrokerPercentage float64 `json:"BrokerPercentage,omitempty"`
+}
+
+func PayBountiesEventHandler(b []byte) {
+	var e PayBountiesEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type PayFinesEvent struct {
+	event.Event
+	Amount           int     `json:"Amount"`
+	AllFines         bool    `json:"AllFines"`
+	Faction          string  `json:"Faction,omitempty"`
+	ShipID           int     `json:"ShipID"`
+	BrokerPercentage float64 `json:"BrokerPercentage,omitempty"`
+}
+
+func PayFinesEventHandler(b []byte) {
+	var e PayFinesEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type RedeemVoucherEvent struct {
+	event.Event
+	Type     string `json:"Type"`
+	Amount   int    `json:"Amount"`
+	Faction  string `json:"Faction,omitempty"`
+	Factions []*struct {
+		Faction string `json:"Faction"`
+		Amount  int    `json:"Amount"`
+	} `json:"Factions,omitempty"`
+	BrokerPercentage float64 `json:"BrokerPercentage,omitempty"`
+}
+
+func RedeemVoucherEventHandler(b []byte) {
+	var e RedeemVoucherEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type RefuelAllEvent struct {
+	event.Event
+	Cost   int     `json:"Cost"`
+	Amount float64 `json:"Amount"`
+}
+
+func RefuelAllEventHandler(b []byte) {
+	var e RefuelAllEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type RefuelPartialEvent struct {
+	event.Event
+	Cost   int     `json:"Cost"`
+	Amount float64 `json:"Amount"`
+}
+
+func RefuelPartialEventHandler(b []byte) {
+	var e RefuelPartialEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type RepairEvent struct {
+	event.Event
+	Item  string   `json:"Item,omitempty"`
+	Items []string `json:"Items,omitempty"`
+	Cost  int      `json:"Cost"`
+}
+
+func RepairEventHandler(b []byte) {
+	var e RepairEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type RepairAllEvent struct {
+	event.Event
+	Cost int `json:"Cost"`
+}
+
+func RepairAllEventHandler(b []byte) {
+	var e RepairAllEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type RestockVehicleEvent struct {
+	event.Event
+	Type          string `json:"Type"`
+	TypeLocalised string `json:"Type_Localised,omitempty"`
+	Loadout       string `json:"Loadout"`
+	ID            *int   `json:"ID,omitempty"`
+	Cost          int    `json:"Cost"`
+	Count         int    `json:"Count"`
+}
+
+func RestockVehicleEventHandler(b []byte) {
+	var e RestockVehicleEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type SearchAndRescueEvent struct {
+	event.Event
+	MarketID      int    `json:"MarketID"`
+	Name          string `json:"Name"`
+	NameLocalised string `json:"Name_Localised,omitempty"`
+	Count         int    `json:"Count"`
+	Reward        int    `json:"Reward"`
+}
+
+func SearchAndRescueEventHandler(b []byte) {
+	var e SearchAndRescueEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type SellDronesEvent struct {
+	event.Event
+	Type      string `json:"Type"`
+	Count     int    `json:"Count"`
+	SellPrice int    `json:"SellPrice"`
+	TotalSale int    `json:"TotalSale"`
+}
+
+func SellDronesEventHandler(b []byte) {
+	var e SellDronesEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type SellShipOnRebuyEvent struct {
+	event.Event
+	ShipType   string `json:"ShipType"`
+	System     string `json:"System"`
+	SellShipID int    `json:"SellShipId"`
+	ShipPrice  int    `json:"ShipPrice"`
+}
+
+func SellShipOnRebuyEventHandler(b []byte) {
+	var e SellShipOnRebuyEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type SetUserShipNameEvent struct {
+	event.Event
+	Ship         string `json:"Ship"`
+	ShipID       int    `json:"ShipID"`
+	UserShipName string `json:"UserShipName"`
+	UserShipID   string `json:"UserShipId"`
+}
+
+func SetUserShipNameEventHandler(b []byte) {
+	var e SetUserShipNameEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type ShipyardBuyEvent struct {
+	event.Event
+	ShipType          string `json:"ShipType"`
+	ShipTypeLocalised string `json:"ShipType_Localised,omitempty"`
+	ShipPrice         int    `json:"ShipPrice"`
+	StoreOldShip      string `json:"StoreOldShip,omitempty"`
+	StoreShipID       *int   `json:"StoreShipID,omitempty"`
+	SellOldShip       string `json:"SellOldShip,omitempty"`
+	SellShipID        *int   `json:"SellShipID,omitempty"`
+	SellPrice         int    `json:"SellPrice,omitempty"`
+	MarketID          int    `json:"MarketID"`
+}
+
+func ShipyardBuyEventHandler(b []byte) {
+	var e ShipyardBuyEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type ShipyardNewEvent struct {
+	event.Event
+	ShipType          string `json:"ShipType"`
+	ShipTypeLocalised string `json:"ShipType_Localised,omitempty"`
+	NewShipID         int    `json:"NewShipID"`
+}
+
+func ShipyardNewEventHandler(b []byte) {
+	var e ShipyardNewEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type ShipyardSellEvent struct {
+	event.Event
+	ShipType          string `json:"ShipType"`
+	ShipTypeLocalised string `json:"ShipType_Localised,omitempty"`
+	SellShipID        int    `json:"SellShipID"`
+	ShipPrice         int    `json:"ShipPrice"`
+	System            string `json:"System,omitempty"`
+	MarketID          int    `json:"MarketID"`
+}
+
+func ShipyardSellEventHandler(b []byte) {
+	var e ShipyardSellEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type ShipyardSwapEvent struct {
+	event.Event
+	ShipType          string `json:"ShipType"`
+	ShipTypeLocalised string `json:"ShipType_Localised,omitempty"`
+	ShipID            int    `json:"ShipID"`
+	StoreOldShip      string `json:"StoreOldShip,omitempty"`
+	StoreShipID       *int   `json:"StoreShipID,omitempty"`
+	SellOldShip       string `json:"SellOldShip,omitempty"`
+	SellShipID        *int   `json:"SellShipID,omitempty"`
+	MarketID          int    `json:"MarketID"`
+}
+
+func ShipyardSwapEventHandler(b []byte) {
+	var e ShipyardSwapEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type ShipyardTransferEvent struct {
+	event.Event
+	ShipType          string  `json:"ShipType"`
+	ShipTypeLocalised string  `json:"ShipType_Localised,omitempty"`
+	ShipID            int     `json:"ShipID"`
+	System            string  `json:"System"`
+	ShipMarketID      int     `json:"ShipMarketID"`
+	Distance          float64 `json:"Distance"`
+	TransferPrice     int     `json:"TransferPrice"`
+	TransferTime      int     `json:"TransferTime"`
+	MarketID          int     `json:"MarketID"`
+}
+
+func ShipyardTransferEventHandler(b []byte) {
+	var e ShipyardTransferEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type StoredShipsEvent struct {
+	event.Event
+	StationName string `json:"StationName"`
+	MarketID    int    `json:"MarketID"`
+	StarSystem  string `json:"StarSystem"`
+	ShipsHere   []*struct {
+		ShipID            int    `json:"ShipID"`
+		ShipType          string `json:"ShipType"`
+		ShipTypeLocalised string `json:"ShipType_Localised,omitempty"`
+		Name              string `json:"Name,omitempty"`
+		Value             int    `json:"Value"`
+		Hot               bool   `json:"Hot"`
+	} `json:"ShipsHere"`
+	ShipsRemote []*struct {
+		ShipID            int    `json:"ShipID"`
+		ShipType          string `json:"ShipType"`
+		ShipTypeLocalised string `json:"ShipType_Localised,omitempty"`
+		Name              string `json:"Name,omitempty"`
+		StarSystem        string `json:"StarSystem,omitempty"`
+		ShipMarketID      int    `json:"ShipMarketID,omitempty"`
+		TransferPrice     int    `json:"TransferPrice,omitempty"`
+		TransferTime      int    `json:"TransferTime,omitempty"`
+		Value             int    `json:"Value"`
+		Hot               bool   `json:"Hot"`
+		InTransit         bool   `json:"InTransit,omitempty"`
+	} `json:"ShipsRemote"`
+}
+
+func StoredShipsEventHandler(b []byte) {
+	var e StoredShipsEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type TechnologyBrokerEvent struct {
+	event.Event
+	BrokerType    string `json:"BrokerType"`
+	MarketID      int    `json:"MarketID"`
+	ItemsUnlocked []*struct {
+		Name          string `json:"Name"`
+		NameLocalised string `json:"Name_Localised,omitempty"`
+	} `json:"ItemsUnlocked"`
+	Commodities []*MaterialCount `json:"Commodities,omitempty"`
+	Materials   []*MaterialCount `json:"Materials,omitempty"`
+}
+
+func TechnologyBrokerEventHandler(b []byte) {
+	var e TechnologyBrokerEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
@@ -0,0 +1,77 @@
+package events
+
+import (
+	"encoding/json"
+
+	"github.com/sht/ed-journal/event"
+)
+
+const (
+	Status = "Status"
+)
+
+// StatusFile is the name of the file the game writes the ship status to
+const StatusFile = "Status.json"
+
+// Status flags
+const (
+	FlagDocked             = 1 << 0
+	FlagLanded             = 1 << 1
+	FlagLandingGearDown    = 1 << 2
+	FlagShieldsUp          = 1 << 3
+	FlagSupercruise        = 1 << 4
+	FlagFlightAssistOff    = 1 << 5
+	FlagHardpointsDeployed = 1 << 6
+	FlagInWing             = 1 << 7
+	FlagLightsOn           = 1 << 8
+	FlagCargoScoopDeployed = 1 << 9
+	FlagSilentRunning      = 1 << 10
+	FlagScoopingFuel       = 1 << 11
+	FlagFsdMassLocked      = 1 << 16
+	FlagFsdCharging        = 1 << 17
+	FlagFsdCooldown        = 1 << 18
+	FlagLowFuel            = 1 << 19
+	FlagOverHeating        = 1 << 20
+	FlagInDanger           = 1 << 22
+	FlagBeingInterdicted   = 1 << 23
+	FlagFsdJump            = 1 << 30
+)
+
+// StatusEvent is the content of Status.json, rewritten by the game whenever
+// the ship status changes. Fuel is only present while in the ship
+type StatusEvent struct {
+	event.Event
+	Flags     int   `json:"Flags"`
+	Flags2    int   `json:"Flags2,omitempty"`
+	Pips      []int `json:"Pips,omitempty"`
+	FireGroup int   `json:"FireGroup,omitempty"`
+	GuiFocus  int   `json:"GuiFocus,omitempty"`
+	Fuel      *struct {
+		FuelMain      float64 `json:"FuelMain"`
+		FuelReservoir float64 `json:"FuelReservoir"`
+	} `json:"Fuel,omitempty"`
+	Cargo        float64  `json:"Cargo,omitempty"`
+	LegalState   string   `json:"LegalState,omitempty"`
+	Latitude     *float64 `json:"Latitude,omitempty"`
+	Longitude    *float64 `json:"Longitude,omitempty"`
+	Altitude     *float64 `json:"Altitude,omitempty"`
+	Heading      *float64 `json:"Heading,omitempty"`
+	BodyName     string   `json:"BodyName,omitempty"`
+	PlanetRadius float64  `json:"PlanetRadius,omitempty"`
+	Balance      *int     `json:"Balance,omitempty"`
+	Destination  *struct {
+		System int    `json:"System"`
+		Body   int    `json:"Body"`
+		Name   string `json:"Name"`
+	} `json:"Destination,omitempty"`
+}
+
+func StatusEventHandler(b []byte) {
+	var e StatusEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
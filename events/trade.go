@@ -0,0 +1,73 @@
+package events
+
+import (
+	"encoding/json"
+
+	"github.com/sht/ed-journal/event"
+)
+
+const (
+	BuyTradeData = "BuyTradeData"
+	MarketBuy    = "MarketBuy"
+	MarketSell   = "MarketSell"
+)
+
+type BuyTradeDataEvent struct {
+	event.Event
+	System string `json:"System"`
+	Cost   int    `json:"Cost"`
+}
+
+func BuyTradeDataEventHandler(b []byte) {
+	var e BuyTradeDataEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type MarketBuyEvent struct {
+	event.Event
+	MarketID      int    `json:"MarketID"`
+	Type          string `json:"Type"`
+	TypeLocalised string `json:"Type_Localised,omitempty"`
+	Count         int    `json:"Count"`
+	BuyPrice      int    `json:"BuyPrice"`
+	TotalCost     int    `json:"TotalCost"`
+}
+
+func MarketBuyEventHandler(b []byte) {
+	var e MarketBuyEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type MarketSellEvent struct {
+	event.Event
+	MarketID      int    `json:"MarketID"`
+	Type          string `json:"Type"`
+	TypeLocalised string `json:"Type_Localised,omitempty"`
+	Count         int    `json:"Count"`
+	SellPrice     int    `json:"SellPrice"`
+	TotalSale     int    `json:"TotalSale"`
+	AvgPricePaid  int    `json:"AvgPricePaid"`
+	IllegalGoods  bool   `json:"IllegalGoods,omitempty"`
+	StolenGoods   bool   `json:"StolenGoods,omitempty"`
+	BlackMarket   bool   `json:"BlackMarket,omitempty"`
+}
+
+func MarketSellEventHandler(b []byte) {
+	var e MarketSellEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
@@ -18,6 +18,8 @@ const (
 	LeaveBody        = "LeaveBody"
 	Liftoff          = "Liftoff"
 	Location         = "Location"
+	NavRoute         = "NavRoute"
+	NavRouteClear    = "NavRouteClear"
 	StartJump        = "StartJump"
 	SupercruiseEntry = "SupercruiseEntry"
 	SupercruiseExit  = "SupercruiseExit"
@@ -165,36 +167,46 @@ func DockingTimeoutEventHandler(b []byte) {
 	debug(b, e)
 }
 
+// Faction is a minor faction present in a system, as reported on arrival
+type Faction struct {
+	Name               string          `json:"Name"`
+	FactionState       string          `json:"FactionState"`
+	Government         string          `json:"Government"`
+	Influence          float64         `json:"Influence"`
+	Allegiance         string          `json:"Allegiance"`
+	Happiness          string          `json:"Happiness"`
+	HappinessLocalised string          `json:"Happiness_Localised,omitempty"`
+	MyReputation       float64         `json:"MyReputation"`
+	PendingStates      []*FactionState `json:"PendingStates,omitempty"`
+	RecoveringStates   []*FactionState `json:"RecoveringStates,omitempty"`
+	ActiveStates       []*FactionState `json:"ActiveStates,omitempty"`
+}
+
+// FactionState is a state a faction is in. Trend is only set for pending and
+// recovering states
+type FactionState struct {
+	State string `json:"State"`
+	Trend int    `json:"Trend,omitempty"`
+}
+
 type FSDJumpEvent struct {
 	event.Event
-	Body     string `json:"Body"`
-	BodyID   int    `json:"BodyID"`
-	BodyType string `json:"BodyType"`
-	Factions []*struct {
-		Allegiance         string  `json:"Allegiance"`
-		FactionState       string  `json:"FactionState"`
-		Government         string  `json:"Government"`
-		Happiness          string  `json:"Happiness"`
-		HappinessLocalised string  `json:"Happiness_Localised"`
-		Influence          float64 `json:"Influence"`
-		MyReputation       int     `json:"MyReputation"`
-		Name               string  `json:"Name"`
-		ActiveStates       []*struct {
-			State string `json:"State"`
-		} `json:"ActiveStates,omitempty"`
-	} `json:"Factions,omitempty"`
-	FuelLevel              float64   `json:"FuelLevel"`
-	FuelUsed               float64   `json:"FuelUsed"`
-	JumpDist               float64   `json:"JumpDist"`
-	Population             int       `json:"Population"`
-	PowerplayState         string    `json:"PowerplayState,omitempty"`
-	Powers                 []string  `json:"Powers,omitempty"`
-	StarPos                []float64 `json:"StarPos"`
-	StarSystem             string    `json:"StarSystem"`
-	SystemAddress          int       `json:"SystemAddress"`
-	SystemAllegiance       string    `json:"SystemAllegiance"`
-	SystemEconomy          string    `json:"SystemEconomy"`
-	SystemEconomyLocalised string    `json:"SystemEconomy_Localised"`
+	Body                   string     `json:"Body"`
+	BodyID                 int        `json:"BodyID"`
+	BodyType               string     `json:"BodyType"`
+	Factions               []*Faction `json:"Factions,omitempty"`
+	FuelLevel              float64    `json:"FuelLevel"`
+	FuelUsed               float64    `json:"FuelUsed"`
+	JumpDist               float64    `json:"JumpDist"`
+	Population             int        `json:"Population"`
+	PowerplayState         string     `json:"PowerplayState,omitempty"`
+	Powers                 []string   `json:"Powers,omitempty"`
+	StarPos                []float64  `json:"StarPos"`
+	StarSystem             string     `json:"StarSystem"`
+	SystemAddress          int        `json:"SystemAddress"`
+	SystemAllegiance       string     `json:"SystemAllegiance"`
+	SystemEconomy          string     `json:"SystemEconomy"`
+	SystemEconomyLocalised string     `json:"SystemEconomy_Localised"`
 	SystemFaction          *struct {
 		FactionState string `json:"FactionState"`
 		Name         string `json:"Name"`
@@ -274,34 +286,18 @@ func LiftoffEventHandler(b []byte) {
 
 type LocationEvent struct {
 	event.Event
-	Body     string `json:"Body"`
-	BodyID   int    `json:"BodyID"`
-	BodyType string `json:"BodyType"`
-	Docked   bool   `json:"Docked"`
-	Factions []*struct {
-		Allegiance         string  `json:"Allegiance"`
-		FactionState       string  `json:"FactionState"`
-		Government         string  `json:"Government"`
-		Happiness          string  `json:"Happiness"`
-		HappinessLocalised string  `json:"Happiness_Localised"`
-		Influence          float64 `json:"Influence"`
-		MyReputation       int     `json:"MyReputation"`
-		Name               string  `json:"Name"`
-		RecoveringStates   []*struct {
-			State string `json:"State"`
-			Trend int    `json:"Trend"`
-		} `json:"RecoveringStates,omitempty"`
-		ActiveStates []*struct {
-			State string `json:"State"`
-		} `json:"ActiveStates,omitempty"`
-	} `json:"Factions,omitempty"`
-	MarketID          int       `json:"MarketID,omitempty"`
-	Population        int       `json:"Population"`
-	PowerplayState    string    `json:"PowerplayState,omitempty"`
-	Powers            []string  `json:"Powers,omitempty"`
-	StarPos           []float64 `json:"StarPos"`
-	StarSystem        string    `json:"StarSystem"`
-	StationAllegiance string    `json:"StationAllegiance,omitempty"`
+	Body              string     `json:"Body"`
+	BodyID            int        `json:"BodyID"`
+	BodyType          string     `json:"BodyType"`
+	Docked            bool       `json:"Docked"`
+	Factions          []*Faction `json:"Factions,omitempty"`
+	MarketID          int        `json:"MarketID,omitempty"`
+	Population        int        `json:"Population"`
+	PowerplayState    string     `json:"PowerplayState,omitempty"`
+	Powers            []string   `json:"Powers,omitempty"`
+	StarPos           []float64  `json:"StarPos"`
+	StarSystem        string     `json:"StarSystem"`
+	StationAllegiance string     `json:"StationAllegiance,omitempty"`
 	StationEconomies  []*struct {
 		Name          string  `json:"Name"`
 		NameLocalised string  `json:"Name_Localised"`
@@ -344,6 +340,44 @@ func LocationEventHandler(b []byte) {
 	debug(b, e)
 }
 
+// NavRouteEvent is written when a route is plotted. The route itself is only
+// included in the NavRoute.json file written alongside the journal
+type NavRouteEvent struct {
+	event.Event
+	Route []*RouteHop `json:"Route,omitempty"`
+}
+
+type RouteHop struct {
+	StarSystem    string    `json:"StarSystem"`
+	SystemAddress int       `json:"SystemAddress"`
+	StarPos       []float64 `json:"StarPos"`
+	StarClass     string    `json:"StarClass"`
+}
+
+func NavRouteEventHandler(b []byte) {
+	var e NavRouteEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
+type NavRouteClearEvent struct {
+	event.Event
+}
+
+func NavRouteClearEventHandler(b []byte) {
+	var e NavRouteClearEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	debug(b, e)
+}
+
 type StartJumpEvent struct {
 	event.Event
 	JumpType      string `json:"JumpType"`
@@ -0,0 +1,446 @@
+package exploration
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/events"
+)
+
+// Body is a scanned star or planet. Mass is in solar masses for stars and in
+// earth masses for planets
+type Body struct {
+	SystemAddress  int     `json:"systemAddress"`
+	BodyID         int     `json:"bodyID"`
+	BodyName       string  `json:"bodyName"`
+	Star           bool    `json:"star"`
+	Type           string  `json:"type"`
+	Terraformable  bool    `json:"terraformable"`
+	Mass           float64 `json:"mass"`
+	FirstDiscovery bool    `json:"firstDiscovery"`
+	FirstMapped    bool    `json:"firstMapped"`
+	Mapped         bool    `json:"mapped"`
+	Efficient      bool    `json:"efficient"`
+	Value          int     `json:"value"`
+	Sold           bool    `json:"sold"`
+}
+
+// System is a system with at least one scanned body. Value includes the
+// bodies and the bonus for finding every body and mapping every planet
+type System struct {
+	SystemAddress  int    `json:"systemAddress"`
+	StarSystem     string `json:"starSystem"`
+	Bodies         []Body `json:"bodies"`
+	BodyCount      int    `json:"bodyCount"`
+	AllBodiesFound bool   `json:"allBodiesFound"`
+	Bonus          int    `json:"bonus"`
+	Value          int    `json:"value"`
+	Unsold         int    `json:"unsold"`
+}
+
+// Sale compares what a sale of exploration data paid with the estimate for
+// the systems sold
+type Sale struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Event      string    `json:"event"`
+	Systems    []string  `json:"systems"`
+	Estimated  int       `json:"estimated"`
+	Paid       int       `json:"paid"`
+	Difference int       `json:"difference"`
+}
+
+type system struct {
+	address        int
+	name           string
+	bodies         map[int]*Body
+	bodyCount      int
+	allBodiesFound bool
+	bonusSold      bool
+}
+
+// Estimator estimates the value of the exploration data collected. It is
+// safe for concurrent use
+type Estimator struct {
+	mu      sync.RWMutex
+	systems map[int]*system
+	names   map[string]int
+	sales   []Sale
+	odyssey bool
+}
+
+func NewEstimator() *Estimator {
+	return &Estimator{
+		systems: make(map[int]*system),
+		names:   make(map[string]int),
+		sales:   make([]Sale, 0),
+	}
+}
+
+const SnapshotVersion = 1
+
+type savedSystem struct {
+	Name           string        `json:"name"`
+	Bodies         map[int]*Body `json:"bodies"`
+	BodyCount      int           `json:"bodyCount"`
+	AllBodiesFound bool          `json:"allBodiesFound"`
+	BonusSold      bool          `json:"bonusSold"`
+}
+
+type snapshot struct {
+	Systems map[int]savedSystem `json:"systems"`
+	Sales   []Sale              `json:"sales"`
+	Odyssey bool                `json:"odyssey"`
+}
+
+// Snapshot saves the scanned systems and the sales
+func (e *Estimator) Snapshot() (json.RawMessage, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	s := snapshot{
+		Systems: make(map[int]savedSystem, len(e.systems)),
+		Sales:   e.sales,
+		Odyssey: e.odyssey,
+	}
+	for address, sys := range e.systems {
+		s.Systems[address] = savedSystem{sys.name, sys.bodies, sys.bodyCount, sys.allBodiesFound, sys.bonusSold}
+	}
+	return json.Marshal(s)
+}
+
+func (e *Estimator) Restore(b json.RawMessage) error {
+	s := snapshot{Sales: make([]Sale, 0)}
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.systems = make(map[int]*system, len(s.Systems))
+	e.names = make(map[string]int, len(s.Systems))
+	for address, sys := range s.Systems {
+		e.systems[address] = &system{address, sys.Name, sys.Bodies, sys.BodyCount, sys.AllBodiesFound, sys.BonusSold}
+		if sys.Name != "" {
+			e.names[sys.Name] = address
+		}
+	}
+	e.sales = s.Sales
+	e.odyssey = s.Odyssey
+	return nil
+}
+
+// AddListeners subscribes the estimator to the scan and sale events
+func (e *Estimator) AddListeners(d *dispatcher.Dispatcher) {
+	// startup
+	{
+		d.OnSync(events.Fileheader, e.fileheader)
+	}
+
+	// exploration
+	{
+		d.OnSync(events.FSSAllBodiesFound, e.fssAllBodiesFound)
+		d.OnSync(events.FSSDiscoveryScan, e.fssDiscoveryScan)
+		d.OnSync(events.MultiSellExplorationData, e.multiSellExplorationData)
+		d.OnSync(events.SAAScanComplete, e.saaScanComplete)
+		d.OnSync(events.Scan, e.scan)
+		d.OnSync(events.SellExplorationData, e.sellExplorationData)
+	}
+}
+
+// Systems returns the systems with scanned bodies, sorted by name
+func (e *Estimator) Systems() []System {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	systems := make([]System, 0, len(e.systems))
+	for _, s := range e.systems {
+		systems = append(systems, s.summary())
+	}
+	sort.Slice(systems, func(i, j int) bool {
+		return systems[i].StarSystem < systems[j].StarSystem
+	})
+	return systems
+}
+
+// System returns the system with the given address
+func (e *Estimator) System(address int) (System, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	s, ok := e.systems[address]
+	if !ok {
+		return System{}, false
+	}
+	return s.summary(), true
+}
+
+// Unsold returns the estimated value of the data that has not been sold yet
+func (e *Estimator) Unsold() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	total := 0
+	for _, s := range e.systems {
+		total += s.summary().Unsold
+	}
+	return total
+}
+
+// Sales returns the sales of exploration data, oldest first
+func (e *Estimator) Sales() []Sale {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	sales := make([]Sale, 0, len(e.sales))
+	for _, s := range e.sales {
+		s.Systems = append([]string{}, s.Systems...)
+		sales = append(sales, s)
+	}
+	return sales
+}
+
+// bonus returns the bonus for finding every body in the system and mapping
+// every planet in it
+func (s *system) bonus() int {
+	if !s.allBodiesFound || s.bodyCount == 0 {
+		return 0
+	}
+
+	bonus := s.bodyCount * allBodiesBonus
+	planets := 0
+	for _, b := range s.bodies {
+		if b.Star {
+			continue
+		}
+		if !b.Mapped {
+			return bonus
+		}
+		planets++
+	}
+	if len(s.bodies) >= s.bodyCount {
+		bonus += planets * allMappedBonus
+	}
+	return bonus
+}
+
+func (s *system) summary() System {
+	sum := System{
+		SystemAddress:  s.address,
+		StarSystem:     s.name,
+		Bodies:         make([]Body, 0, len(s.bodies)),
+		BodyCount:      s.bodyCount,
+		AllBodiesFound: s.allBodiesFound,
+		Bonus:          s.bonus(),
+	}
+	for _, b := range s.bodies {
+		sum.Bodies = append(sum.Bodies, *b)
+		sum.Value += b.Value
+		if !b.Sold {
+			sum.Unsold += b.Value
+		}
+	}
+	sort.Slice(sum.Bodies, func(i, j int) bool {
+		return sum.Bodies[i].BodyID < sum.Bodies[j].BodyID
+	})
+	sum.Value += sum.Bonus
+	if !s.bonusSold {
+		sum.Unsold += sum.Bonus
+	}
+	return sum
+}
+
+// system returns the system with the given address, creating it if needed.
+// Must be called with the lock held
+func (e *Estimator) system(address int, name string) *system {
+	s, ok := e.systems[address]
+	if !ok {
+		s = &system{
+			address: address,
+			bodies:  make(map[int]*Body),
+		}
+		e.systems[address] = s
+	}
+	if name != "" {
+		s.name = name
+		e.names[name] = address
+	}
+	return s
+}
+
+// sell marks the named systems as sold, returning their unsold value. Must
+// be called with the lock held
+func (e *Estimator) sell(names []string) int {
+	estimated := 0
+	for _, name := range names {
+		address, ok := e.names[name]
+		if !ok {
+			continue
+		}
+		s := e.systems[address]
+		sum := s.summary()
+		estimated += sum.Unsold
+		for _, b := range s.bodies {
+			b.Sold = true
+		}
+		if sum.Bonus > 0 {
+			s.bonusSold = true
+		}
+	}
+	return estimated
+}
+
+func (e *Estimator) fileheader(b []byte) {
+	var ev events.FileheaderEvent
+	err := json.Unmarshal(b, &ev)
+	if err != nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.odyssey = ev.Odyssey != nil && *ev.Odyssey
+}
+
+func (e *Estimator) fssDiscoveryScan(b []byte) {
+	var ev events.FSSDiscoveryScanEvent
+	err := json.Unmarshal(b, &ev)
+	if err != nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	s := e.system(ev.SystemAddress, ev.SystemName)
+	s.bodyCount = ev.BodyCount
+}
+
+func (e *Estimator) fssAllBodiesFound(b []byte) {
+	var ev events.FSSAllBodiesFoundEvent
+	err := json.Unmarshal(b, &ev)
+	if err != nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	s := e.system(ev.SystemAddress, ev.SystemName)
+	s.bodyCount = ev.Count
+	s.allBodiesFound = true
+}
+
+func (e *Estimator) scan(b []byte) {
+	var ev events.ScanEvent
+	err := json.Unmarshal(b, &ev)
+	if err != nil {
+		return
+	}
+	// belt clusters and rings are not sold as bodies
+	if ev.StarType == "" && ev.PlanetClass == "" {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	s := e.system(ev.SystemAddress, ev.StarSystem)
+	body, ok := s.bodies[ev.BodyID]
+	if !ok {
+		body = &Body{
+			SystemAddress: ev.SystemAddress,
+			BodyID:        ev.BodyID,
+		}
+		s.bodies[ev.BodyID] = body
+	}
+
+	body.BodyName = ev.BodyName
+	body.FirstDiscovery = !ev.WasDiscovered
+	if ev.StarType != "" {
+		body.Star = true
+		body.Type = ev.StarType
+		body.Mass = ev.StellarMass
+	} else {
+		body.Type = ev.PlanetClass
+		body.Mass = ev.MassEM
+		body.FirstMapped = !ev.WasMapped
+		body.Terraformable = ev.TerraformState != nil && *ev.TerraformState == "Terraformable"
+	}
+	body.Value = body.value(e.odyssey)
+}
+
+func (e *Estimator) saaScanComplete(b []byte) {
+	var ev events.SAAScanCompleteEvent
+	err := json.Unmarshal(b, &ev)
+	if err != nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	s := e.system(ev.SystemAddress, "")
+	body, ok := s.bodies[ev.BodyID]
+	if !ok {
+		// mapped without a detailed scan, the value cannot be worked out
+		return
+	}
+
+	body.Mapped = true
+	body.Efficient = ev.ProbesUsed <= ev.EfficiencyTarget
+	body.Sold = false
+	body.Value = body.value(e.odyssey)
+}
+
+func (e *Estimator) sellExplorationData(b []byte) {
+	var ev events.SellExplorationDataEvent
+	err := json.Unmarshal(b, &ev)
+	if err != nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	estimated := e.sell(ev.Systems)
+	e.sales = append(e.sales, Sale{
+		Timestamp:  ev.Timestamp,
+		Event:      ev.Event.Event,
+		Systems:    append([]string{}, ev.Systems...),
+		Estimated:  estimated,
+		Paid:       ev.TotalEarnings,
+		Difference: ev.TotalEarnings - estimated,
+	})
+}
+
+func (e *Estimator) multiSellExplorationData(b []byte) {
+	var ev events.MultiSellExplorationDataEvent
+	err := json.Unmarshal(b, &ev)
+	if err != nil {
+		return
+	}
+
+	names := make([]string, 0, len(ev.Discovered))
+	for _, d := range ev.Discovered {
+		names = append(names, d.SystemName)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	estimated := e.sell(names)
+	e.sales = append(e.sales, Sale{
+		Timestamp:  ev.Timestamp,
+		Event:      ev.Event.Event,
+		Systems:    names,
+		Estimated:  estimated,
+		Paid:       ev.TotalEarnings,
+		Difference: ev.TotalEarnings - estimated,
+	})
+}
@@ -0,0 +1,119 @@
+package exploration
+
+import (
+	"math"
+	"strings"
+)
+
+// Valuation of scanned bodies, following the formulas worked out by the
+// community from the sale prices paid since the 3.3 exploration update
+
+const (
+	// massExponentFactor scales the mass term of the planet formula
+	massExponentFactor = 0.56591828
+
+	// allBodiesBonus is paid per body for a system where every body was found
+	allBodiesBonus = 1000
+	// allMappedBonus is paid per planet for a system where every planet was
+	// mapped
+	allMappedBonus = 10000
+
+	// minimumValue is the least any body sells for
+	minimumValue = 500
+	// firstDiscoveryMultiplier applies to bodies nobody had discovered before
+	firstDiscoveryMultiplier = 2.6
+	// efficiencyMultiplier applies to bodies mapped within the probe target
+	efficiencyMultiplier = 1.25
+)
+
+// starValue returns the base value of a star of the given type and mass in
+// solar masses
+func starValue(starType string, mass float64) float64 {
+	k := 1200.0
+	switch {
+	case starType == "SupermassiveBlackHole":
+		// only ever seen at Sagittarius A*, whose value was measured
+		k = 33.5678
+	case starType == "N" || starType == "H":
+		k = 22628
+	case strings.HasPrefix(starType, "D"):
+		k = 14057
+	}
+	return k + mass*k/66.25
+}
+
+// planetK returns the base constant for a planet class
+func planetK(class string, terraformable bool) float64 {
+	switch class {
+	case "Metal rich body":
+		return 21790
+	case "Ammonia world":
+		return 96932
+	case "Sudarsky class I gas giant":
+		return 1656
+	case "Sudarsky class II gas giant", "High metal content body":
+		if terraformable {
+			return 9654 + 100677
+		}
+		return 9654
+	case "Water world":
+		if terraformable {
+			return 64831 + 116295
+		}
+		return 64831
+	case "Earthlike body":
+		return 64831 + 116295
+	}
+	if terraformable {
+		return 300 + 93328
+	}
+	return 300
+}
+
+// planetValue returns the value of a planet of the given class and mass in
+// earth masses
+func planetValue(class string, terraformable bool, mass float64, firstDiscovery, mapped, firstMapped, efficient, odyssey bool) float64 {
+	k := planetK(class, terraformable)
+
+	multiplier := 1.0
+	if mapped {
+		switch {
+		case firstDiscovery && firstMapped:
+			multiplier = 3.699622554
+		case firstMapped:
+			multiplier = 8.0956
+		default:
+			multiplier = 3.3333333333
+		}
+	}
+
+	value := (k + k*massExponentFactor*math.Pow(mass, 0.2)) * multiplier
+	if mapped {
+		if odyssey {
+			value += math.Max(value*0.3, 555)
+		}
+		if efficient {
+			value *= efficiencyMultiplier
+		}
+	}
+	value = math.Max(value, minimumValue)
+	if firstDiscovery {
+		value *= firstDiscoveryMultiplier
+	}
+	return value
+}
+
+// value returns the estimated sale value of the body in credits
+func (b *Body) value(odyssey bool) int {
+	var v float64
+	if b.Star {
+		v = starValue(b.Type, b.Mass)
+		v = math.Max(v, minimumValue)
+		if b.FirstDiscovery {
+			v *= firstDiscoveryMultiplier
+		}
+	} else {
+		v = planetValue(b.Type, b.Terraformable, b.Mass, b.FirstDiscovery, b.Mapped, b.FirstMapped, b.Efficient, odyssey)
+	}
+	return int(math.Round(v))
+}
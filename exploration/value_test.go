@@ -0,0 +1,55 @@
+package exploration
+
+import "testing"
+
+func TestBodyValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    Body
+		odyssey bool
+		want    int
+	}{
+		{"K star", Body{Star: true, Type: "K", Mass: 0.7}, false, 1213},
+		{"K star first discovered", Body{Star: true, Type: "K", Mass: 0.7, FirstDiscovery: true}, false, 3153},
+		{"neutron star", Body{Star: true, Type: "N", Mass: 1.2}, false, 23038},
+		{"white dwarf", Body{Star: true, Type: "DA", Mass: 0.5}, false, 14163},
+		{"Sagittarius A*", Body{Star: true, Type: "SupermassiveBlackHole", Mass: 4e6}, false, 2026769},
+		{"icy body at the minimum", Body{Type: "Icy body", Mass: 0.01}, false, 500},
+		{"earthlike scanned", Body{Type: "Earthlike body", Mass: 1}, false, 283629},
+		{"earthlike mapped", Body{Type: "Earthlike body", Mass: 1, Mapped: true, Efficient: true}, false, 1181785},
+		{"earthlike first discovered and mapped",
+			Body{Type: "Earthlike body", Mass: 1, FirstDiscovery: true, FirstMapped: true, Mapped: true, Efficient: true}, false, 3410285},
+		{"earthlike first discovered and mapped in Odyssey",
+			Body{Type: "Earthlike body", Mass: 1, FirstDiscovery: true, FirstMapped: true, Mapped: true, Efficient: true}, true, 4433370},
+		{"terraformable high metal content first mapped",
+			Body{Type: "High metal content body", Terraformable: true, Mass: 0.5, FirstMapped: true, Mapped: true}, false, 1333238},
+		{"water world mapped inefficiently in Odyssey", Body{Type: "Water world", Mass: 0.8, Mapped: true}, true, 432981},
+		{"icy body mapped in Odyssey", Body{Type: "Icy body", Mass: 0.01, Mapped: true}, true, 1780},
+	}
+	for _, tt := range tests {
+		if got := tt.body.value(tt.odyssey); got != tt.want {
+			t.Errorf("%s: value %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSystemBonus(t *testing.T) {
+	star := &Body{Star: true}
+	planet := &Body{}
+	mapped := &Body{Mapped: true}
+	tests := []struct {
+		name   string
+		system system
+		want   int
+	}{
+		{"bodies not all found", system{bodyCount: 2, bodies: map[int]*Body{0: star, 1: mapped}}, 0},
+		{"all found", system{bodyCount: 2, allBodiesFound: true, bodies: map[int]*Body{0: star, 1: planet}}, 2000},
+		{"all found and mapped", system{bodyCount: 2, allBodiesFound: true, bodies: map[int]*Body{0: star, 1: mapped}}, 12000},
+		{"all mapped but not all scanned", system{bodyCount: 3, allBodiesFound: true, bodies: map[int]*Body{0: star, 1: mapped}}, 3000},
+	}
+	for _, tt := range tests {
+		if got := tt.system.bonus(); got != tt.want {
+			t.Errorf("%s: bonus %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/events"
+	"github.com/sht/ed-journal/importer"
+	"github.com/sht/ed-journal/loadout"
+)
+
+func exportCmd(args []string) int {
+	fs := newFlagSet("export")
+	dir := dirFlag(fs)
+	format := formatFlag(fs, "table")
+	to := fs.String("to", "coriolis", "planner to export to: coriolis, edsy or json for the import JSON itself")
+	ship := fs.Int("ship", -1, "only export the ship with this ID")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, fmt.Errorf("unexpected argument %q", fs.Arg(0)))
+	}
+
+	var export func(e *events.LoadoutEvent) (string, error)
+	switch *to {
+	case "coriolis":
+		export = loadout.CoriolisURL
+	case "edsy":
+		export = loadout.EDSYURL
+	case "json":
+		export = func(e *events.LoadoutEvent) (string, error) {
+			b, err := loadout.ImportJSON(e)
+			return string(b), err
+		}
+	default:
+		return usageError(fs, fmt.Errorf("unknown planner %q", *to))
+	}
+	out, err := newWriter(*format, []string{"shipId", "ship", "name", "ident", "export"})
+	if err != nil {
+		return usageError(fs, err)
+	}
+
+	// keep the latest build of every ship
+	d := dispatcher.NewDispatcher()
+	builds := make(map[int]*events.LoadoutEvent)
+	d.OnSync(events.Loadout, func(b []byte) {
+		var e events.LoadoutEvent
+		if json.Unmarshal(b, &e) == nil {
+			builds[e.ShipID] = &e
+		}
+	})
+	d.OnSync(events.ShipyardSell, func(b []byte) {
+		var e events.ShipyardSellEvent
+		if json.Unmarshal(b, &e) == nil {
+			delete(builds, e.SellShipID)
+		}
+	})
+	d.OnSync(events.SellShipOnRebuy, func(b []byte) {
+		var e events.SellShipOnRebuyEvent
+		if json.Unmarshal(b, &e) == nil {
+			delete(builds, e.SellShipID)
+		}
+	})
+
+	results, err := importer.NewImporter(d).ImportDir(*dir)
+	if err != nil {
+		return fail(err)
+	}
+	code := exitOK
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", r.Path, r.Err)
+			code = exitError
+		}
+	}
+
+	ids := make([]int, 0, len(builds))
+	for id := range builds {
+		if *ship < 0 || id == *ship {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	if *ship >= 0 && len(ids) == 0 {
+		return fail(fmt.Errorf("no build found for ship %d", *ship))
+	}
+
+	for _, id := range ids {
+		e := builds[id]
+		s, err := export(e)
+		if err != nil {
+			return fail(err)
+		}
+		err = out.Write(map[string]interface{}{
+			"shipId": float64(e.ShipID),
+			"ship":   e.Ship,
+			"name":   e.ShipName,
+			"ident":  e.ShipIdent,
+			"export": s,
+		})
+		if err != nil {
+			return fail(err)
+		}
+	}
+	err = out.Flush()
+	if err != nil {
+		return fail(err)
+	}
+	return code
+}
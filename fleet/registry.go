@@ -0,0 +1,494 @@
+package fleet
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/events"
+)
+
+// Ship is a ship owned by the commander. Loadout is the last loadout event
+// seen for the ship and must not be modified
+type Ship struct {
+	ShipID          int                  `json:"shipId"`
+	Type            string               `json:"type"`
+	TypeLocalised   string               `json:"typeLocalised,omitempty"`
+	Name            string               `json:"name,omitempty"`
+	Ident           string               `json:"ident,omitempty"`
+	Value           int                  `json:"value,omitempty"`
+	HullValue       int                  `json:"hullValue,omitempty"`
+	ModulesValue    int                  `json:"modulesValue,omitempty"`
+	Rebuy           int                  `json:"rebuy,omitempty"`
+	Hot             bool                 `json:"hot,omitempty"`
+	StarSystem      string               `json:"starSystem,omitempty"`
+	Station         string               `json:"station,omitempty"`
+	MarketID        int                  `json:"marketId,omitempty"`
+	Current         bool                 `json:"current"`
+	Transit         bool                 `json:"inTransit,omitempty"`
+	TransferArrival time.Time            `json:"transferArrival,omitempty"`
+	Loadout         *events.LoadoutEvent `json:"loadout,omitempty"`
+	UpdatedAt       time.Time            `json:"updatedAt"`
+}
+
+// InTransit reports whether the ship is still being transferred at the given
+// time
+func (s Ship) InTransit(now time.Time) bool {
+	if !s.Transit {
+		return false
+	}
+	return s.TransferArrival.IsZero() || now.Before(s.TransferArrival)
+}
+
+// location is where the commander is currently docked, if anywhere
+type location struct {
+	StarSystem string `json:"starSystem,omitempty"`
+	Station    string `json:"station,omitempty"`
+	MarketID   int    `json:"marketId,omitempty"`
+}
+
+// Registry keeps track of every ship owned by the commander. It is safe for
+// concurrent use
+type Registry struct {
+	mu       sync.RWMutex
+	ships    map[int]*Ship
+	current  int
+	location location
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		ships:   make(map[int]*Ship),
+		current: -1,
+	}
+}
+
+// registryFile is the on-disk representation of a registry
+type registryFile struct {
+	Current  int      `json:"current"`
+	Location location `json:"location"`
+	Ships    []*Ship  `json:"ships"`
+}
+
+// LoadRegistry reads a registry previously written with Save. A missing file
+// results in an empty registry
+func LoadRegistry(path string) (*Registry, error) {
+	r := NewRegistry()
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f registryFile
+	err = json.Unmarshal(b, &f)
+	if err != nil {
+		return nil, err
+	}
+
+	r.current = f.Current
+	r.location = f.Location
+	for _, s := range f.Ships {
+		r.ships[s.ShipID] = s
+	}
+	return r, nil
+}
+
+// Save writes the registry to path, replacing it atomically
+func (r *Registry) Save(path string) error {
+	r.mu.RLock()
+	f := registryFile{
+		Current:  r.current,
+		Location: r.location,
+		Ships:    r.list(),
+	}
+	b, err := json.MarshalIndent(f, "", "  ")
+	r.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, b, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Ships returns every owned ship ordered by ShipID
+func (r *Registry) Ships() []Ship {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ships := make([]Ship, 0, len(r.ships))
+	for _, s := range r.list() {
+		ships = append(ships, *s)
+	}
+	return ships
+}
+
+// Ship returns the ship with the given id
+func (r *Registry) Ship(id int) (Ship, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.ships[id]
+	if !ok {
+		return Ship{}, false
+	}
+	return *s, true
+}
+
+// Current returns the ship the commander is flying
+func (r *Registry) Current() (Ship, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.ships[r.current]
+	if !ok {
+		return Ship{}, false
+	}
+	return *s, true
+}
+
+// list returns the ships ordered by id. Must be called with the lock held
+func (r *Registry) list() []*Ship {
+	ships := make([]*Ship, 0, len(r.ships))
+	for _, s := range r.ships {
+		ships = append(ships, s)
+	}
+	sort.Slice(ships, func(i, j int) bool { return ships[i].ShipID < ships[j].ShipID })
+	return ships
+}
+
+// AddListeners subscribes the registry to the shipyard and location events
+func (r *Registry) AddListeners(d *dispatcher.Dispatcher) {
+	// startup
+	{
+		d.OnSync(events.LoadGame, r.loadGame)
+		d.OnSync(events.Loadout, r.loadout)
+	}
+
+	// travel
+	{
+		d.OnSync(events.Docked, r.docked)
+		d.OnSync(events.FSDJump, r.fsdJump)
+		d.OnSync(events.Location, r.locationEvent)
+		d.OnSync(events.Undocked, r.undocked)
+	}
+
+	// station services
+	{
+		d.OnSync(events.SellShipOnRebuy, r.sellShipOnRebuy)
+		d.OnSync(events.SetUserShipName, r.setUserShipName)
+		d.OnSync(events.ShipyardBuy, r.shipyardBuy)
+		d.OnSync(events.ShipyardNew, r.shipyardNew)
+		d.OnSync(events.ShipyardSell, r.shipyardSell)
+		d.OnSync(events.ShipyardSwap, r.shipyardSwap)
+		d.OnSync(events.ShipyardTransfer, r.shipyardTransfer)
+		d.OnSync(events.StoredShips, r.storedShips)
+	}
+}
+
+// update decodes b into e and applies fn while holding the lock
+func (r *Registry) update(b []byte, e interface{}, fn func()) {
+	err := json.Unmarshal(b, e)
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fn()
+}
+
+// ship returns the ship with the given id, adding it to the registry if
+// needed. Must be called with the lock held
+func (r *Registry) ship(id int, shipType string, t time.Time) *Ship {
+	s, ok := r.ships[id]
+	if !ok || (shipType != "" && !strings.EqualFold(s.Type, shipType)) {
+		s = &Ship{
+			ShipID: id,
+			Type:   shipType,
+		}
+		r.ships[id] = s
+	}
+	s.UpdatedAt = t
+	return s
+}
+
+// setCurrent marks the ship with the given id as the one being flown. The
+// previously flown ship is left at the current location. Must be called with
+// the lock held
+func (r *Registry) setCurrent(s *Ship) {
+	if prev, ok := r.ships[r.current]; ok && prev != s {
+		prev.Current = false
+		r.store(prev)
+	}
+	r.current = s.ShipID
+	s.Current = true
+	s.Transit = false
+	s.TransferArrival = time.Time{}
+	r.store(s)
+}
+
+// store places s at the current location. Must be called with the lock held
+func (r *Registry) store(s *Ship) {
+	s.StarSystem = r.location.StarSystem
+	s.Station = r.location.Station
+	s.MarketID = r.location.MarketID
+}
+
+// remove drops a sold ship from the registry. Must be called with the lock
+// held
+func (r *Registry) remove(id int) {
+	delete(r.ships, id)
+	if r.current == id {
+		r.current = -1
+	}
+}
+
+func (r *Registry) loadGame(b []byte) {
+	var e events.LoadGameEvent
+	r.update(b, &e, func() {
+		if e.ShipID == nil || !isShip(e.Ship) {
+			return
+		}
+		s := r.ship(*e.ShipID, e.Ship, e.Timestamp)
+		if e.ShipLocalised != "" {
+			s.TypeLocalised = e.ShipLocalised
+		}
+		if e.ShipName != nil {
+			s.Name = *e.ShipName
+		}
+		if e.ShipIdent != nil {
+			s.Ident = *e.ShipIdent
+		}
+		r.setCurrent(s)
+	})
+}
+
+func (r *Registry) loadout(b []byte) {
+	e := new(events.LoadoutEvent)
+	r.update(b, e, func() {
+		s := r.ship(e.ShipID, e.Ship, e.Timestamp)
+		s.Name = e.ShipName
+		s.Ident = e.ShipIdent
+		s.Rebuy = e.Rebuy
+		s.Hot = e.Hot
+		if e.HullValue != nil {
+			s.HullValue = *e.HullValue
+		}
+		if e.ModulesValue != nil {
+			s.ModulesValue = *e.ModulesValue
+		}
+		if s.HullValue > 0 || s.ModulesValue > 0 {
+			s.Value = s.HullValue + s.ModulesValue
+		}
+		s.Loadout = e
+		r.setCurrent(s)
+	})
+}
+
+func (r *Registry) docked(b []byte) {
+	var e events.DockedEvent
+	r.update(b, &e, func() {
+		r.location = location{
+			StarSystem: e.StarSystem,
+			Station:    e.StationName,
+			MarketID:   e.MarketID,
+		}
+		if s, ok := r.ships[r.current]; ok {
+			r.store(s)
+		}
+	})
+}
+
+func (r *Registry) fsdJump(b []byte) {
+	var e events.FSDJumpEvent
+	r.update(b, &e, func() {
+		r.location = location{StarSystem: e.StarSystem}
+		if s, ok := r.ships[r.current]; ok {
+			r.store(s)
+		}
+	})
+}
+
+func (r *Registry) locationEvent(b []byte) {
+	var e events.LocationEvent
+	r.update(b, &e, func() {
+		r.location = location{StarSystem: e.StarSystem}
+		if e.Docked {
+			r.location.Station = e.StationName
+			r.location.MarketID = e.MarketID
+		}
+		if s, ok := r.ships[r.current]; ok {
+			r.store(s)
+		}
+	})
+}
+
+func (r *Registry) undocked(b []byte) {
+	var e events.UndockedEvent
+	r.update(b, &e, func() {
+		r.location.Station = ""
+		r.location.MarketID = 0
+		if s, ok := r.ships[r.current]; ok {
+			r.store(s)
+		}
+	})
+}
+
+func (r *Registry) sellShipOnRebuy(b []byte) {
+	var e events.SellShipOnRebuyEvent
+	r.update(b, &e, func() {
+		r.remove(e.SellShipID)
+	})
+}
+
+func (r *Registry) setUserShipName(b []byte) {
+	var e events.SetUserShipNameEvent
+	r.update(b, &e, func() {
+		s := r.ship(e.ShipID, e.Ship, e.Timestamp)
+		s.Name = e.UserShipName
+		s.Ident = e.UserShipID
+	})
+}
+
+func (r *Registry) shipyardBuy(b []byte) {
+	var e events.ShipyardBuyEvent
+	r.update(b, &e, func() {
+		if e.SellShipID != nil {
+			r.remove(*e.SellShipID)
+		}
+		if e.StoreShipID != nil {
+			if s, ok := r.ships[*e.StoreShipID]; ok {
+				s.Current = false
+				s.UpdatedAt = e.Timestamp
+				r.store(s)
+			}
+			if r.current == *e.StoreShipID {
+				r.current = -1
+			}
+		}
+	})
+}
+
+func (r *Registry) shipyardNew(b []byte) {
+	var e events.ShipyardNewEvent
+	r.update(b, &e, func() {
+		s := r.ship(e.NewShipID, e.ShipType, e.Timestamp)
+		s.TypeLocalised = e.ShipTypeLocalised
+		r.setCurrent(s)
+	})
+}
+
+func (r *Registry) shipyardSell(b []byte) {
+	var e events.ShipyardSellEvent
+	r.update(b, &e, func() {
+		r.remove(e.SellShipID)
+	})
+}
+
+func (r *Registry) shipyardSwap(b []byte) {
+	var e events.ShipyardSwapEvent
+	r.update(b, &e, func() {
+		if e.SellShipID != nil {
+			r.remove(*e.SellShipID)
+		}
+		s := r.ship(e.ShipID, e.ShipType, e.Timestamp)
+		if e.ShipTypeLocalised != "" {
+			s.TypeLocalised = e.ShipTypeLocalised
+		}
+		r.setCurrent(s)
+	})
+}
+
+func (r *Registry) shipyardTransfer(b []byte) {
+	var e events.ShipyardTransferEvent
+	r.update(b, &e, func() {
+		s := r.ship(e.ShipID, e.ShipType, e.Timestamp)
+		if e.ShipTypeLocalised != "" {
+			s.TypeLocalised = e.ShipTypeLocalised
+		}
+		r.store(s)
+		s.MarketID = e.MarketID
+		s.Transit = true
+		s.TransferArrival = e.Timestamp.Add(time.Duration(e.TransferTime) * time.Second)
+	})
+}
+
+func (r *Registry) storedShips(b []byte) {
+	var e events.StoredShipsEvent
+	r.update(b, &e, func() {
+		listed := make(map[int]bool)
+		if s, ok := r.ships[r.current]; ok {
+			listed[s.ShipID] = true
+		}
+
+		for _, h := range e.ShipsHere {
+			s := r.ship(h.ShipID, h.ShipType, e.Timestamp)
+			s.TypeLocalised = h.ShipTypeLocalised
+			s.Value = h.Value
+			s.Hot = h.Hot
+			if h.Name != "" {
+				s.Name = h.Name
+			}
+			s.StarSystem = e.StarSystem
+			s.Station = e.StationName
+			s.MarketID = e.MarketID
+			s.Transit = false
+			s.TransferArrival = time.Time{}
+			listed[s.ShipID] = true
+		}
+
+		for _, h := range e.ShipsRemote {
+			s := r.ship(h.ShipID, h.ShipType, e.Timestamp)
+			s.TypeLocalised = h.ShipTypeLocalised
+			s.Value = h.Value
+			s.Hot = h.Hot
+			if h.Name != "" {
+				s.Name = h.Name
+			}
+			if h.InTransit {
+				if !s.Transit {
+					s.TransferArrival = time.Time{}
+				}
+				s.Transit = true
+			} else {
+				if s.StarSystem != h.StarSystem || s.MarketID != h.ShipMarketID {
+					s.Station = ""
+				}
+				s.StarSystem = h.StarSystem
+				s.MarketID = h.ShipMarketID
+				s.Transit = false
+				s.TransferArrival = time.Time{}
+			}
+			listed[s.ShipID] = true
+		}
+
+		// anything else has been sold or destroyed while we weren't looking
+		for id := range r.ships {
+			if !listed[id] {
+				delete(r.ships, id)
+			}
+		}
+	})
+}
+
+// isShip reports whether a LoadGame vehicle is a ship. Games loaded on foot
+// or in an SRV name the suit or SRV instead, with an id of its own
+func isShip(vehicle string) bool {
+	v := strings.ToLower(vehicle)
+	return !strings.Contains(v, "suit") && v != "testbuggy" && !strings.HasSuffix(v, "_srv_01")
+}
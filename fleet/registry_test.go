@@ -0,0 +1,98 @@
+package fleet
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sht/ed-journal/dispatcher"
+)
+
+func trigger(t *testing.T, d *dispatcher.Dispatcher, name, fields string) {
+	t.Helper()
+
+	b := []byte(`{ "timestamp":"2021-01-01T00:00:00Z", "event":"` + name + `", ` + fields + ` }`)
+	err := d.Trigger(name, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// owned returns the ids of the ships in the registry
+func owned(r *Registry) []int {
+	ids := make([]int, 0)
+	for _, s := range r.Ships() {
+		ids = append(ids, s.ShipID)
+	}
+	return ids
+}
+
+func checkShips(t *testing.T, r *Registry, current int, ids ...int) {
+	t.Helper()
+
+	if got := owned(r); !reflect.DeepEqual(got, ids) {
+		t.Errorf("ships %v, want %v", got, ids)
+	}
+	s, ok := r.Current()
+	if !ok || s.ShipID != current {
+		t.Errorf("current ship %d, %v, want %d", s.ShipID, ok, current)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	d := dispatcher.NewDispatcher()
+	r := NewRegistry()
+	r.AddListeners(d)
+
+	trigger(t, d, "LoadGame", `"Commander":"Bob", "Ship":"Python", "ShipID":1, "ShipName":"Snake", "ShipIdent":"SN-01"`)
+	trigger(t, d, "Docked", `"StarSystem":"Shinrarta Dezhra", "StationName":"Jameson Memorial", "MarketID":128666762`)
+	checkShips(t, r, 1, 1)
+
+	// buying a ship stores the one flown
+	trigger(t, d, "ShipyardBuy", `"ShipType":"krait_mkii", "ShipPrice":40000000, "StoreOldShip":"Python", "StoreShipID":1, "MarketID":128666762`)
+	trigger(t, d, "ShipyardNew", `"ShipType":"krait_mkii", "NewShipID":2`)
+	checkShips(t, r, 2, 1, 2)
+	s, _ := r.Ship(1)
+	if s.Current || s.Station != "Jameson Memorial" || s.Name != "Snake" {
+		t.Errorf("stored ship %+v, want at Jameson Memorial", s)
+	}
+
+	// swapping back and selling the other one
+	trigger(t, d, "ShipyardSwap", `"ShipType":"python", "ShipID":1, "StoreOldShip":"krait_mkii", "StoreShipID":2, "MarketID":128666762`)
+	checkShips(t, r, 1, 1, 2)
+	trigger(t, d, "ShipyardSell", `"ShipType":"krait_mkii", "SellShipID":2, "ShipPrice":38000000, "MarketID":128666762`)
+	checkShips(t, r, 1, 1)
+
+	// loading the game on foot names the suit, which is not a ship
+	trigger(t, d, "LoadGame", `"Commander":"Bob", "Ship":"TacticalSuit_Class1", "Ship_Localised":"Dominator Suit", "ShipID":4293000005`)
+	trigger(t, d, "LoadGame", `"Commander":"Bob", "Ship":"testbuggy", "Ship_Localised":"SRV Scarab", "ShipID":4293000006`)
+	checkShips(t, r, 1, 1)
+
+	// stored ships list the fleet, anything else is gone
+	trigger(t, d, "StoredShips", `"StationName":"Jameson Memorial", "MarketID":128666762, "StarSystem":"Shinrarta Dezhra",
+		"ShipsHere":[{ "ShipID":3, "ShipType":"sidewinder", "Value":30000, "Hot":false }],
+		"ShipsRemote":[{ "ShipID":4, "ShipType":"anaconda", "Value":140000000, "Hot":false, "InTransit":true }]`)
+	checkShips(t, r, 1, 1, 3, 4)
+	s, _ = r.Ship(3)
+	if s.Station != "Jameson Memorial" || s.Value != 30000 {
+		t.Errorf("ship here %+v, want at Jameson Memorial", s)
+	}
+	s, _ = r.Ship(4)
+	if !s.Transit {
+		t.Errorf("remote ship %+v, want in transit", s)
+	}
+
+	path := filepath.Join(t.TempDir(), "fleet.json")
+	err := r.Save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Ships(), r.Ships()) {
+		t.Errorf("loaded %+v, want %+v", loaded.Ships(), r.Ships())
+	}
+	checkShips(t, loaded, 1, 1, 3, 4)
+}
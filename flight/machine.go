@@ -0,0 +1,278 @@
+package flight
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/event"
+	"github.com/sht/ed-journal/events"
+)
+
+// State is the flight state of the commander's ship
+type State string
+
+const (
+	Unknown     State = "Unknown"
+	Docked      State = "Docked"
+	NormalSpace State = "NormalSpace"
+	Supercruise State = "Supercruise"
+	Hyperspace  State = "Hyperspace"
+	Landed      State = "Landed"
+)
+
+const (
+	FlightStateChanged   = "FlightStateChanged"
+	ImpossibleTransition = "ImpossibleTransition"
+)
+
+// StateChangedEvent is triggered on the dispatcher when the flight state
+// changes
+type StateChangedEvent struct {
+	event.Event
+	From    State  `json:"From"`
+	To      State  `json:"To"`
+	Trigger string `json:"Trigger"`
+}
+
+// ImpossibleTransitionEvent is triggered on the dispatcher when an event
+// arrives that cannot happen in the current state. This usually means journal
+// lines were missed
+type ImpossibleTransitionEvent struct {
+	event.Event
+	State    State   `json:"State"`
+	Trigger  string  `json:"Trigger"`
+	Expected []State `json:"Expected"`
+	Resumed  State   `json:"Resumed"`
+}
+
+// transition describes the states an event is valid in and the state it
+// leads to. An empty to leaves the state unchanged
+type transition struct {
+	from []State
+	to   State
+}
+
+var transitions = map[string]transition{
+	events.Docked:           {from: []State{NormalSpace}, to: Docked},
+	events.Undocked:         {from: []State{Docked}, to: NormalSpace},
+	events.DockingGranted:   {from: []State{NormalSpace}},
+	events.FSDJump:          {from: []State{Hyperspace}, to: Supercruise},
+	events.SupercruiseEntry: {from: []State{NormalSpace}, to: Supercruise},
+	events.SupercruiseExit:  {from: []State{Supercruise}, to: NormalSpace},
+	events.ApproachBody:     {from: []State{Supercruise, NormalSpace}},
+	events.LeaveBody:        {from: []State{Supercruise, NormalSpace}},
+	events.Touchdown:        {from: []State{NormalSpace}, to: Landed},
+	events.Liftoff:          {from: []State{Landed}, to: NormalSpace},
+}
+
+// Machine tracks the flight state from travel events. It is safe for
+// concurrent use
+type Machine struct {
+	mu        sync.RWMutex
+	d         *dispatcher.Dispatcher
+	state     State
+	since     time.Time
+	anomalies []ImpossibleTransitionEvent
+}
+
+func NewMachine() *Machine {
+	return &Machine{
+		state:     Unknown,
+		anomalies: make([]ImpossibleTransitionEvent, 0),
+	}
+}
+
+// State returns the current flight state and when it was entered
+func (m *Machine) State() (State, time.Time) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.state, m.since
+}
+
+// Anomalies returns the impossible transitions seen so far
+func (m *Machine) Anomalies() []ImpossibleTransitionEvent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	a := make([]ImpossibleTransitionEvent, len(m.anomalies))
+	copy(a, m.anomalies)
+	return a
+}
+
+const SnapshotVersion = 1
+
+// Snapshot saves the flight state and the impossible transitions seen
+func (m *Machine) Snapshot() (json.RawMessage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return json.Marshal(m.saved())
+}
+
+func (m *Machine) Restore(b json.RawMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return json.Unmarshal(b, m.saved())
+}
+
+// saved points at the fields kept in snapshots
+func (m *Machine) saved() interface{} {
+	return &struct {
+		State     *State                       `json:"state"`
+		Since     *time.Time                   `json:"since"`
+		Anomalies *[]ImpossibleTransitionEvent `json:"anomalies"`
+	}{&m.state, &m.since, &m.anomalies}
+}
+
+// AddListeners subscribes the machine to the travel events. Transition events
+// are triggered on the same dispatcher
+func (m *Machine) AddListeners(d *dispatcher.Dispatcher) {
+	m.mu.Lock()
+	m.d = d
+	m.mu.Unlock()
+
+	// startup
+	{
+		d.OnSync(events.LoadGame, m.loadGame)
+	}
+
+	// travel
+	{
+		for name := range transitions {
+			d.OnSync(name, m.transition)
+		}
+		d.OnSync(events.Location, m.location)
+		d.OnSync(events.StartJump, m.startJump)
+	}
+}
+
+func (m *Machine) loadGame(b []byte) {
+	var e events.LoadGameEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	to := Unknown
+	if e.StartLanded {
+		to = Landed
+	}
+	m.apply(e.Event, nil, to)
+}
+
+func (m *Machine) location(b []byte) {
+	var e events.LocationEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	m.mu.RLock()
+	to := NormalSpace
+	if e.Docked {
+		to = Docked
+	} else if m.state == Landed {
+		to = Landed
+	}
+	m.mu.RUnlock()
+
+	m.apply(e.Event, nil, to)
+}
+
+func (m *Machine) startJump(b []byte) {
+	var e events.StartJumpEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	switch e.JumpType {
+	case "Hyperspace":
+		m.apply(e.Event, []State{NormalSpace, Supercruise}, Hyperspace)
+	default:
+		// supercruise is entered with SupercruiseEntry once charged
+		m.apply(e.Event, []State{NormalSpace}, "")
+	}
+}
+
+func (m *Machine) transition(b []byte) {
+	var e event.Event
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	t, ok := transitions[e.Event]
+	if !ok {
+		return
+	}
+	m.apply(e, t.from, t.to)
+}
+
+// apply moves the machine to state to for event e, reporting an impossible
+// transition when the current state is not one of from. A nil from accepts
+// any state, an empty to leaves the state unchanged
+func (m *Machine) apply(e event.Event, from []State, to State) {
+	m.mu.Lock()
+	d := m.d
+	prev := m.state
+	triggers := make([]interface{}, 0, 2)
+	names := make([]string, 0, 2)
+
+	if from != nil && prev != Unknown && !contains(from, prev) {
+		resumed := to
+		if resumed == "" {
+			resumed = from[0]
+		}
+		anomaly := ImpossibleTransitionEvent{
+			Event:    event.Event{Event: ImpossibleTransition, Timestamp: e.Timestamp},
+			State:    prev,
+			Trigger:  e.Event,
+			Expected: from,
+			Resumed:  resumed,
+		}
+		m.anomalies = append(m.anomalies, anomaly)
+		triggers = append(triggers, anomaly)
+		names = append(names, ImpossibleTransition)
+		if to == "" {
+			to = resumed
+		}
+	}
+
+	if to != "" && to != prev {
+		m.state = to
+		m.since = e.Timestamp
+		triggers = append(triggers, StateChangedEvent{
+			Event:   event.Event{Event: FlightStateChanged, Timestamp: e.Timestamp},
+			From:    prev,
+			To:      to,
+			Trigger: e.Event,
+		})
+		names = append(names, FlightStateChanged)
+	}
+	m.mu.Unlock()
+
+	if d == nil {
+		return
+	}
+	for i, t := range triggers {
+		b, err := json.Marshal(t)
+		if err != nil {
+			continue
+		}
+		_ = d.Trigger(names[i], b)
+	}
+}
+
+func contains(states []State, s State) bool {
+	for _, st := range states {
+		if st == s {
+			return true
+		}
+	}
+	return false
+}
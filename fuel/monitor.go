@@ -0,0 +1,384 @@
+package fuel
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/event"
+	"github.com/sht/ed-journal/events"
+	"github.com/sht/ed-journal/route"
+)
+
+const (
+	FuelWarning = "FuelWarning"
+)
+
+// Warning kinds
+const (
+	// Unreachable is sent when the fuel left is not enough to reach the next
+	// scoopable star on the route, or the end of the route when there is none
+	Unreachable = "unreachable"
+	// Reserve is sent when the main tank is empty and the reservoir is in use
+	Reserve = "reserve"
+)
+
+// recentJumps is the number of jumps used to estimate the fuel used per
+// light year
+const recentJumps = 20
+
+// Level is the fuel in the tanks
+type Level struct {
+	Main             float64   `json:"main"`
+	Reservoir        float64   `json:"reservoir"`
+	MainCapacity     float64   `json:"mainCapacity"`
+	ReserveCapacity  float64   `json:"reserveCapacity"`
+	FuelPerLightYear float64   `json:"fuelPerLightYear"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// WarningEvent is triggered on the dispatcher when fuel runs short. For
+// Unreachable warnings Needed is the estimated fuel to reach Target
+type WarningEvent struct {
+	event.Event
+	Kind   string  `json:"Kind"`
+	Fuel   float64 `json:"Fuel"`
+	Needed float64 `json:"Needed,omitempty"`
+	Target string  `json:"Target,omitempty"`
+	Jumps  int     `json:"Jumps,omitempty"`
+}
+
+// Monitor tracks the fuel in the tanks and warns when it runs short. It is
+// safe for concurrent use
+type Monitor struct {
+	mu      sync.RWMutex
+	d       *dispatcher.Dispatcher
+	routes  *route.Tracker
+	level   Level
+	rates   []float64
+	reserve bool
+}
+
+// NewMonitor returns a fuel monitor. When routes is not nil, the plotted
+// route is used to check the next scoopable star can be reached
+func NewMonitor(routes *route.Tracker) *Monitor {
+	return &Monitor{
+		routes: routes,
+		rates:  make([]float64, 0, recentJumps),
+	}
+}
+
+const SnapshotVersion = 1
+
+// Snapshot saves the fuel level and the recent consumption rates
+func (m *Monitor) Snapshot() (json.RawMessage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return json.Marshal(m.saved())
+}
+
+func (m *Monitor) Restore(b json.RawMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return json.Unmarshal(b, m.saved())
+}
+
+// saved points at the fields kept in snapshots
+func (m *Monitor) saved() interface{} {
+	return &struct {
+		Level   *Level     `json:"level"`
+		Rates   *[]float64 `json:"rates"`
+		Reserve *bool      `json:"reserve"`
+	}{&m.level, &m.rates, &m.reserve}
+}
+
+// AddListeners subscribes the monitor to the fuel events. FuelWarning events
+// are triggered on the same dispatcher
+func (m *Monitor) AddListeners(d *dispatcher.Dispatcher) {
+	m.mu.Lock()
+	m.d = d
+	m.mu.Unlock()
+
+	// startup
+	{
+		d.OnSync(events.LoadGame, m.loadGame)
+		d.OnSync(events.Loadout, m.loadout)
+	}
+
+	// travel
+	{
+		d.OnSync(events.FSDJump, m.fsdJump)
+		d.OnSync(events.NavRoute, m.navRoute)
+	}
+
+	// station services
+	{
+		d.OnSync(events.RefuelAll, m.refuelAll)
+		d.OnSync(events.RefuelPartial, m.refuelPartial)
+	}
+
+	// status file
+	{
+		d.OnSync(events.Status, m.status)
+	}
+
+	// other
+	{
+		d.OnSync(events.FuelScoop, m.fuelScoop)
+		d.OnSync(events.ReservoirReplenished, m.reservoirReplenished)
+	}
+}
+
+// Level returns the fuel in the tanks
+func (m *Monitor) Level() Level {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.level
+}
+
+// Scoopable reports whether fuel can be scooped from a star of the given
+// class
+func Scoopable(starClass string) bool {
+	class := strings.SplitN(starClass, "_", 2)[0]
+	switch class {
+	case "O", "B", "A", "F", "G", "K", "M":
+		return true
+	}
+	return false
+}
+
+// update decodes b into e and applies fn while holding the lock
+func (m *Monitor) update(b []byte, e interface{}, fn func()) {
+	err := json.Unmarshal(b, e)
+	if err != nil {
+		return
+	}
+
+	m.mu.Lock()
+	fn()
+	m.mu.Unlock()
+}
+
+func (m *Monitor) loadGame(b []byte) {
+	var e events.LoadGameEvent
+	m.update(b, &e, func() {
+		if e.FuelLevel != nil {
+			m.level.Main = *e.FuelLevel
+		}
+		if e.FuelCapacity != nil {
+			m.level.MainCapacity = *e.FuelCapacity
+		}
+		m.level.UpdatedAt = e.Timestamp
+		m.reserve = false
+	})
+}
+
+func (m *Monitor) loadout(b []byte) {
+	var e events.LoadoutEvent
+	m.update(b, &e, func() {
+		m.level.MainCapacity = e.FuelCapacity.Main
+		m.level.ReserveCapacity = e.FuelCapacity.Reserve
+		m.level.UpdatedAt = e.Timestamp
+	})
+}
+
+func (m *Monitor) refuelAll(b []byte) {
+	var e events.RefuelAllEvent
+	m.update(b, &e, func() {
+		m.refuel(e.Amount, e.Timestamp)
+	})
+}
+
+func (m *Monitor) refuelPartial(b []byte) {
+	var e events.RefuelPartialEvent
+	m.update(b, &e, func() {
+		m.refuel(e.Amount, e.Timestamp)
+	})
+}
+
+// refuel adds fuel bought at a station. Must be called with the lock held
+func (m *Monitor) refuel(amount float64, ts time.Time) {
+	m.level.Main += amount
+	if m.level.MainCapacity > 0 {
+		m.level.Main = math.Min(m.level.Main, m.level.MainCapacity)
+	}
+	m.level.UpdatedAt = ts
+	m.reserve = false
+}
+
+func (m *Monitor) fuelScoop(b []byte) {
+	var e events.FuelScoopEvent
+	m.update(b, &e, func() {
+		m.level.Main = e.Total
+		m.level.UpdatedAt = e.Timestamp
+		m.reserve = false
+	})
+}
+
+func (m *Monitor) reservoirReplenished(b []byte) {
+	var e events.ReservoirReplenishedEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	m.setLevel(e.Timestamp, e.FuelMain, e.FuelReservoir)
+}
+
+func (m *Monitor) status(b []byte) {
+	var e events.StatusEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+	if e.Fuel == nil {
+		return
+	}
+	// Status.json is left over from the last time the game ran
+	m.mu.RLock()
+	stale := e.Timestamp.Before(m.level.UpdatedAt)
+	m.mu.RUnlock()
+	if stale {
+		return
+	}
+
+	m.setLevel(e.Timestamp, e.Fuel.FuelMain, e.Fuel.FuelReservoir)
+}
+
+// setLevel records the fuel in both tanks, warning once when the main tank
+// runs dry
+func (m *Monitor) setLevel(ts time.Time, main, reservoir float64) {
+	m.mu.Lock()
+	m.level.Main = main
+	m.level.Reservoir = reservoir
+	m.level.UpdatedAt = ts
+	empty := main <= 0
+	warn := empty && !m.reserve
+	m.reserve = empty
+	m.mu.Unlock()
+
+	if warn {
+		m.trigger(WarningEvent{
+			Event: event.Event{Event: FuelWarning, Timestamp: ts},
+			Kind:  Reserve,
+			Fuel:  reservoir,
+		})
+	}
+}
+
+func (m *Monitor) fsdJump(b []byte) {
+	var e events.FSDJumpEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	m.mu.Lock()
+	m.level.Main = e.FuelLevel
+	m.level.UpdatedAt = e.Timestamp
+	if e.JumpDist > 0 && e.FuelUsed > 0 {
+		if len(m.rates) == recentJumps {
+			m.rates = m.rates[1:]
+		}
+		m.rates = append(m.rates, e.FuelUsed/e.JumpDist)
+	}
+	m.level.FuelPerLightYear = m.rate()
+	m.mu.Unlock()
+
+	m.check(e.Timestamp)
+}
+
+func (m *Monitor) navRoute(b []byte) {
+	var e event.Event
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	m.check(e.Timestamp)
+}
+
+// rate returns the highest fuel used per light year over the recent jumps,
+// to err on the safe side. Must be called with the lock held
+func (m *Monitor) rate() float64 {
+	max := 0.0
+	for _, r := range m.rates {
+		max = math.Max(max, r)
+	}
+	return max
+}
+
+// check warns when the fuel left cannot reach the next scoopable star on the
+// plotted route. The route tracker must be subscribed before the monitor so
+// that its progress is up to date
+func (m *Monitor) check(ts time.Time) {
+	if m.routes == nil {
+		return
+	}
+	p, ok := m.routes.Progress()
+	if !ok || p.Deviated || p.Complete {
+		return
+	}
+
+	m.mu.RLock()
+	fuel := m.level.Main
+	rate := m.level.FuelPerLightYear
+	m.mu.RUnlock()
+	if rate == 0 {
+		return
+	}
+
+	needed := 0.0
+	target := ""
+	jumps := 0
+	for i := p.Hop; i+1 < len(p.Route); i++ {
+		from, to := p.Route[i], p.Route[i+1]
+		needed += distance(from.StarPos, to.StarPos) * rate
+		target = to.StarSystem
+		jumps++
+		if Scoopable(to.StarClass) {
+			break
+		}
+	}
+	if needed <= fuel {
+		return
+	}
+
+	m.trigger(WarningEvent{
+		Event:  event.Event{Event: FuelWarning, Timestamp: ts},
+		Kind:   Unreachable,
+		Fuel:   fuel,
+		Needed: needed,
+		Target: target,
+		Jumps:  jumps,
+	})
+}
+
+func (m *Monitor) trigger(w WarningEvent) {
+	m.mu.RLock()
+	d := m.d
+	m.mu.RUnlock()
+	if d == nil {
+		return
+	}
+
+	b, err := json.Marshal(w)
+	if err != nil {
+		return
+	}
+	_ = d.Trigger(FuelWarning, b)
+}
+
+func distance(a, b []float64) float64 {
+	if len(a) != 3 || len(b) != 3 {
+		return 0
+	}
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/importer"
+	"github.com/sht/ed-journal/store"
+)
+
+// files in the data directory
+const (
+	// importedFile keeps how much of each journal file was imported
+	importedFile = "imported.json"
+	// fleetFile keeps the fleet registry between runs
+	fleetFile = "fleet.json"
+)
+
+// openStore opens the event store in the data directory
+func openStore(data string) (*store.FileStore, error) {
+	return store.OpenFileStore(filepath.Join(data, "store"))
+}
+
+// newRecorder returns a recorder that carries on after the last stored event,
+// so journals read again are not stored twice. Status file updates are not
+// stored either, they are not part of the journal
+func newRecorder(s *store.FileStore) (*store.Recorder, error) {
+	rec := store.NewRecorder(s, notJournal...)
+	last, ok, err := s.Last()
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		rec.ResumeAfter(last)
+	}
+	return rec, nil
+}
+
+func importCmd(args []string) int {
+	fs := newFlagSet("import")
+	dir := dirFlag(fs)
+	data := dataFlag(fs)
+	format := formatFlag(fs, "table")
+	quiet := fs.Bool("q", false, "do not report progress")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, fmt.Errorf("unexpected argument %q", fs.Arg(0)))
+	}
+	out, err := newWriter(*format, []string{"path", "events", "skipped", "errors", "error"})
+	if err != nil {
+		return usageError(fs, err)
+	}
+
+	s, err := openStore(*data)
+	if err != nil {
+		return fail(err)
+	}
+	defer s.Close()
+
+	d := dispatcher.NewDispatcher()
+	rec, err := newRecorder(s)
+	if err != nil {
+		return fail(err)
+	}
+	rec.AddListeners(d)
+
+	im := importer.NewImporter(d)
+	imported := filepath.Join(*data, importedFile)
+	err = im.LoadImported(imported)
+	if err != nil {
+		return fail(err)
+	}
+	// files read once the recorder failed are not all stored, they are
+	// imported again next time
+	unstored := make([]string, 0)
+	im.OnProgress = func(p importer.Progress) {
+		if rec.Err() != nil && !p.Result.Skipped {
+			unstored = append(unstored, p.Result.Path)
+		}
+		if !*quiet {
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s\n", p.File, p.Files, describe(p.Result))
+		}
+	}
+
+	results, err := im.ImportDir(*dir)
+	if err != nil {
+		return fail(err)
+	}
+	for _, path := range unstored {
+		im.Forget(path)
+	}
+	err = im.SaveImported(imported)
+	if err != nil {
+		return fail(err)
+	}
+
+	code := exitOK
+	for _, r := range results {
+		row := map[string]interface{}{
+			"path":    r.Path,
+			"events":  float64(r.Events),
+			"skipped": r.Skipped,
+			"errors":  float64(len(r.Errors)),
+		}
+		if r.Err != nil {
+			row["error"] = r.Err.Error()
+			code = exitError
+		}
+		err = out.Write(row)
+		if err != nil {
+			return fail(err)
+		}
+	}
+	err = out.Flush()
+	if err != nil {
+		return fail(err)
+	}
+	if err = rec.Err(); err != nil {
+		return fail(err)
+	}
+	return code
+}
+
+// describe returns a one line report of an imported file
+func describe(r importer.Result) string {
+	name := filepath.Base(r.Path)
+	switch {
+	case r.Err != nil:
+		return fmt.Sprintf("%s: %v", name, r.Err)
+	case r.Skipped:
+		return name + ": already imported"
+	}
+	events := fmt.Sprintf("%d events", r.Events)
+	if r.From > 0 {
+		events = fmt.Sprintf("%d new events", r.Events)
+	}
+	if len(r.Errors) > 0 {
+		return fmt.Sprintf("%s: %s, %d bad lines", name, events, len(r.Errors))
+	}
+	return fmt.Sprintf("%s: %s", name, events)
+}
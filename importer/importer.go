@@ -0,0 +1,242 @@
+package importer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/event"
+)
+
+// maxLine is the longest journal line read. Loadout and Market lines can be
+// large, but never near this
+const maxLine = 4 * 1024 * 1024
+
+// LineError is a journal line that could not be parsed
+type LineError struct {
+	Line int    `json:"line"`
+	Err  string `json:"error"`
+}
+
+// Result is the outcome of importing one journal file. Err is set when the
+// file could not be read, only the lines read before are marked as imported
+// then. From is the offset the import started at, which is not 0 when the
+// file grew since it was last imported
+type Result struct {
+	Path    string      `json:"path"`
+	From    int64       `json:"from,omitempty"`
+	Events  int         `json:"events"`
+	Skipped bool        `json:"skipped,omitempty"`
+	Errors  []LineError `json:"errors,omitempty"`
+	Err     error       `json:"-"`
+}
+
+// Progress is reported after every file of an import
+type Progress struct {
+	File   int
+	Files  int
+	Result Result
+}
+
+// Mark records how much of a journal file was imported: its first Size
+// bytes, whose SHA-256 hash is Hash
+type Mark struct {
+	Size int64  `json:"size"`
+	Hash string `json:"hash"`
+}
+
+// Importer replays journal archives through a dispatcher, remembering how
+// much of every file was imported so no line is imported twice. Files are
+// known by name, so an archive moved elsewhere is not imported again, and a
+// file the game is still writing only has its new lines imported. It is safe
+// for concurrent use, but imports run one at a time
+type Importer struct {
+	mu       sync.Mutex
+	d        *dispatcher.Dispatcher
+	imported map[string]Mark
+
+	// OnProgress is called after every file when set
+	OnProgress func(p Progress)
+}
+
+// NewImporter returns an importer triggering events on d
+func NewImporter(d *dispatcher.Dispatcher) *Importer {
+	return &Importer{
+		d:        d,
+		imported: make(map[string]Mark),
+	}
+}
+
+// Imported returns how much of each file was imported, by file name
+func (im *Importer) Imported() map[string]Mark {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	imported := make(map[string]Mark, len(im.imported))
+	for name, m := range im.imported {
+		imported[name] = m
+	}
+	return imported
+}
+
+// Forget removes a file from the imported ones, so that it is imported again
+// from the start next time
+func (im *Importer) Forget(path string) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	delete(im.imported, filepath.Base(path))
+}
+
+// LoadImported reads how much of each file was imported in earlier runs, as
+// saved by SaveImported. A missing file is not an error
+func (im *Importer) LoadImported(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var imported map[string]Mark
+	err = json.Unmarshal(b, &imported)
+	if err != nil {
+		return err
+	}
+
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	for name, m := range imported {
+		im.imported[name] = m
+	}
+	return nil
+}
+
+// SaveImported writes how much of each file was imported
+func (im *Importer) SaveImported(path string) error {
+	b, err := json.Marshal(im.Imported())
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// ImportDir imports the journal files in dir in the order they were written.
+// Errors in single files are reported in their result and do not stop the
+// import
+func (im *Importer) ImportDir(dir string) ([]Result, error) {
+	files, err := event.FindJournals(dir)
+	if err != nil {
+		return nil, err
+	}
+	return im.Import(files), nil
+}
+
+// Import imports journal files in the given order
+func (im *Importer) Import(files []event.JournalFile) []Result {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	results := make([]Result, 0, len(files))
+	for i, f := range files {
+		r := im.importFile(f.Path)
+		results = append(results, r)
+		if im.OnProgress != nil {
+			im.OnProgress(Progress{File: i + 1, Files: len(files), Result: r})
+		}
+	}
+	return results
+}
+
+// importFile imports the lines of a file added since it was last imported.
+// A file whose imported part changed is imported again from the start. Must
+// be called with the lock held
+func (im *Importer) importFile(path string) Result {
+	r := Result{Path: path}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	name := filepath.Base(path)
+	if m, ok := im.imported[name]; ok && m.Size <= int64(len(b)) && hash(b[:m.Size]) == m.Hash {
+		r.From = m.Size
+	}
+
+	line := bytes.Count(b[:r.From], []byte{'\n'})
+	size, err := im.importLines(b[r.From:], line, &r)
+	r.Err = err
+	if size == 0 {
+		r.Skipped = r.From > 0 && err == nil
+		return r
+	}
+	end := r.From + size
+	im.imported[name] = Mark{Size: end, Hash: hash(b[:end])}
+	return r
+}
+
+// importLines triggers the events of the complete lines in b, which start
+// after the given line number, returning the number of bytes read. A last
+// line without a line break is only imported when it is a whole event,
+// otherwise the game may still be writing it
+func (im *Importer) importLines(b []byte, line int, r *Result) (int64, error) {
+	var size int64
+	for len(b) > 0 {
+		line++
+		l := b
+		n := bytes.IndexByte(b, '\n')
+		if n >= 0 {
+			l = b[:n]
+		} else if !json.Valid(bytes.TrimSpace(b)) {
+			break
+		}
+		if len(l) > maxLine {
+			return size, fmt.Errorf("line %d: line too long", line)
+		}
+		b = b[len(l):]
+		size += int64(len(l))
+		if n >= 0 {
+			b = b[1:]
+			size++
+		}
+
+		l = bytes.TrimSpace(l)
+		if len(l) == 0 {
+			continue
+		}
+		var e event.Event
+		err := json.Unmarshal(l, &e)
+		if err != nil {
+			r.Errors = append(r.Errors, LineError{Line: line, Err: err.Error()})
+			continue
+		}
+		if e.Event == "" {
+			r.Errors = append(r.Errors, LineError{Line: line, Err: "missing event name"})
+			continue
+		}
+
+		// handlers may keep the line, so hand each its own copy
+		data := make([]byte, len(l))
+		copy(data, l)
+		// events nobody listens to are not an error
+		_ = im.d.Trigger(e.Event, data)
+		r.Events++
+	}
+	return size, nil
+}
+
+// hash returns the hex encoded SHA-256 hash of b
+func hash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,210 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sht/ed-journal/dispatcher"
+)
+
+// line returns a journal line for event n
+func line(n int) string {
+	return fmt.Sprintf(`{ "timestamp":"2021-01-01T00:00:%02dZ", "event":"Test", "N":%d }`+"\r\n", n, n)
+}
+
+func lines(from, to int) string {
+	var b strings.Builder
+	for n := from; n < to; n++ {
+		b.WriteString(line(n))
+	}
+	return b.String()
+}
+
+func writeJournal(t *testing.T, dir, name, s string) {
+	t.Helper()
+
+	err := ioutil.WriteFile(filepath.Join(dir, name), []byte(s), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func appendJournal(t *testing.T, dir, name, s string) {
+	t.Helper()
+
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	_, err = f.WriteString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// recorder is an importer recording the events it triggers
+type recorder struct {
+	*Importer
+	t   *testing.T
+	dir string
+	got []int
+}
+
+func newRecorder(t *testing.T) *recorder {
+	d := dispatcher.NewDispatcher()
+	r := &recorder{
+		Importer: NewImporter(d),
+		t:        t,
+		dir:      t.TempDir(),
+	}
+	d.OnAllSync(func(b []byte) {
+		var e struct {
+			N int
+		}
+		err := json.Unmarshal(b, &e)
+		if err != nil {
+			t.Errorf("handler got %q: %v", b, err)
+		}
+		r.got = append(r.got, e.N)
+	})
+	return r
+}
+
+// importDir imports the directory and checks the events triggered
+func (r *recorder) importDir(want ...int) []Result {
+	r.t.Helper()
+
+	r.got = nil
+	results, err := r.ImportDir(r.dir)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	if len(want) == 0 {
+		want = nil
+	}
+	if !reflect.DeepEqual(r.got, want) {
+		r.t.Errorf("imported %v, want %v", r.got, want)
+	}
+	return results
+}
+
+func TestImportOrder(t *testing.T) {
+	r := newRecorder(t)
+	writeJournal(t, r.dir, "Journal.2021-01-02T000000.02.log", lines(6, 8))
+	writeJournal(t, r.dir, "Journal.2021-01-02T000000.01.log", lines(4, 6))
+	writeJournal(t, r.dir, "Journal.201231000000.01.log", lines(0, 2))
+	writeJournal(t, r.dir, "Journal.2021-01-01T000000.01.log", lines(2, 4))
+	writeJournal(t, r.dir, "notes.txt", "not a journal")
+
+	results := r.importDir(0, 1, 2, 3, 4, 5, 6, 7)
+	if len(results) != 4 {
+		t.Errorf("got %d results, want one per journal file", len(results))
+	}
+}
+
+func TestImportSkipsImported(t *testing.T) {
+	r := newRecorder(t)
+	writeJournal(t, r.dir, "Journal.2021-01-01T000000.01.log", lines(0, 3))
+	r.importDir(0, 1, 2)
+
+	results := r.importDir()
+	if !results[0].Skipped {
+		t.Errorf("result %+v, want skipped", results[0])
+	}
+
+	// an archive moved elsewhere is known by name
+	path := filepath.Join(r.dir, "Journal.2021-01-01T000000.01.log")
+	moved := filepath.Join(t.TempDir(), "Journal.2021-01-01T000000.01.log")
+	err := os.Rename(path, moved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.dir = filepath.Dir(moved)
+	r.importDir()
+}
+
+func TestImportGrowingFile(t *testing.T) {
+	r := newRecorder(t)
+	name := "Journal.2021-01-01T000000.01.log"
+	partial := line(3)
+	writeJournal(t, r.dir, name, lines(0, 3)+partial[:10])
+	r.importDir(0, 1, 2)
+
+	// only the new lines are imported, once complete
+	appendJournal(t, r.dir, name, partial[10:]+lines(4, 5))
+	results := r.importDir(3, 4)
+	if results[0].From != int64(len(lines(0, 3))) {
+		t.Errorf("import started at %d, want %d", results[0].From, len(lines(0, 3)))
+	}
+
+	// a last line without a line break is imported when it is whole
+	appendJournal(t, r.dir, name, strings.TrimSpace(line(5)))
+	r.importDir(5)
+	r.importDir()
+}
+
+func TestImportReplacedFile(t *testing.T) {
+	r := newRecorder(t)
+	name := "Journal.2021-01-01T000000.01.log"
+	writeJournal(t, r.dir, name, lines(0, 3))
+	r.importDir(0, 1, 2)
+
+	// a file whose imported part changed is imported again
+	writeJournal(t, r.dir, name, lines(5, 9))
+	r.importDir(5, 6, 7, 8)
+}
+
+func TestImportLineErrors(t *testing.T) {
+	r := newRecorder(t)
+	name := "Journal.2021-01-01T000000.01.log"
+	writeJournal(t, r.dir, name, line(0)+"{ not json\r\n\r\n"+`{ "timestamp":"2021-01-01T00:00:00Z" }`+"\r\n"+line(1))
+	results := r.importDir(0, 1)
+
+	want := []int{2, 4}
+	got := make([]int, 0)
+	for _, e := range results[0].Errors {
+		got = append(got, e.Line)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("errors on lines %v, want %v", got, want)
+	}
+
+	// line numbers count from the start of the file when it grew
+	appendJournal(t, r.dir, name, "{ not json\r\n")
+	results = r.importDir()
+	if len(results[0].Errors) != 1 || results[0].Errors[0].Line != 6 {
+		t.Errorf("errors %+v, want one on line 6", results[0].Errors)
+	}
+}
+
+func TestImportedSaveLoad(t *testing.T) {
+	r := newRecorder(t)
+	name := "Journal.2021-01-01T000000.01.log"
+	writeJournal(t, r.dir, name, lines(0, 3))
+	r.importDir(0, 1, 2)
+	path := filepath.Join(t.TempDir(), "imported.json")
+	err := r.SaveImported(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := newRecorder(t)
+	next.dir = r.dir
+	err = next.LoadImported(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendJournal(t, r.dir, name, lines(3, 4))
+	next.importDir(3)
+
+	// forgotten files are imported again from the start
+	next.Forget(filepath.Join(r.dir, name))
+	next.importDir(0, 1, 2, 3)
+}
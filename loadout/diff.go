@@ -0,0 +1,196 @@
+package loadout
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sht/ed-journal/events"
+)
+
+// Module is a module in a slot
+type Module struct {
+	Slot string `json:"slot"`
+	Item string `json:"item"`
+}
+
+// Move is a module moved from one slot to another
+type Move struct {
+	Item string `json:"item"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Blueprint is the engineering applied to a module
+type Blueprint struct {
+	Name         string  `json:"name"`
+	Level        int     `json:"level"`
+	Quality      float64 `json:"quality"`
+	Experimental string  `json:"experimental,omitempty"`
+}
+
+// EngineeringChange is a module whose engineering changed. From or To is nil
+// when the module was not engineered
+type EngineeringChange struct {
+	Slot string     `json:"slot"`
+	Item string     `json:"item"`
+	From *Blueprint `json:"from"`
+	To   *Blueprint `json:"to"`
+}
+
+// PriorityChange is a module moved to another power priority group
+type PriorityChange struct {
+	Slot string `json:"slot"`
+	Item string `json:"item"`
+	From int    `json:"from"`
+	To   int    `json:"to"`
+}
+
+// PowerChange is a module switched on or off
+type PowerChange struct {
+	Slot string `json:"slot"`
+	Item string `json:"item"`
+	On   bool   `json:"on"`
+}
+
+// Delta is the change of a ship statistic
+type Delta struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Delta float64 `json:"delta"`
+}
+
+// Diff is the difference between two loadouts of the same ship
+type Diff struct {
+	Added       []Module            `json:"added,omitempty"`
+	Removed     []Module            `json:"removed,omitempty"`
+	Moved       []Move              `json:"moved,omitempty"`
+	Engineering []EngineeringChange `json:"engineering,omitempty"`
+	Priority    []PriorityChange    `json:"priority,omitempty"`
+	Power       []PowerChange       `json:"power,omitempty"`
+	Mass        *Delta              `json:"mass,omitempty"`
+	JumpRange   *Delta              `json:"jumpRange,omitempty"`
+	Cargo       *Delta              `json:"cargo,omitempty"`
+	Rebuy       *Delta              `json:"rebuy,omitempty"`
+}
+
+// Empty reports whether the loadouts are the same
+func (d *Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Moved) == 0 &&
+		len(d.Engineering) == 0 && len(d.Priority) == 0 && len(d.Power) == 0 &&
+		d.Mass == nil && d.JumpRange == nil && d.Cargo == nil && d.Rebuy == nil
+}
+
+// Compare returns the difference between two loadouts of the same ship. A
+// module that left one slot and turned up in another is reported as moved
+func Compare(from, to *events.LoadoutEvent) Diff {
+	var d Diff
+
+	before := slots(from)
+	after := slots(to)
+
+	// modules that left their slot, by item
+	gone := make(map[string][]*events.LoadoutModule)
+	arrived := make([]*events.LoadoutModule, 0)
+	for _, slot := range sortedSlots(before) {
+		m := before[slot]
+		n, ok := after[slot]
+		if ok && strings.EqualFold(m.Item, n.Item) {
+			d.compareModule(m, n)
+			continue
+		}
+		item := strings.ToLower(m.Item)
+		gone[item] = append(gone[item], m)
+	}
+	for _, slot := range sortedSlots(after) {
+		n := after[slot]
+		m, ok := before[slot]
+		if ok && strings.EqualFold(m.Item, n.Item) {
+			continue
+		}
+		arrived = append(arrived, n)
+	}
+
+	for _, n := range arrived {
+		item := strings.ToLower(n.Item)
+		if len(gone[item]) == 0 {
+			d.Added = append(d.Added, Module{Slot: n.Slot, Item: n.Item})
+			continue
+		}
+		m := gone[item][0]
+		gone[item] = gone[item][1:]
+		d.Moved = append(d.Moved, Move{Item: n.Item, From: m.Slot, To: n.Slot})
+		d.compareModule(m, n)
+	}
+	for _, ms := range gone {
+		for _, m := range ms {
+			d.Removed = append(d.Removed, Module{Slot: m.Slot, Item: m.Item})
+		}
+	}
+	sort.Slice(d.Removed, func(i, j int) bool {
+		return d.Removed[i].Slot < d.Removed[j].Slot
+	})
+
+	d.Mass = delta(from.UnladenMass, to.UnladenMass)
+	d.JumpRange = delta(from.MaxJumpRange, to.MaxJumpRange)
+	d.Cargo = delta(float64(from.CargoCapacity), float64(to.CargoCapacity))
+	d.Rebuy = delta(float64(from.Rebuy), float64(to.Rebuy))
+	return d
+}
+
+// compareModule adds the changes between the same module in two loadouts
+func (d *Diff) compareModule(m, n *events.LoadoutModule) {
+	bm, bn := blueprint(m.Engineering), blueprint(n.Engineering)
+	if !sameBlueprint(bm, bn) {
+		d.Engineering = append(d.Engineering, EngineeringChange{Slot: n.Slot, Item: n.Item, From: bm, To: bn})
+	}
+	if m.Priority != n.Priority {
+		d.Priority = append(d.Priority, PriorityChange{Slot: n.Slot, Item: n.Item, From: m.Priority, To: n.Priority})
+	}
+	if m.On != n.On {
+		d.Power = append(d.Power, PowerChange{Slot: n.Slot, Item: n.Item, On: n.On})
+	}
+}
+
+func slots(l *events.LoadoutEvent) map[string]*events.LoadoutModule {
+	s := make(map[string]*events.LoadoutModule, len(l.Modules))
+	for _, m := range l.Modules {
+		s[m.Slot] = m
+	}
+	return s
+}
+
+func sortedSlots(s map[string]*events.LoadoutModule) []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func blueprint(e *events.Engineering) *Blueprint {
+	if e == nil {
+		return nil
+	}
+	return &Blueprint{
+		Name:         e.BlueprintName,
+		Level:        e.Level,
+		Quality:      e.Quality,
+		Experimental: e.ExperimentalEffect,
+	}
+}
+
+func sameBlueprint(a, b *Blueprint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// delta returns the change between two values, or nil when they are equal
+func delta(from, to float64) *Delta {
+	if from == to {
+		return nil
+	}
+	return &Delta{From: from, To: to, Delta: to - from}
+}
@@ -0,0 +1,70 @@
+package loadout
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/sht/ed-journal/events"
+)
+
+const before = `{ "timestamp":"2021-01-01T00:00:00Z", "event":"Loadout", "Ship":"python", "ShipID":1,
+	"UnladenMass":350.5, "CargoCapacity":64, "MaxJumpRange":20.5, "Rebuy":2500000, "Modules":[
+	{ "Slot":"LargeHardpoint1", "Item":"Hpt_PulseLaser_Gimbal_Large", "On":true, "Priority":0 },
+	{ "Slot":"MediumHardpoint1", "Item":"Hpt_MultiCannon_Gimbal_Medium", "On":true, "Priority":0 },
+	{ "Slot":"Slot01_Size6", "Item":"Int_CargoRack_Size5_Class1", "On":true, "Priority":1 },
+	{ "Slot":"Slot02_Size5", "Item":"Int_ShieldGenerator_Size5_Class2", "On":true, "Priority":0 },
+	{ "Slot":"FrameShiftDrive", "Item":"Int_Hyperdrive_Size5_Class5", "On":true, "Priority":0,
+		"Engineering":{ "BlueprintName":"FSD_LongRange", "Level":4, "Quality":0.5 } } ] }`
+
+const after = `{ "timestamp":"2021-01-01T01:00:00Z", "event":"Loadout", "Ship":"python", "ShipID":1,
+	"UnladenMass":360.5, "CargoCapacity":64, "MaxJumpRange":22, "Rebuy":2600000, "Modules":[
+	{ "Slot":"LargeHardpoint1", "Item":"Hpt_PulseLaser_Gimbal_Large", "On":false, "Priority":2 },
+	{ "Slot":"MediumHardpoint2", "Item":"Hpt_MultiCannon_Gimbal_Medium", "On":true, "Priority":0 },
+	{ "Slot":"Slot01_Size6", "Item":"Int_CargoRack_Size5_Class1", "On":true, "Priority":1 },
+	{ "Slot":"Slot03_Size4", "Item":"Int_FuelScoop_Size4_Class5", "On":true, "Priority":0 },
+	{ "Slot":"FrameShiftDrive", "Item":"Int_Hyperdrive_Size5_Class5", "On":true, "Priority":0,
+		"Engineering":{ "BlueprintName":"FSD_LongRange", "Level":5, "Quality":1, "ExperimentalEffect":"special_fsd_heavy" } } ] }`
+
+func loadout(t *testing.T, s string) *events.LoadoutEvent {
+	t.Helper()
+
+	var e events.LoadoutEvent
+	err := json.Unmarshal([]byte(s), &e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &e
+}
+
+func TestCompare(t *testing.T) {
+	d := Compare(loadout(t, before), loadout(t, after))
+	want := Diff{
+		Added:   []Module{{Slot: "Slot03_Size4", Item: "Int_FuelScoop_Size4_Class5"}},
+		Removed: []Module{{Slot: "Slot02_Size5", Item: "Int_ShieldGenerator_Size5_Class2"}},
+		Moved:   []Move{{Item: "Hpt_MultiCannon_Gimbal_Medium", From: "MediumHardpoint1", To: "MediumHardpoint2"}},
+		Engineering: []EngineeringChange{{
+			Slot: "FrameShiftDrive",
+			Item: "Int_Hyperdrive_Size5_Class5",
+			From: &Blueprint{Name: "FSD_LongRange", Level: 4, Quality: 0.5},
+			To:   &Blueprint{Name: "FSD_LongRange", Level: 5, Quality: 1, Experimental: "special_fsd_heavy"},
+		}},
+		Priority:  []PriorityChange{{Slot: "LargeHardpoint1", Item: "Hpt_PulseLaser_Gimbal_Large", From: 0, To: 2}},
+		Power:     []PowerChange{{Slot: "LargeHardpoint1", Item: "Hpt_PulseLaser_Gimbal_Large", On: false}},
+		Mass:      &Delta{From: 350.5, To: 360.5, Delta: 10},
+		JumpRange: &Delta{From: 20.5, To: 22, Delta: 1.5},
+		Rebuy:     &Delta{From: 2500000, To: 2600000, Delta: 100000},
+	}
+	if !reflect.DeepEqual(d, want) {
+		got, _ := json.MarshalIndent(d, "", "  ")
+		t.Errorf("diff %s", got)
+	}
+	if d.Empty() {
+		t.Error("diff reported empty")
+	}
+
+	same := Compare(loadout(t, before), loadout(t, before))
+	if !same.Empty() {
+		t.Errorf("diff of a loadout with itself %+v, want empty", same)
+	}
+}
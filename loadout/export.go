@@ -0,0 +1,64 @@
+package loadout
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/sht/ed-journal/events"
+)
+
+const (
+	coriolisURL = "https://coriolis.io/import?data="
+	edsyURL     = "https://edsy.org/#/I="
+)
+
+// ImportJSON returns the build in the import format shared by Coriolis and
+// EDSY, which is the journal Loadout event itself including engineering
+func ImportJSON(e *events.LoadoutEvent) ([]byte, error) {
+	l := *e
+	l.Event.Event = events.Loadout
+	return json.Marshal(l)
+}
+
+// Encode compresses the import JSON and encodes it for use in a URL, the way
+// both planners expect it
+func Encode(b []byte) (string, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(b)
+	if err != nil {
+		return "", err
+	}
+	err = w.Close()
+	if err != nil {
+		return "", err
+	}
+
+	s := base64.URLEncoding.EncodeToString(buf.Bytes())
+	return strings.Replace(s, "=", "%3D", -1), nil
+}
+
+// CoriolisURL returns a link that opens the build in Coriolis
+func CoriolisURL(e *events.LoadoutEvent) (string, error) {
+	return exportURL(coriolisURL, e)
+}
+
+// EDSYURL returns a link that opens the build in EDSY
+func EDSYURL(e *events.LoadoutEvent) (string, error) {
+	return exportURL(edsyURL, e)
+}
+
+func exportURL(prefix string, e *events.LoadoutEvent) (string, error) {
+	b, err := ImportJSON(e)
+	if err != nil {
+		return "", err
+	}
+	s, err := Encode(b)
+	if err != nil {
+		return "", err
+	}
+	return prefix + s, nil
+}
@@ -0,0 +1,76 @@
+package loadout
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// golden compares got with the named file in testdata, or writes it with
+// -update
+func golden(t *testing.T, name, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		err := ioutil.WriteFile(path, []byte(got), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(b) {
+		t.Errorf("%s differs from the golden file:\n%s", name, got)
+	}
+}
+
+func TestExportURL(t *testing.T) {
+	l := loadout(t, after)
+	b, err := ImportJSON(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "import.json", string(b))
+
+	coriolis, err := CoriolisURL(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "coriolis.url", coriolis)
+	edsy, err := EDSYURL(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "edsy.url", edsy)
+
+	// both planners decode the same data back to the import JSON
+	data := strings.TrimPrefix(coriolis, coriolisURL)
+	if data != strings.TrimPrefix(edsy, edsyURL) {
+		t.Errorf("Coriolis and EDSY links carry different data")
+	}
+	gz, err := base64.URLEncoding.DecodeString(strings.Replace(data, "%3D", "=", -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(gz))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, b) {
+		t.Errorf("link decodes to %s, want %s", decoded, b)
+	}
+}
@@ -0,0 +1,133 @@
+package loadout
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/event"
+	"github.com/sht/ed-journal/events"
+)
+
+const (
+	LoadoutChanged = "LoadoutChanged"
+)
+
+// ChangedEvent is triggered on the dispatcher when a ship's loadout differs
+// from the previous one seen for it
+type ChangedEvent struct {
+	event.Event
+	ShipID   int    `json:"ShipID"`
+	Ship     string `json:"Ship"`
+	ShipName string `json:"ShipName,omitempty"`
+	Diff     Diff   `json:"Diff"`
+}
+
+// Tracker compares every loadout with the previous one of the same ship. It
+// is safe for concurrent use
+type Tracker struct {
+	mu       sync.RWMutex
+	d        *dispatcher.Dispatcher
+	loadouts map[int]*events.LoadoutEvent
+	changes  []ChangedEvent
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		loadouts: make(map[int]*events.LoadoutEvent),
+		changes:  make([]ChangedEvent, 0),
+	}
+}
+
+const SnapshotVersion = 1
+
+// Snapshot saves the last loadout of each ship and the changes seen
+func (t *Tracker) Snapshot() (json.RawMessage, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return json.Marshal(t.saved())
+}
+
+func (t *Tracker) Restore(b json.RawMessage) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.loadouts = make(map[int]*events.LoadoutEvent)
+	return json.Unmarshal(b, t.saved())
+}
+
+// saved points at the fields kept in snapshots
+func (t *Tracker) saved() interface{} {
+	return &struct {
+		Loadouts *map[int]*events.LoadoutEvent `json:"loadouts"`
+		Changes  *[]ChangedEvent               `json:"changes"`
+	}{&t.loadouts, &t.changes}
+}
+
+// AddListeners subscribes the tracker to the loadout events. LoadoutChanged
+// events are triggered on the same dispatcher
+func (t *Tracker) AddListeners(d *dispatcher.Dispatcher) {
+	t.mu.Lock()
+	t.d = d
+	t.mu.Unlock()
+
+	// startup
+	{
+		d.OnSync(events.Loadout, t.loadout)
+	}
+}
+
+// Changes returns the changelog of the given ship, oldest first
+func (t *Tracker) Changes(shipID int) []ChangedEvent {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	changes := make([]ChangedEvent, 0)
+	for _, c := range t.changes {
+		if c.ShipID == shipID {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+func (t *Tracker) loadout(b []byte) {
+	e := new(events.LoadoutEvent)
+	err := json.Unmarshal(b, e)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	prev, ok := t.loadouts[e.ShipID]
+	t.loadouts[e.ShipID] = e
+	if !ok {
+		t.mu.Unlock()
+		return
+	}
+	diff := Compare(prev, e)
+	if diff.Empty() {
+		t.mu.Unlock()
+		return
+	}
+	changed := ChangedEvent{
+		Event:    event.Event{Event: LoadoutChanged, Timestamp: e.Timestamp},
+		ShipID:   e.ShipID,
+		Ship:     e.Ship,
+		ShipName: e.ShipName,
+		Diff:     diff,
+	}
+	t.changes = append(t.changes, changed)
+	d := t.d
+	t.mu.Unlock()
+
+	if d == nil {
+		return
+	}
+	out, err := json.Marshal(changed)
+	if err != nil {
+		return
+	}
+	_ = d.Trigger(LoadoutChanged, out)
+}
@@ -1,74 +1,188 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"flag"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 
-	"github.com/sht/ed-journal/dispatcher"
-	"github.com/sht/ed-journal/event"
-	"github.com/sht/ed-journal/events"
+	"github.com/sht/ed-journal/query"
 )
 
-//func startWatcher() {
-//	w, err := event.NewWatcher(func(b []byte) {
-//		fmt.Println(string(b))
-//	}, 10 * time.Millisecond)
-//	if err != nil {
-//		panic(err)
-//	}
-//	w.Start()
-//	err = w.Watch("journal.log")
-//	if err != nil {
-//		panic(err)
-//	}
-//}
+// exit codes
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// command is a subcommand of the CLI
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(args []string) int
+}
+
+func commands() []command {
+	return []command{
+		{"tail", "[flags] [expression]", "Print journal events as they are written", tailCmd},
+		{"import", "[flags]", "Import the journal archive into the event store", importCmd},
+		{"parse", "[flags] file", "Parse a journal file and print the resulting state", parseCmd},
+		{"stats", "[flags]", "Print statistics over the journal archive", statsCmd},
+		{"query", "[flags] expression", "Print the journal events matching an expression", queryCmd},
+		{"export", "[flags]", "Export ship builds as Coriolis or EDSY links", exportCmd},
+		{"validate", "[flags] [file|dir ...]", "Check journal files for malformed or unknown events", validateCmd},
+		{"serve", "[flags]", "Serve the journal over a local HTTP API with live streams", serveCmd},
+	}
+}
 
 func main() {
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	os.Exit(run(os.Args[1:]))
+}
 
-	d := dispatcher.NewDispatcher()
+func run(args []string) int {
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return exitUsage
+	}
 
-	// add event listeners
-	events.AddListeners(d)
+	name := args[0]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		if len(args) > 1 {
+			// show the flags of a command
+			return run([]string{args[1], "-h"})
+		}
+		usage(os.Stdout)
+		return exitOK
+	}
+	for _, c := range commands() {
+		if c.name == name {
+			return c.run(args[1:])
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "ed-journal: unknown command %q\n\n", name)
+	usage(os.Stderr)
+	return exitUsage
+}
+
+func usage(w io.Writer) {
+	fmt.Fprint(w, "ed-journal reads the Elite Dangerous journal\n\nusage: ed-journal <command> [flags]\n\ncommands:\n")
+	for _, c := range commands() {
+		fmt.Fprintf(w, "  %-9s %s\n", c.name, c.summary)
+	}
+	fmt.Fprint(w, "\nRun \"ed-journal help <command>\" for the flags of a command. The journal\n"+
+		"directory defaults to $ED_JOURNAL_DIR, or the game's own directory when found.\n")
+}
+
+// newFlagSet returns the flag set of a command, with help text built from its
+// command entry
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		for _, c := range commands() {
+			if c.name == name {
+				fmt.Fprintf(fs.Output(), "usage: ed-journal %s %s\n\n%s\n\nflags:\n", c.name, c.args, c.summary)
+			}
+		}
+		fs.PrintDefaults()
+	}
+	return fs
+}
 
-	b, err := ioutil.ReadFile("journal.log")
+// parseFlags parses the flags of a command, returning the exit code when the
+// command must not run
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	err := fs.Parse(args)
+	if err == flag.ErrHelp {
+		return exitOK, false
+	}
 	if err != nil {
-		fmt.Println(err)
-		return
+		return exitUsage, false
 	}
+	return 0, true
+}
 
-	bs := bytes.Split(b, []byte("\n"))
-	for _, b := range bs {
-		if len(b) > 0 {
-			// TODO: use goroutine to handle these
-			parseEvent(d, b)
+// defaultDir returns the journal directory used when none is given
+func defaultDir() string {
+	if dir := os.Getenv("ED_JOURNAL_DIR"); dir != "" {
+		return dir
+	}
+	if home := os.Getenv("USERPROFILE"); home != "" {
+		dir := filepath.Join(home, "Saved Games", "Frontier Developments", "Elite Dangerous")
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
 		}
 	}
-	fmt.Println("parsed")
+	return "."
+}
 
-	<-quit
+// dataDir returns the directory keeping the event store, cursor and snapshot
+func dataDir() string {
+	if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, "ed-journal")
+	}
+	return ".ed-journal"
 }
 
-func parseEvent(d *dispatcher.Dispatcher, b []byte) {
-	var e event.Event
-	err := json.Unmarshal(b, &e)
-	if err != nil {
-		fmt.Println(string(b))
-		fmt.Println(err)
-		panic(err)
-		return
+func dirFlag(fs *flag.FlagSet) *string {
+	return fs.String("dir", defaultDir(), "journal directory")
+}
+
+func dataFlag(fs *flag.FlagSet) *string {
+	return fs.String("data", dataDir(), "directory keeping the event store and snapshots")
+}
+
+func formatFlag(fs *flag.FlagSet, def string) *string {
+	return fs.String("format", def, "output format: json or table")
+}
+
+// newWriter returns the writer for an output format
+func newWriter(format string, paths []string) (query.Writer, error) {
+	switch format {
+	case "json":
+		return query.NewJSONWriter(os.Stdout, paths), nil
+	case "table":
+		return query.NewTableWriter(os.Stdout, paths), nil
 	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
 
-	// trigger the event
-	err = d.Trigger(e.Event, b)
-	if err != nil {
-		//fmt.Println(err)
-		return
+// splitList splits a comma separated flag value
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
 	}
+	return list
+}
+
+// fail prints an error and returns the error exit code
+func fail(err error) int {
+	fmt.Fprintf(os.Stderr, "ed-journal: %v\n", err)
+	return exitError
+}
+
+// usageError prints an error with the command usage and returns the usage
+// exit code
+func usageError(fs *flag.FlagSet, err error) int {
+	fmt.Fprintf(os.Stderr, "ed-journal: %v\n\n", err)
+	fs.Usage()
+	return exitUsage
+}
+
+// interrupted returns a channel receiving the signals that stop long running
+// commands
+func interrupted() chan os.Signal {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	return quit
 }
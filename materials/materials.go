@@ -0,0 +1,148 @@
+package materials
+
+import (
+	"strings"
+)
+
+const (
+	Raw          = "Raw"
+	Manufactured = "Manufactured"
+	Encoded      = "Encoded"
+)
+
+// Material describes a known engineering material
+type Material struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Grade    int    `json:"grade"`
+}
+
+// Cap returns the maximum number of units of the material that can be stored
+func (m Material) Cap() int {
+	return GradeCap(m.Grade)
+}
+
+// GradeCap returns the storage cap for materials of the given grade
+func GradeCap(grade int) int {
+	switch grade {
+	case 1:
+		return 300
+	case 2:
+		return 250
+	case 3:
+		return 200
+	case 4:
+		return 150
+	case 5:
+		return 100
+	}
+	return 0
+}
+
+// Lookup returns the known material with the given journal name
+func Lookup(name string) (Material, bool) {
+	m, ok := known[strings.ToLower(name)]
+	return m, ok
+}
+
+// All returns every known material
+func All() []Material {
+	all := make([]Material, 0, len(known))
+	for _, m := range known {
+		all = append(all, m)
+	}
+	return all
+}
+
+// normalizeCategory maps the category variants found in the journal, e.g.
+// "$MICRORESOURCE_CATEGORY_Encoded;", onto Raw, Manufactured or Encoded
+func normalizeCategory(c string) string {
+	c = strings.TrimSuffix(strings.TrimPrefix(c, "$MICRORESOURCE_CATEGORY_"), ";")
+	switch strings.ToLower(c) {
+	case "raw":
+		return Raw
+	case "manufactured":
+		return Manufactured
+	case "encoded":
+		return Encoded
+	}
+	return c
+}
+
+var known = make(map[string]Material)
+
+func init() {
+	grades := map[string][][]string{
+		Raw: {
+			{"carbon", "phosphorus", "sulphur", "iron", "nickel", "rhenium", "lead"},
+			{"vanadium", "chromium", "manganese", "zinc", "germanium", "arsenic", "zirconium"},
+			{"niobium", "molybdenum", "cadmium", "tin", "tungsten", "mercury", "boron"},
+			{"yttrium", "technetium", "ruthenium", "selenium", "tellurium", "polonium", "antimony"},
+		},
+		Manufactured: {
+			{
+				"chemicalstorageunits", "heatconductionwiring", "gridresistors", "basicconductors",
+				"mechanicalscrap", "salvagedalloys", "wornshieldemitters", "compactcomposites",
+				"crystalshards", "guardian_powercell", "guardian_sentinel_wreckagecomponents",
+			},
+			{
+				"chemicalprocessors", "heatdispersionplate", "hybridcapacitors", "conductivecomponents",
+				"mechanicalequipment", "galvanisingalloys", "shieldemitters", "filamentcomposites",
+				"uncutfocuscrystals", "guardian_powerconduit", "unknowncarapace",
+			},
+			{
+				"chemicaldistillery", "heatexchangers", "electrochemicalarrays", "conductiveceramics",
+				"mechanicalcomponents", "phasealloys", "shieldingsensors", "highdensitycomposites",
+				"focuscrystals", "guardian_techcomponent", "guardian_sentinel_weaponparts",
+				"unknownenergycell", "tg_biomechanicalconduits", "tg_wreckagecomponents",
+			},
+			{
+				"chemicalmanipulators", "heatvanes", "polymercapacitors", "conductivepolymers",
+				"configurablecomponents", "protolightalloys", "compoundshielding", "fedproprietarycomposites",
+				"refinedfocuscrystals", "unknowntechnologycomponents", "tg_weaponparts",
+			},
+			{
+				"pharmaceuticalisolators", "protoheatradiators", "militarysupercapacitors", "biotechconductors",
+				"improvisedcomponents", "protoradiolicalloys", "imperialshielding", "fedcorecomposites",
+				"exquisitefocuscrystals", "unknowncorechip", "tg_propulsionelement",
+			},
+		},
+		Encoded: {
+			{
+				"scrambledemissiondata", "disruptedwakeechoes", "shieldcyclerecordings", "encryptedfiles",
+				"bulkscandata", "legacyfirmware",
+			},
+			{
+				"archivedemissiondata", "fsdtelemetry", "shieldsoakanalysis", "encryptioncodes",
+				"scanarchives", "consumerfirmware", "tg_structuraldata",
+			},
+			{
+				"emissiondata", "wakesolutions", "shielddensityreports", "symmetrickeys",
+				"scandatabanks", "industrialfirmware", "ancientbiologicaldata", "ancientculturaldata",
+				"ancienthistoricaldata", "tg_shipflightdata", "tg_shipsystemsdata", "unknownshipsignature",
+			},
+			{
+				"decodedemissiondata", "hyperspacetrajectories", "shieldpatternanalysis", "encryptionarchives",
+				"encodedscandata", "securityfirmware", "ancientlanguagedata", "unknownwakedata",
+				"tg_interdictiondata",
+			},
+			{
+				"compactemissionsdata", "dataminedwake", "shieldfrequencydata", "adaptiveencryptors",
+				"classifiedscandata", "embeddedfirmware", "ancienttechnologicaldata",
+				"guardian_moduleblueprint", "guardian_weaponblueprint", "guardian_vesselblueprint",
+			},
+		},
+	}
+
+	for category, byGrade := range grades {
+		for i, names := range byGrade {
+			for _, name := range names {
+				known[name] = Material{
+					Name:     name,
+					Category: category,
+					Grade:    i + 1,
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package materials
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/events"
+)
+
+// Item is a material in the inventory together with its stored count
+type Item struct {
+	Material
+	NameLocalised string `json:"nameLocalised,omitempty"`
+	Count         int    `json:"count"`
+	Cap           int    `json:"cap"`
+}
+
+// Tracker keeps the materials inventory up to date, starting from the
+// baseline of the Materials event written at login. It is safe for
+// concurrent use
+type Tracker struct {
+	mu        sync.RWMutex
+	counts    map[string]int
+	materials map[string]Material
+	localised map[string]string
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		counts:    make(map[string]int),
+		materials: make(map[string]Material),
+		localised: make(map[string]string),
+	}
+}
+
+const SnapshotVersion = 1
+
+// Snapshot saves the material counts along with the names seen for them
+func (t *Tracker) Snapshot() (json.RawMessage, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return json.Marshal(t.saved())
+}
+
+func (t *Tracker) Restore(b json.RawMessage) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.counts = make(map[string]int)
+	t.materials = make(map[string]Material)
+	t.localised = make(map[string]string)
+	return json.Unmarshal(b, t.saved())
+}
+
+// saved points at the fields kept in snapshots
+func (t *Tracker) saved() interface{} {
+	return &struct {
+		Counts    *map[string]int      `json:"counts"`
+		Materials *map[string]Material `json:"materials"`
+		Localised *map[string]string   `json:"localised"`
+	}{&t.counts, &t.materials, &t.localised}
+}
+
+// AddListeners subscribes the tracker to the events that change the inventory
+func (t *Tracker) AddListeners(d *dispatcher.Dispatcher) {
+	d.OnSync(events.Materials, t.materialsEvent)
+	d.OnSync(events.MaterialCollected, t.materialCollected)
+	d.OnSync(events.MaterialDiscarded, t.materialDiscarded)
+	d.OnSync(events.MaterialTrade, t.materialTrade)
+	d.OnSync(events.EngineerCraft, t.engineerCraft)
+	d.OnSync(events.Synthesis, t.synthesis)
+	d.OnSync(events.TechnologyBroker, t.technologyBroker)
+	d.OnSync(events.MissionCompleted, t.missionCompleted)
+}
+
+// Count returns the stored count of the named material
+func (t *Tracker) Count(name string) int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.counts[strings.ToLower(name)]
+}
+
+// Inventory returns every material with a non-zero count, ordered by
+// category, grade and name
+func (t *Tracker) Inventory() []Item {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	items := make([]Item, 0, len(t.counts))
+	for name, count := range t.counts {
+		if count > 0 {
+			items = append(items, t.item(name))
+		}
+	}
+	sortItems(items)
+	return items
+}
+
+// NearCap returns the materials whose count is at or above the given fraction
+// of their storage cap, e.g. 0.9 for materials that are 90% full
+func (t *Tracker) NearCap(fraction float64) []Item {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	items := make([]Item, 0)
+	for name, count := range t.counts {
+		it := t.item(name)
+		if it.Cap > 0 && count > 0 && float64(count) >= fraction*float64(it.Cap) {
+			items = append(items, it)
+		}
+	}
+	sortItems(items)
+	return items
+}
+
+// Missing returns the known materials that are not in the inventory
+func (t *Tracker) Missing() []Item {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	items := make([]Item, 0)
+	for _, m := range known {
+		if t.counts[m.Name] <= 0 {
+			items = append(items, t.item(m.Name))
+		}
+	}
+	sortItems(items)
+	return items
+}
+
+// item returns the inventory item for name. Must be called with the lock held
+func (t *Tracker) item(name string) Item {
+	m, ok := known[name]
+	if !ok {
+		m = t.materials[name]
+	}
+	return Item{
+		Material:      m,
+		NameLocalised: t.localised[name],
+		Count:         t.counts[name],
+		Cap:           m.Cap(),
+	}
+}
+
+func sortItems(items []Item) {
+	order := map[string]int{Raw: 0, Manufactured: 1, Encoded: 2}
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.Category != b.Category {
+			return order[a.Category] < order[b.Category]
+		}
+		if a.Grade != b.Grade {
+			return a.Grade < b.Grade
+		}
+		return a.Name < b.Name
+	})
+}
+
+// add adjusts the count of a material. Must be called with the lock held
+func (t *Tracker) add(name, localised, category string, n int) {
+	name = strings.ToLower(name)
+	if _, ok := known[name]; !ok {
+		if category == "" {
+			// ingredients without a category may be commodities, which are
+			// not tracked unless already seen as a material
+			if _, seen := t.materials[name]; !seen {
+				return
+			}
+		} else {
+			t.materials[name] = Material{Name: name, Category: normalizeCategory(category)}
+		}
+	}
+	if localised != "" {
+		t.localised[name] = localised
+	}
+
+	count := t.counts[name] + n
+	if count < 0 {
+		count = 0
+	}
+	if m, ok := known[name]; ok && count > m.Cap() {
+		count = m.Cap()
+	}
+	t.counts[name] = count
+}
+
+// update decodes b into e and applies fn while holding the lock
+func (t *Tracker) update(b []byte, e interface{}, fn func()) {
+	err := json.Unmarshal(b, e)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fn()
+}
+
+func (t *Tracker) materialsEvent(b []byte) {
+	var e events.MaterialsEvent
+	t.update(b, &e, func() {
+		t.counts = make(map[string]int)
+		for category, list := range map[string][]*events.Material{
+			Raw:          e.Raw,
+			Manufactured: e.Manufactured,
+			Encoded:      e.Encoded,
+		} {
+			for _, m := range list {
+				t.add(m.Name, m.NameLocalised, category, m.Count)
+			}
+		}
+	})
+}
+
+func (t *Tracker) materialCollected(b []byte) {
+	var e events.MaterialCollectedEvent
+	t.update(b, &e, func() {
+		t.add(e.Name, e.NameLocalised, e.Category, e.Count)
+	})
+}
+
+func (t *Tracker) materialDiscarded(b []byte) {
+	var e events.MaterialDiscardedEvent
+	t.update(b, &e, func() {
+		t.add(e.Name, e.NameLocalised, e.Category, -e.Count)
+	})
+}
+
+func (t *Tracker) materialTrade(b []byte) {
+	var e events.MaterialTradeEvent
+	t.update(b, &e, func() {
+		if e.Paid != nil {
+			t.add(e.Paid.Material, e.Paid.MaterialLocalised, e.Paid.Category, -e.Paid.Quantity)
+		}
+		if e.Received != nil {
+			t.add(e.Received.Material, e.Received.MaterialLocalised, e.Received.Category, e.Received.Quantity)
+		}
+	})
+}
+
+func (t *Tracker) engineerCraft(b []byte) {
+	var e events.EngineerCraftEvent
+	t.update(b, &e, func() {
+		for _, m := range e.Ingredients {
+			t.add(m.Name, m.NameLocalised, m.Category, -m.Count)
+		}
+	})
+}
+
+func (t *Tracker) synthesis(b []byte) {
+	var e events.SynthesisEvent
+	t.update(b, &e, func() {
+		for _, m := range e.Materials {
+			t.add(m.Name, m.NameLocalised, m.Category, -m.Count)
+		}
+	})
+}
+
+func (t *Tracker) technologyBroker(b []byte) {
+	var e events.TechnologyBrokerEvent
+	t.update(b, &e, func() {
+		for _, m := range e.Materials {
+			t.add(m.Name, m.NameLocalised, m.Category, -m.Count)
+		}
+	})
+}
+
+func (t *Tracker) missionCompleted(b []byte) {
+	var e events.MissionCompletedEvent
+	t.update(b, &e, func() {
+		for _, m := range e.MaterialsReward {
+			t.add(m.Name, m.NameLocalised, m.Category, m.Count)
+		}
+	})
+}
@@ -0,0 +1,364 @@
+package missions
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/event"
+	"github.com/sht/ed-journal/events"
+)
+
+const (
+	MissionExpiring = "MissionExpiring"
+	MissionExpired  = "MissionExpired"
+)
+
+// DefaultThresholds are the remaining times at which expiry warnings are sent
+var DefaultThresholds = []time.Duration{24 * time.Hour, 4 * time.Hour, time.Hour, 15 * time.Minute}
+
+// Mission is an open mission in the ledger
+type Mission struct {
+	MissionID          int       `json:"missionId"`
+	Name               string    `json:"name"`
+	LocalisedName      string    `json:"localisedName,omitempty"`
+	Faction            string    `json:"faction,omitempty"`
+	DestinationSystem  string    `json:"destinationSystem,omitempty"`
+	DestinationStation string    `json:"destinationStation,omitempty"`
+	AcceptedAt         time.Time `json:"acceptedAt,omitempty"`
+	Expiry             time.Time `json:"expiry,omitempty"`
+	Reward             int       `json:"reward"`
+	Wing               bool      `json:"wing"`
+	Passenger          bool      `json:"passenger"`
+	PassengerCount     int       `json:"passengerCount,omitempty"`
+	PassengerType      string    `json:"passengerType,omitempty"`
+	PassengerVIP       bool      `json:"passengerVip,omitempty"`
+	PassengerWanted    bool      `json:"passengerWanted,omitempty"`
+
+	// warned is the number of thresholds already warned about
+	warned int
+}
+
+// ExpiringEvent is triggered on the dispatcher when an open mission passes
+// one of the ledger thresholds (MissionExpiring) or its expiry (MissionExpired)
+type ExpiringEvent struct {
+	event.Event
+	MissionID          int       `json:"MissionID"`
+	Name               string    `json:"Name"`
+	LocalisedName      string    `json:"LocalisedName,omitempty"`
+	DestinationSystem  string    `json:"DestinationSystem,omitempty"`
+	DestinationStation string    `json:"DestinationStation,omitempty"`
+	Expiry             time.Time `json:"Expiry"`
+	Remaining          int       `json:"Remaining"`
+}
+
+// Ledger keeps track of the commander's open missions. It is safe for
+// concurrent use
+type Ledger struct {
+	mu         sync.RWMutex
+	d          *dispatcher.Dispatcher
+	thresholds []time.Duration
+	missions   map[int]*Mission
+}
+
+// NewLedger returns a ledger warning at the given remaining times before
+// expiry, or at DefaultThresholds when none are given
+func NewLedger(thresholds ...time.Duration) *Ledger {
+	if len(thresholds) == 0 {
+		thresholds = DefaultThresholds
+	}
+	t := make([]time.Duration, len(thresholds))
+	copy(t, thresholds)
+	sort.Slice(t, func(i, j int) bool { return t[i] > t[j] })
+
+	return &Ledger{
+		thresholds: t,
+		missions:   make(map[int]*Mission),
+	}
+}
+
+const SnapshotVersion = 1
+
+type snapshot struct {
+	Missions map[int]*Mission `json:"missions"`
+	// Warned is the number of thresholds warned about for each mission
+	Warned map[int]int `json:"warned"`
+}
+
+// Snapshot saves the open missions and the warnings already sent for them
+func (l *Ledger) Snapshot() (json.RawMessage, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	s := snapshot{Missions: l.missions, Warned: make(map[int]int)}
+	for id, m := range l.missions {
+		s.Warned[id] = m.warned
+	}
+	return json.Marshal(s)
+}
+
+func (l *Ledger) Restore(b json.RawMessage) error {
+	s := snapshot{Missions: make(map[int]*Mission)}
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+	for id, m := range s.Missions {
+		m.warned = s.Warned[id]
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.missions = s.Missions
+	return nil
+}
+
+// AddListeners subscribes the ledger to mission events. Expiry warnings are
+// triggered on the same dispatcher
+func (l *Ledger) AddListeners(d *dispatcher.Dispatcher) {
+	l.mu.Lock()
+	l.d = d
+	l.mu.Unlock()
+
+	d.OnSync(events.Missions, l.missionsEvent)
+	d.OnSync(events.Passengers, l.passengers)
+	d.OnSync(events.MissionAccepted, l.missionAccepted)
+	d.OnSync(events.MissionCompleted, l.missionCompleted)
+	d.OnSync(events.MissionFailed, l.missionFailed)
+	d.OnSync(events.MissionAbandoned, l.missionAbandoned)
+	d.OnSync(events.MissionRedirected, l.missionRedirected)
+}
+
+// Open returns the open missions ordered by expiry
+func (l *Ledger) Open() []Mission {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	open := make([]Mission, 0, len(l.missions))
+	for _, m := range l.missions {
+		open = append(open, *m)
+	}
+	sort.Slice(open, func(i, j int) bool {
+		if open[i].Expiry.Equal(open[j].Expiry) {
+			return open[i].MissionID < open[j].MissionID
+		}
+		if open[i].Expiry.IsZero() || open[j].Expiry.IsZero() {
+			return !open[i].Expiry.IsZero()
+		}
+		return open[i].Expiry.Before(open[j].Expiry)
+	})
+	return open
+}
+
+// Expiring returns the open missions that expire within d of now
+func (l *Ledger) Expiring(now time.Time, d time.Duration) []Mission {
+	expiring := make([]Mission, 0)
+	for _, m := range l.Open() {
+		if !m.Expiry.IsZero() && m.Expiry.Sub(now) <= d {
+			expiring = append(expiring, m)
+		}
+	}
+	return expiring
+}
+
+// Passengers returns the total number of passengers carried for open missions
+func (l *Ledger) Passengers() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	n := 0
+	for _, m := range l.missions {
+		n += m.PassengerCount
+	}
+	return n
+}
+
+// Check triggers MissionExpiring and MissionExpired events for the open
+// missions that crossed a threshold at the given time. It is called with the
+// timestamp of every mission event. Calling it with the wall clock warns about
+// missions expiring while nothing is written to the journal, it must then be
+// called from the goroutine dispatching the journal, such as the watcher's
+// OnTick, so that the warnings are ordered with the journal events
+func (l *Ledger) Check(now time.Time) {
+	l.mu.Lock()
+	d := l.d
+	warnings := make([]ExpiringEvent, 0)
+	for id, m := range l.missions {
+		if m.Expiry.IsZero() {
+			continue
+		}
+		remaining := m.Expiry.Sub(now)
+		if remaining <= 0 {
+			warnings = append(warnings, expiringEvent(MissionExpired, now, m))
+			delete(l.missions, id)
+			continue
+		}
+
+		crossed := m.warned
+		for crossed < len(l.thresholds) && remaining <= l.thresholds[crossed] {
+			crossed++
+		}
+		if crossed > m.warned {
+			m.warned = crossed
+			warnings = append(warnings, expiringEvent(MissionExpiring, now, m))
+		}
+	}
+	l.mu.Unlock()
+
+	if d == nil {
+		return
+	}
+	sort.Slice(warnings, func(i, j int) bool { return warnings[i].Expiry.Before(warnings[j].Expiry) })
+	for _, w := range warnings {
+		b, err := json.Marshal(w)
+		if err != nil {
+			continue
+		}
+		_ = d.Trigger(w.Event.Event, b)
+	}
+}
+
+func expiringEvent(name string, now time.Time, m *Mission) ExpiringEvent {
+	remaining := m.Expiry.Sub(now)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return ExpiringEvent{
+		Event: event.Event{
+			Event:     name,
+			Timestamp: now.UTC(),
+		},
+		MissionID:          m.MissionID,
+		Name:               m.Name,
+		LocalisedName:      m.LocalisedName,
+		DestinationSystem:  m.DestinationSystem,
+		DestinationStation: m.DestinationStation,
+		Expiry:             m.Expiry,
+		Remaining:          int(remaining.Seconds()),
+	}
+}
+
+// update decodes b into e, applies fn while holding the lock and then checks
+// for expiring missions at the event time
+func (l *Ledger) update(b []byte, e interface{}, fn func() time.Time) {
+	err := json.Unmarshal(b, e)
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	now := fn()
+	l.mu.Unlock()
+
+	l.Check(now)
+}
+
+func (l *Ledger) missionsEvent(b []byte) {
+	var e events.MissionsEvent
+	l.update(b, &e, func() time.Time {
+		active := make(map[int]*Mission, len(e.Active))
+		for _, am := range e.Active {
+			m, ok := l.missions[am.MissionID]
+			if !ok {
+				m = &Mission{
+					MissionID: am.MissionID,
+					Name:      am.Name,
+				}
+			}
+			m.Passenger = am.PassengerMission
+			if am.Expires > 0 {
+				m.Expiry = e.Timestamp.Add(time.Duration(am.Expires) * time.Second)
+			}
+			active[m.MissionID] = m
+		}
+		l.missions = active
+		return e.Timestamp
+	})
+}
+
+func (l *Ledger) passengers(b []byte) {
+	var e events.PassengersEvent
+	l.update(b, &e, func() time.Time {
+		for _, p := range e.Manifest {
+			m, ok := l.missions[p.MissionID]
+			if !ok {
+				continue
+			}
+			m.Passenger = true
+			m.PassengerCount = p.Count
+			m.PassengerType = p.Type
+			m.PassengerVIP = p.VIP
+			m.PassengerWanted = p.Wanted
+		}
+		return e.Timestamp
+	})
+}
+
+func (l *Ledger) missionAccepted(b []byte) {
+	var e events.MissionAcceptedEvent
+	l.update(b, &e, func() time.Time {
+		m := &Mission{
+			MissionID:          e.MissionID,
+			Name:               e.Name,
+			LocalisedName:      e.LocalisedName,
+			Faction:            e.Faction,
+			DestinationSystem:  e.DestinationSystem,
+			DestinationStation: e.DestinationStation,
+			AcceptedAt:         e.Timestamp,
+			Reward:             e.Reward,
+			Wing:               e.Wing,
+			Passenger:          e.PassengerCount > 0,
+			PassengerCount:     e.PassengerCount,
+			PassengerType:      e.PassengerType,
+			PassengerVIP:       e.PassengerVIPs,
+			PassengerWanted:    e.PassengerWanted,
+		}
+		if m.DestinationStation == "" {
+			m.DestinationStation = e.DestinationSettlement
+		}
+		if e.Expiry != nil {
+			m.Expiry = *e.Expiry
+		}
+		l.missions[m.MissionID] = m
+		return e.Timestamp
+	})
+}
+
+func (l *Ledger) missionCompleted(b []byte) {
+	var e events.MissionCompletedEvent
+	l.update(b, &e, func() time.Time {
+		delete(l.missions, e.MissionID)
+		return e.Timestamp
+	})
+}
+
+func (l *Ledger) missionFailed(b []byte) {
+	var e events.MissionFailedEvent
+	l.update(b, &e, func() time.Time {
+		delete(l.missions, e.MissionID)
+		return e.Timestamp
+	})
+}
+
+func (l *Ledger) missionAbandoned(b []byte) {
+	var e events.MissionAbandonedEvent
+	l.update(b, &e, func() time.Time {
+		delete(l.missions, e.MissionID)
+		return e.Timestamp
+	})
+}
+
+func (l *Ledger) missionRedirected(b []byte) {
+	var e events.MissionRedirectedEvent
+	l.update(b, &e, func() time.Time {
+		m, ok := l.missions[e.MissionID]
+		if ok {
+			m.DestinationSystem = e.NewDestinationSystem
+			m.DestinationStation = e.NewDestinationStation
+		}
+		return e.Timestamp
+	})
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/event"
+	"github.com/sht/ed-journal/importer"
+	"github.com/sht/ed-journal/query"
+	"github.com/sht/ed-journal/session"
+	"github.com/sht/ed-journal/state"
+	"github.com/sht/ed-journal/travel"
+)
+
+// parseResult is what parse prints
+type parseResult struct {
+	State    state.State       `json:"state"`
+	Sessions []session.Session `json:"sessions"`
+	Travel   travel.Stats      `json:"travel"`
+}
+
+func parseCmd(args []string) int {
+	fs := newFlagSet("parse")
+	format := formatFlag(fs, "table")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		return usageError(fs, fmt.Errorf("expected a single journal file"))
+	}
+	if *format != "json" && *format != "table" {
+		return usageError(fs, fmt.Errorf("unknown format %q", *format))
+	}
+	path := fs.Arg(0)
+
+	d := dispatcher.NewDispatcher()
+	t := newTrackers(filepath.Dir(path))
+	t.addListeners(d)
+
+	r := importer.NewImporter(d).Import([]event.JournalFile{{Path: path}})[0]
+	if r.Err != nil {
+		return fail(r.Err)
+	}
+	for _, e := range r.Errors {
+		fmt.Fprintf(os.Stderr, "%s:%d: %s\n", path, e.Line, e.Err)
+	}
+	t.sessions.Close()
+
+	res := parseResult{
+		State:    t.state.State(),
+		Sessions: t.sessions.Sessions(),
+		Travel:   t.travel.Lifetime(),
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err := enc.Encode(res)
+		if err != nil {
+			return fail(err)
+		}
+		return exitOK
+	}
+
+	s := res.State
+	err := writeRows([][2]interface{}{
+		{"commander", s.Commander},
+		{"credits", s.Credits},
+		{"system", s.Location.StarSystem},
+		{"body", s.Location.Body},
+		{"station", s.Location.Station},
+		{"docked", s.Location.Docked},
+		{"ship", s.Ship.Type},
+		{"ship name", s.Ship.Name},
+		{"jump range", s.Ship.MaxJumpRange},
+		{"sessions", len(res.Sessions)},
+		{"jumps", res.Travel.Jumps},
+		{"distance", res.Travel.Distance},
+		{"events", r.Events},
+		{"bad lines", len(r.Errors)},
+	})
+	if err != nil {
+		return fail(err)
+	}
+	return exitOK
+}
+
+// writeRows prints name and value pairs as a two column table
+func writeRows(rows [][2]interface{}) error {
+	w := query.NewTableWriter(os.Stdout, []string{"name", "value"})
+	for _, row := range rows {
+		// round trip through JSON so values format like decoded events
+		b, err := json.Marshal(row[1])
+		if err != nil {
+			return err
+		}
+		var v interface{}
+		err = json.Unmarshal(b, &v)
+		if err != nil {
+			return err
+		}
+		err = w.Write(map[string]interface{}{"name": row[0], "value": v})
+		if err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
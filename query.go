@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sht/ed-journal/event"
+	"github.com/sht/ed-journal/query"
+)
+
+// errLimit stops a query once enough events were written
+var errLimit = errors.New("limit reached")
+
+func queryCmd(args []string) int {
+	fs := newFlagSet("query")
+	dir := dirFlag(fs)
+	format := formatFlag(fs, "json")
+	fields := fs.String("fields", "", "comma separated fields to output, e.g. timestamp,StarSystem,JumpDist")
+	limit := fs.Int("limit", 0, "stop after this many events, 0 for no limit")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	expr, err := parseExpr(fs.Args())
+	if err != nil {
+		return usageError(fs, err)
+	}
+	w, err := newWriter(*format, splitList(*fields))
+	if err != nil {
+		return usageError(fs, err)
+	}
+
+	files, err := event.FindJournals(*dir)
+	if err != nil {
+		return fail(err)
+	}
+
+	code := exitOK
+	matched := 0
+	for _, f := range files {
+		err = queryFile(f.Path, expr, func(fields map[string]interface{}) error {
+			err := w.Write(fields)
+			if err != nil {
+				return err
+			}
+			matched++
+			if *limit > 0 && matched >= *limit {
+				return errLimit
+			}
+			return nil
+		})
+		if err == errLimit {
+			break
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", f.Path, err)
+			code = exitError
+		}
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return fail(err)
+	}
+	return code
+}
+
+// parseExpr parses the expression given as arguments. No expression matches
+// every event
+func parseExpr(args []string) (*query.Expr, error) {
+	src := strings.Join(args, " ")
+	if strings.TrimSpace(src) == "" {
+		src = "true"
+	}
+	return query.Parse(src)
+}
+
+// queryFile calls fn with every event of a journal file matching expr.
+// Lines that are not valid JSON are skipped
+func queryFile(path string, expr *query.Expr, fn func(fields map[string]interface{}) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		b := bytes.TrimSpace(sc.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		fields, err := query.Decode(b)
+		if err != nil {
+			continue
+		}
+		if !expr.Eval(fields) {
+			continue
+		}
+		err = fn(fields)
+		if err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
@@ -0,0 +1,244 @@
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Expr is a parsed filter expression. It is safe for concurrent use
+type Expr struct {
+	src  string
+	root node
+}
+
+// String returns the expression source
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Eval reports whether the decoded event matches the expression
+func (e *Expr) Eval(fields map[string]interface{}) bool {
+	return truthy(e.root.eval(fields))
+}
+
+// Match reports whether the raw event matches the expression
+func (e *Expr) Match(b []byte) (bool, error) {
+	fields, err := Decode(b)
+	if err != nil {
+		return false, err
+	}
+	return e.Eval(fields), nil
+}
+
+// Decode decodes a raw event for Eval, Lookup and Project. Numbers are
+// decoded as float64
+func Decode(b []byte) (map[string]interface{}, error) {
+	var fields map[string]interface{}
+	err := json.Unmarshal(b, &fields)
+	return fields, err
+}
+
+// Lookup returns the value at a dotted path, such as Materials.0.Name. It
+// reports false when there is no such field
+func Lookup(fields map[string]interface{}, path string) (interface{}, bool) {
+	var v interface{} = fields
+	for _, key := range strings.Split(path, ".") {
+		switch c := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			v, ok = c[key]
+			if !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, false
+			}
+			v = c[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+type node interface {
+	eval(fields map[string]interface{}) interface{}
+}
+
+type literalNode struct {
+	v interface{}
+}
+
+func (n *literalNode) eval(map[string]interface{}) interface{} {
+	return n.v
+}
+
+type fieldNode struct {
+	path string
+}
+
+func (n *fieldNode) eval(fields map[string]interface{}) interface{} {
+	v, _ := Lookup(fields, n.path)
+	return v
+}
+
+type notNode struct {
+	n node
+}
+
+func (n *notNode) eval(fields map[string]interface{}) interface{} {
+	return !truthy(n.n.eval(fields))
+}
+
+type andNode struct {
+	left, right node
+}
+
+func (n *andNode) eval(fields map[string]interface{}) interface{} {
+	return truthy(n.left.eval(fields)) && truthy(n.right.eval(fields))
+}
+
+type orNode struct {
+	left, right node
+}
+
+func (n *orNode) eval(fields map[string]interface{}) interface{} {
+	return truthy(n.left.eval(fields)) || truthy(n.right.eval(fields))
+}
+
+type matchNode struct {
+	negate bool
+	left   node
+	rex    *regexp.Regexp
+}
+
+func (n *matchNode) eval(fields map[string]interface{}) interface{} {
+	s, ok := n.left.eval(fields).(string)
+	if !ok {
+		return false
+	}
+	return n.rex.MatchString(s) != n.negate
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n *compareNode) eval(fields map[string]interface{}) interface{} {
+	a, b := n.left.eval(fields), n.right.eval(fields)
+	switch n.op {
+	case "==":
+		return equal(a, b)
+	case "!=":
+		return !equal(a, b)
+	}
+
+	c, ok := compare(a, b)
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+func equal(a, b interface{}) bool {
+	if c, ok := compare(a, b); ok {
+		return c == 0
+	}
+	switch a := a.(type) {
+	case nil:
+		return b == nil
+	case bool:
+		bb, ok := b.(bool)
+		return ok && a == bb
+	}
+	// objects and arrays compare by their encoding
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(ja, jb)
+}
+
+// compare orders two numbers or two strings. It reports false for any other
+// pair of values
+func compare(a, b interface{}) (int, bool) {
+	switch a := a.(type) {
+	case float64:
+		b, ok := b.(float64)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case a < b:
+			return -1, true
+		case a > b:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		b, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(a, b), true
+	}
+	return 0, false
+}
+
+func truthy(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	}
+	return true
+}
+
+// Format returns a value for display in a table
+func Format(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
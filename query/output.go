@@ -0,0 +1,101 @@
+package query
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// defaultColumns are the table columns when no fields are selected
+var defaultColumns = []string{"timestamp", "event"}
+
+// Project returns the selected fields of an event, keyed by their path.
+// Missing fields are left out. With no paths the event is returned as is
+func Project(fields map[string]interface{}, paths []string) map[string]interface{} {
+	if len(paths) == 0 {
+		return fields
+	}
+
+	out := make(map[string]interface{}, len(paths))
+	for _, path := range paths {
+		if v, ok := Lookup(fields, path); ok {
+			out[path] = v
+		}
+	}
+	return out
+}
+
+// Writer writes matching events
+type Writer interface {
+	Write(fields map[string]interface{}) error
+	// Flush writes anything buffered
+	Flush() error
+}
+
+// JSONWriter writes events as JSON lines, projected on the selected fields
+type JSONWriter struct {
+	enc   *json.Encoder
+	paths []string
+}
+
+// NewJSONWriter returns a writer of JSON lines. With no paths whole events
+// are written
+func NewJSONWriter(w io.Writer, paths []string) *JSONWriter {
+	return &JSONWriter{enc: json.NewEncoder(w), paths: paths}
+}
+
+func (w *JSONWriter) Write(fields map[string]interface{}) error {
+	return w.enc.Encode(Project(fields, w.paths))
+}
+
+func (w *JSONWriter) Flush() error {
+	return nil
+}
+
+// TableWriter writes events as an aligned table with a column per selected
+// field. Rows are buffered until Flush so the columns can be sized
+type TableWriter struct {
+	tw      *tabwriter.Writer
+	columns []string
+	header  bool
+}
+
+// NewTableWriter returns a table writer. With no paths the table shows the
+// timestamp and name of the events
+func NewTableWriter(w io.Writer, paths []string) *TableWriter {
+	if len(paths) == 0 {
+		paths = defaultColumns
+	}
+	return &TableWriter{
+		tw:      tabwriter.NewWriter(w, 0, 8, 2, ' ', 0),
+		columns: paths,
+	}
+}
+
+func (w *TableWriter) Write(fields map[string]interface{}) error {
+	if !w.header {
+		w.header = true
+		err := w.row(w.columns)
+		if err != nil {
+			return err
+		}
+	}
+
+	cells := make([]string, len(w.columns))
+	for i, path := range w.columns {
+		v, _ := Lookup(fields, path)
+		// tabs and newlines would break the table
+		cells[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(Format(v))
+	}
+	return w.row(cells)
+}
+
+func (w *TableWriter) row(cells []string) error {
+	_, err := io.WriteString(w.tw, strings.Join(cells, "\t")+"\n")
+	return err
+}
+
+func (w *TableWriter) Flush() error {
+	return w.tw.Flush()
+}
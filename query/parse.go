@@ -0,0 +1,259 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// token kinds
+const (
+	tokEOF = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind int
+	text string
+	pos  int
+}
+
+// SyntaxError is an error parsing an expression, at a byte offset
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at %d: %s", e.Pos, e.Msg)
+}
+
+// operators, longest first so that "==" is not lexed as "="
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!"}
+
+func lex(s string) ([]token, error) {
+	tokens := make([]token, 0)
+	i := 0
+	for i < len(s) {
+		c, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case unicode.IsSpace(c):
+			i += size
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		case c == '"' || c == '\'':
+			start := i
+			var b strings.Builder
+			i++
+			for i < len(s) && rune(s[i]) != c {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+				i++
+			}
+			if i == len(s) {
+				return nil, &SyntaxError{Pos: start, Msg: "unterminated string"}
+			}
+			i++
+			tokens = append(tokens, token{kind: tokString, text: b.String(), pos: start})
+		case c >= '0' && c <= '9' || c == '-' || c == '.':
+			start := i
+			i++
+			for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.' || s[i] == 'e' || s[i] == 'E' ||
+				(s[i] == '-' || s[i] == '+') && (s[i-1] == 'e' || s[i-1] == 'E')) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: s[start:i], pos: start})
+		case c == '_' || c == '$' || unicode.IsLetter(c):
+			start := i
+			for i < len(s) {
+				r, size := utf8.DecodeRuneInString(s[i:])
+				if r != '_' && r != '$' && r != '.' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				i += size
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: s[start:i], pos: start})
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, &SyntaxError{Pos: i, Msg: fmt.Sprintf("unexpected %q", c)}
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(s)}), nil
+}
+
+// Parse parses a filter expression. Expressions compare event fields with
+// literals using ==, !=, <, <=, >, >=, =~ and !~ (regular expression match),
+// and combine comparisons with &&, || and !. Fields are named by their
+// journal key, with dots to reach into objects and arrays, e.g.
+// Materials.0.Name. A field on its own is true when present and not false,
+// zero or empty
+func Parse(s string) (*Expr, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.text)}
+	}
+	return &Expr{src: s, root: n}, nil
+}
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOp && p.peek().text == "||" {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOp && p.peek().text == "&&" {
+		p.next()
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) not() (node, error) {
+	if t := p.peek(); t.kind == tokOp && t.text == "!" {
+		p.next()
+		n, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{n: n}, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (node, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	if t.kind != tokOp {
+		return left, nil
+	}
+	switch t.text {
+	case "==", "!=", "<", "<=", ">", ">=":
+		p.next()
+		right, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		return &compareNode{op: t.text, left: left, right: right}, nil
+	case "=~", "!~":
+		p.next()
+		r := p.next()
+		if r.kind != tokString {
+			return nil, &SyntaxError{Pos: r.pos, Msg: "expected a regular expression string"}
+		}
+		rex, err := regexp.Compile(r.text)
+		if err != nil {
+			return nil, &SyntaxError{Pos: r.pos, Msg: err.Error()}
+		}
+		return &matchNode{negate: t.text == "!~", left: left, rex: rex}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) operand() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if r := p.next(); r.kind != tokRParen {
+			return nil, &SyntaxError{Pos: r.pos, Msg: "expected )"}
+		}
+		return n, nil
+	case tokString:
+		return &literalNode{v: t.text}, nil
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("invalid number %q", t.text)}
+		}
+		return &literalNode{v: f}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{v: true}, nil
+		case "false":
+			return &literalNode{v: false}, nil
+		case "null":
+			return &literalNode{v: nil}, nil
+		}
+		return &fieldNode{path: t.text}, nil
+	case tokEOF:
+		return nil, &SyntaxError{Pos: t.pos, Msg: "unexpected end of expression"}
+	}
+	return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.text)}
+}
@@ -0,0 +1,142 @@
+package query
+
+import (
+	"testing"
+)
+
+const testEvent = `{
+	"timestamp": "2021-01-01T00:00:00Z",
+	"event": "FSDJump",
+	"StarSystem": "Ölfeld",
+	"JumpDist": 12.5,
+	"Taxi": false,
+	"Factions": [{"Name": "Ödland Union", "Influence": 0.25}],
+	"SystemEconomy": ""
+}`
+
+func TestParseEval(t *testing.T) {
+	fields, err := Decode([]byte(testEvent))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`true`, true},
+		{`false`, false},
+		{`event == "FSDJump"`, true},
+		{`event == 'FSDJump'`, true},
+		{`event != "FSDJump"`, false},
+		{`JumpDist > 10`, true},
+		{`JumpDist >= 12.5`, true},
+		{`JumpDist < 12.5`, false},
+		{`JumpDist <= 1.25e1`, true},
+		{`JumpDist > -1`, true},
+		{`StarSystem == "Ölfeld"`, true},
+		{`StarSystem =~ "^Öl"`, true},
+		{`StarSystem !~ "^Öl"`, false},
+		{`Factions.0.Name == "Ödland Union"`, true},
+		{`Factions.0.Influence < 0.5`, true},
+		{`Factions.1.Name`, false},
+		{`Taxi`, false},
+		{`!Taxi`, true},
+		{`SystemEconomy`, false},
+		{`Missing`, false},
+		{`Missing == null`, true},
+		{`Taxi == false`, true},
+		{`JumpDist == "12.5"`, false},
+		{`JumpDist > "1"`, false},
+		{`event == "Scan" || JumpDist > 10`, true},
+		{`event == "Scan" || JumpDist > 20`, false},
+		{`event == "FSDJump" && !(JumpDist > 20)`, true},
+		{`!event == "FSDJump"`, false},
+		{`event == "Scan" || event == "FSDJump" && Taxi`, false},
+		{`(event == "Scan" || event == "FSDJump") && !Taxi`, true},
+		{`StarSystem == "a \"quoted\" name"`, false},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := e.Eval(fields); got != tt.want {
+			t.Errorf("Parse(%q).Eval() = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseFieldNames(t *testing.T) {
+	tests := []struct {
+		expr string
+		path string
+	}{
+		{`StarSystem`, "StarSystem"},
+		{`Factions.0.Name`, "Factions.0.Name"},
+		{`Ölfeld`, "Ölfeld"},
+		{`Système_2`, "Système_2"},
+		{`  $schema  `, "$schema"},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+		f, ok := e.root.(*fieldNode)
+		if !ok {
+			t.Errorf("Parse(%q) = %T, want a field", tt.expr, e.root)
+			continue
+		}
+		if f.path != tt.path {
+			t.Errorf("Parse(%q) = field %q, want %q", tt.expr, f.path, tt.path)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  int
+	}{
+		{``, 0},
+		{`event ==`, 8},
+		{`event == "FSDJump`, 9},
+		{`(event == "FSDJump"`, 19},
+		{`event == "FSDJump")`, 18},
+		{`event =~ FSDJump`, 9},
+		{`event =~ "("`, 9},
+		{`event = "FSDJump"`, 6},
+		{`JumpDist > 1.2.3`, 11},
+		{`event == "FSDJump" ☃`, 19},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.expr)
+		serr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("Parse(%q) error = %v, want a syntax error", tt.expr, err)
+			continue
+		}
+		if serr.Pos != tt.pos {
+			t.Errorf("Parse(%q) error at %d, want %d: %v", tt.expr, serr.Pos, tt.pos, serr)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	e, err := Parse(`event == "FSDJump"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err := e.Match([]byte(testEvent))
+	if err != nil || !ok {
+		t.Errorf("Match() = %v, %v, want true", ok, err)
+	}
+	_, err = e.Match([]byte(`{"event":`))
+	if err == nil {
+		t.Error("Match() of invalid JSON did not fail")
+	}
+}
@@ -0,0 +1,326 @@
+package ranks
+
+import (
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/event"
+	"github.com/sht/ed-journal/events"
+)
+
+// Category is a rank category
+type Category string
+
+const (
+	Combat       Category = "combat"
+	Trade        Category = "trade"
+	Explore      Category = "explore"
+	Empire       Category = "empire"
+	Federation   Category = "federation"
+	CQC          Category = "cqc"
+	Soldier      Category = "soldier"
+	Exobiologist Category = "exobiologist"
+)
+
+// Categories lists every rank category
+var Categories = []Category{Combat, Trade, Explore, Empire, Federation, CQC, Soldier, Exobiologist}
+
+// maxRank is the highest rank of each category. Combat, trade, exploration
+// and the Odyssey ranks go up to Elite V
+var maxRank = map[Category]int{
+	Combat:       13,
+	Trade:        13,
+	Explore:      13,
+	Empire:       14,
+	Federation:   14,
+	CQC:          8,
+	Soldier:      13,
+	Exobiologist: 13,
+}
+
+const (
+	// idleGap is the longest gap between two events counted as play time
+	idleGap = 30 * time.Minute
+	// rateWindow is the play time the rate of progress is worked out over
+	rateWindow = 20 * time.Hour
+)
+
+// Sample is the rank and progress percentage in a category at some point,
+// along with the total play time at that point
+type Sample struct {
+	Timestamp time.Time     `json:"timestamp"`
+	PlayTime  time.Duration `json:"playTime"`
+	Rank      int           `json:"rank"`
+	Progress  int           `json:"progress"`
+}
+
+// Forecast is the expected play time until the next rank in a category.
+// Rate is in percent per hour of play, Remaining is zero when no progress has
+// been made in the rate window
+type Forecast struct {
+	Category  Category      `json:"category"`
+	Rank      int           `json:"rank"`
+	Progress  int           `json:"progress"`
+	Rate      float64       `json:"rate"`
+	Remaining time.Duration `json:"remaining"`
+	Max       bool          `json:"max"`
+}
+
+// Forecaster records rank progress over time and forecasts the time to the
+// next rank. It is safe for concurrent use
+type Forecaster struct {
+	mu       sync.RWMutex
+	samples  map[Category][]Sample
+	playTime time.Duration
+	last     time.Time
+	playing  bool
+	ignore   map[string]bool
+}
+
+// NewForecaster returns a forecaster. Events named in ignore are not journal
+// lines and do not count towards play time
+func NewForecaster(ignore ...string) *Forecaster {
+	samples := make(map[Category][]Sample, len(Categories))
+	for _, c := range Categories {
+		samples[c] = make([]Sample, 0)
+	}
+	f := &Forecaster{
+		samples: samples,
+		ignore:  make(map[string]bool, len(ignore)),
+	}
+	for _, name := range ignore {
+		f.ignore[name] = true
+	}
+	return f
+}
+
+const SnapshotVersion = 1
+
+// Snapshot saves the rank samples and the play time they are measured in
+func (f *Forecaster) Snapshot() (json.RawMessage, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return json.Marshal(f.saved())
+}
+
+func (f *Forecaster) Restore(b json.RawMessage) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return json.Unmarshal(b, f.saved())
+}
+
+// saved points at the fields kept in snapshots
+func (f *Forecaster) saved() interface{} {
+	return &struct {
+		Samples  *map[Category][]Sample `json:"samples"`
+		PlayTime *time.Duration         `json:"playTime"`
+		Last     *time.Time             `json:"last"`
+		Playing  *bool                  `json:"playing"`
+	}{&f.samples, &f.playTime, &f.last, &f.playing}
+}
+
+// AddListeners subscribes the forecaster to the rank events and to every
+// event to measure play time
+func (f *Forecaster) AddListeners(d *dispatcher.Dispatcher) {
+	// startup
+	{
+		d.OnSync(events.Progress, f.progress)
+		d.OnSync(events.Rank, f.rank)
+	}
+
+	// other
+	{
+		d.OnSync(events.Promotion, f.promotion)
+	}
+
+	d.OnAllSync(f.clock)
+}
+
+// PlayTime returns the total play time seen in the journal
+func (f *Forecaster) PlayTime() time.Duration {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.playTime
+}
+
+// Samples returns the recorded samples of a category, oldest first
+func (f *Forecaster) Samples(c Category) []Sample {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	samples := make([]Sample, len(f.samples[c]))
+	copy(samples, f.samples[c])
+	return samples
+}
+
+// Forecast returns the forecast for a category, or false when nothing is
+// known about it yet
+func (f *Forecaster) Forecast(c Category) (Forecast, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	samples := f.samples[c]
+	if len(samples) == 0 {
+		return Forecast{}, false
+	}
+
+	last := samples[len(samples)-1]
+	fc := Forecast{
+		Category: c,
+		Rank:     last.Rank,
+		Progress: last.Progress,
+		Max:      last.Rank >= maxRank[c],
+	}
+	if fc.Max {
+		return fc, true
+	}
+
+	// use the oldest sample within the rate window
+	first := last
+	for i := len(samples) - 1; i >= 0; i-- {
+		if last.PlayTime-samples[i].PlayTime > rateWindow {
+			break
+		}
+		first = samples[i]
+	}
+
+	hours := (last.PlayTime - first.PlayTime).Hours()
+	gained := float64(value(last) - value(first))
+	if hours <= 0 || gained <= 0 {
+		return fc, true
+	}
+	fc.Rate = gained / hours
+	fc.Remaining = time.Duration(float64(100-last.Progress) / fc.Rate * float64(time.Hour))
+	return fc, true
+}
+
+// Forecasts returns the forecast for every category with samples
+func (f *Forecaster) Forecasts() []Forecast {
+	forecasts := make([]Forecast, 0, len(Categories))
+	for _, c := range Categories {
+		if fc, ok := f.Forecast(c); ok {
+			forecasts = append(forecasts, fc)
+		}
+	}
+	return forecasts
+}
+
+// value combines rank and progress into a single percentage
+func value(s Sample) int {
+	return s.Rank*100 + s.Progress
+}
+
+// clock adds the time since the previous event to the play time. Gaps longer
+// than idleGap and the time between a shutdown and the next session are not
+// counted
+func (f *Forecaster) clock(b []byte) {
+	var e event.Event
+	err := json.Unmarshal(b, &e)
+	if err != nil || f.ignore[e.Event] {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.playing && e.Event != events.Fileheader && e.Timestamp.After(f.last) {
+		f.playTime += time.Duration(math.Min(float64(e.Timestamp.Sub(f.last)), float64(idleGap)))
+	}
+	f.last = e.Timestamp
+	f.playing = e.Event != events.Shutdown
+}
+
+// record adds a sample for every category with a value. The Rank and
+// Progress events written together at startup update a single sample. Must
+// be called with the lock held
+func (f *Forecaster) record(ts time.Time, values map[Category]*int, fn func(s *Sample, v int)) {
+	for c, v := range values {
+		if v == nil {
+			continue
+		}
+		samples := f.samples[c]
+		s := Sample{Timestamp: ts, PlayTime: f.playTime}
+		n := len(samples)
+		if n > 0 {
+			s.Rank = samples[n-1].Rank
+			s.Progress = samples[n-1].Progress
+		}
+		fn(&s, *v)
+		if n > 0 && samples[n-1].Timestamp.Equal(ts) {
+			samples[n-1] = s
+			continue
+		}
+		f.samples[c] = append(samples, s)
+	}
+}
+
+func values(combat, trade, explore, empire, federation, cqc, soldier, exobiologist *int) map[Category]*int {
+	return map[Category]*int{
+		Combat:       combat,
+		Trade:        trade,
+		Explore:      explore,
+		Empire:       empire,
+		Federation:   federation,
+		CQC:          cqc,
+		Soldier:      soldier,
+		Exobiologist: exobiologist,
+	}
+}
+
+func (f *Forecaster) progress(b []byte) {
+	var e events.ProgressEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	v := values(&e.Combat, &e.Trade, &e.Explore, &e.Empire, &e.Federation, &e.CQC, e.Soldier, e.Exobiologist)
+	f.record(e.Timestamp, v, func(s *Sample, progress int) {
+		s.Progress = progress
+	})
+}
+
+func (f *Forecaster) rank(b []byte) {
+	var e events.RankEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	v := values(&e.Combat, &e.Trade, &e.Explore, &e.Empire, &e.Federation, &e.CQC, e.Soldier, e.Exobiologist)
+	f.record(e.Timestamp, v, func(s *Sample, rank int) {
+		if rank != s.Rank {
+			s.Progress = 0
+		}
+		s.Rank = rank
+	})
+}
+
+func (f *Forecaster) promotion(b []byte) {
+	var e events.PromotionEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	v := values(e.Combat, e.Trade, e.Explore, e.Empire, e.Federation, e.CQC, e.Soldier, e.Exobiologist)
+	f.record(e.Timestamp, v, func(s *Sample, rank int) {
+		s.Rank = rank
+		s.Progress = 0
+	})
+}
@@ -0,0 +1,301 @@
+package route
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/event"
+	"github.com/sht/ed-journal/events"
+)
+
+const (
+	RouteProgress  = "RouteProgress"
+	RouteDeviation = "RouteDeviation"
+)
+
+// NavRouteFile is the name of the file the game writes the plotted route to
+const NavRouteFile = "NavRoute.json"
+
+// Progress describes how far along the plotted route the commander is. Hop
+// is the index in Route of the system the commander is in
+type Progress struct {
+	Route             []*events.RouteHop `json:"route,omitempty"`
+	Hop               int                `json:"hop"`
+	Jumps             int                `json:"jumps"`
+	JumpsRemaining    int                `json:"jumpsRemaining"`
+	DistanceRemaining float64            `json:"distanceRemaining"`
+	AverageJumpTime   time.Duration      `json:"averageJumpTime"`
+	ETA               time.Time          `json:"eta,omitempty"`
+	Deviated          bool               `json:"deviated"`
+	Complete          bool               `json:"complete"`
+}
+
+// ProgressEvent is triggered on the dispatcher after every jump along the route
+type ProgressEvent struct {
+	event.Event
+	Progress
+}
+
+// DeviationEvent is triggered on the dispatcher when the commander jumps to a
+// system that is not on the plotted route
+type DeviationEvent struct {
+	event.Event
+	Expected      string `json:"Expected"`
+	StarSystem    string `json:"StarSystem"`
+	SystemAddress int    `json:"SystemAddress"`
+}
+
+// ReadNavRoute reads the route from a NavRoute.json file
+func ReadNavRoute(path string) (*events.NavRouteEvent, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var e events.NavRouteEvent
+	err = json.Unmarshal(b, &e)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Tracker follows the commander along the plotted route. It is safe for
+// concurrent use
+type Tracker struct {
+	mu        sync.RWMutex
+	d         *dispatcher.Dispatcher
+	dir       string
+	route     []*events.RouteHop
+	hop       int
+	remaining int
+	deviated  bool
+	lastJump  time.Time
+	jumpTimes []time.Duration
+}
+
+// NewTracker returns a route tracker that reads NavRoute.json from the given
+// journal directory when a route is plotted
+func NewTracker(dir string) *Tracker {
+	return &Tracker{
+		dir:       dir,
+		remaining: -1,
+	}
+}
+
+const SnapshotVersion = 1
+
+// Snapshot saves the route and the progress along it
+func (t *Tracker) Snapshot() (json.RawMessage, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return json.Marshal(t.saved())
+}
+
+func (t *Tracker) Restore(b json.RawMessage) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return json.Unmarshal(b, t.saved())
+}
+
+// saved points at the fields kept in snapshots
+func (t *Tracker) saved() interface{} {
+	return &struct {
+		Route     *[]*events.RouteHop `json:"route"`
+		Hop       *int                `json:"hop"`
+		Remaining *int                `json:"remaining"`
+		Deviated  *bool               `json:"deviated"`
+		LastJump  *time.Time          `json:"lastJump"`
+		JumpTimes *[]time.Duration    `json:"jumpTimes"`
+	}{&t.route, &t.hop, &t.remaining, &t.deviated, &t.lastJump, &t.jumpTimes}
+}
+
+// AddListeners subscribes the tracker to the route and jump events. Progress
+// and deviation events are triggered on the same dispatcher
+func (t *Tracker) AddListeners(d *dispatcher.Dispatcher) {
+	t.mu.Lock()
+	t.d = d
+	t.mu.Unlock()
+
+	d.OnSync(events.FSDJump, t.fsdJump)
+	d.OnSync(events.FSDTarget, t.fsdTarget)
+	d.OnSync(events.NavRoute, t.navRoute)
+	d.OnSync(events.NavRouteClear, t.navRouteClear)
+}
+
+// SetRoute replaces the plotted route, assuming the commander is at its start
+func (t *Tracker) SetRoute(r *events.NavRouteEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.route = r.Route
+	t.hop = 0
+	t.remaining = -1
+	t.deviated = false
+	t.lastJump = time.Time{}
+	t.jumpTimes = nil
+}
+
+// Progress returns the progress along the plotted route, or false when no
+// route is plotted
+func (t *Tracker) Progress() (Progress, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.route) == 0 {
+		return Progress{}, false
+	}
+	return t.progress(), true
+}
+
+// progress must be called with the lock held
+func (t *Tracker) progress() Progress {
+	p := Progress{
+		Route:          t.route,
+		Hop:            t.hop,
+		Jumps:          len(t.route) - 1,
+		JumpsRemaining: len(t.route) - 1 - t.hop,
+		Deviated:       t.deviated,
+	}
+	if t.remaining >= 0 && t.deviated {
+		p.JumpsRemaining = t.remaining
+	}
+	for i := t.hop; i+1 < len(t.route); i++ {
+		p.DistanceRemaining += distance(t.route[i].StarPos, t.route[i+1].StarPos)
+	}
+	p.Complete = p.JumpsRemaining == 0 && !t.deviated
+
+	if len(t.jumpTimes) > 0 {
+		var total time.Duration
+		for _, d := range t.jumpTimes {
+			total += d
+		}
+		p.AverageJumpTime = total / time.Duration(len(t.jumpTimes))
+		p.ETA = t.lastJump.Add(p.AverageJumpTime * time.Duration(p.JumpsRemaining))
+	}
+	return p
+}
+
+func distance(a, b []float64) float64 {
+	if len(a) != 3 || len(b) != 3 {
+		return 0
+	}
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
+
+func (t *Tracker) navRoute(b []byte) {
+	var e events.NavRouteEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	// NavRoute.json only holds the latest route, so it is only used for the
+	// event that wrote it. Older events leave the route unknown
+	if len(e.Route) == 0 && t.dir != "" {
+		r, err := ReadNavRoute(filepath.Join(t.dir, NavRouteFile))
+		if err == nil && r.Timestamp.Equal(e.Timestamp) {
+			e.Route = r.Route
+		}
+	}
+	t.SetRoute(&e)
+}
+
+func (t *Tracker) navRouteClear(b []byte) {
+	var e events.NavRouteClearEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	t.SetRoute(&events.NavRouteEvent{})
+}
+
+func (t *Tracker) fsdTarget(b []byte) {
+	var e events.FSDTargetEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.remaining = e.RemainingJumpsInRoute
+}
+
+func (t *Tracker) fsdJump(b []byte) {
+	var e events.FSDJumpEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	// once at the destination, jumps are no longer along the route
+	if len(t.route) == 0 || t.hop == len(t.route)-1 {
+		t.mu.Unlock()
+		return
+	}
+
+	var trigger interface{}
+	var name string
+
+	hop := -1
+	for i := t.hop + 1; i < len(t.route); i++ {
+		if t.route[i].SystemAddress == e.SystemAddress {
+			hop = i
+			break
+		}
+	}
+
+	if hop < 0 {
+		expected := ""
+		if t.hop+1 < len(t.route) {
+			expected = t.route[t.hop+1].StarSystem
+		}
+		t.deviated = true
+		name = RouteDeviation
+		trigger = DeviationEvent{
+			Event:         event.Event{Event: RouteDeviation, Timestamp: e.Timestamp},
+			Expected:      expected,
+			StarSystem:    e.StarSystem,
+			SystemAddress: e.SystemAddress,
+		}
+	} else {
+		if !t.lastJump.IsZero() {
+			t.jumpTimes = append(t.jumpTimes, e.Timestamp.Sub(t.lastJump)/time.Duration(hop-t.hop))
+		}
+		t.hop = hop
+		t.deviated = false
+		// the route itself is left out of progress events to keep them small
+		p := t.progress()
+		p.Route = nil
+		name = RouteProgress
+		trigger = ProgressEvent{
+			Event:    event.Event{Event: RouteProgress, Timestamp: e.Timestamp},
+			Progress: p,
+		}
+	}
+	t.lastJump = e.Timestamp
+	t.remaining = -1
+	d := t.d
+	t.mu.Unlock()
+
+	if d == nil {
+		return
+	}
+	out, err := json.Marshal(trigger)
+	if err != nil {
+		return
+	}
+	_ = d.Trigger(name, out)
+}
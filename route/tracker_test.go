@@ -0,0 +1,91 @@
+package route
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sht/ed-journal/dispatcher"
+)
+
+var start = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// jump triggers an FSDJump into system address at start+at
+func jump(t *testing.T, d *dispatcher.Dispatcher, at time.Duration, name string, address int) {
+	t.Helper()
+
+	b := []byte(fmt.Sprintf(`{ "timestamp":"%s", "event":"FSDJump", "StarSystem":"%s", "SystemAddress":%d }`,
+		start.Add(at).Format(time.RFC3339), name, address))
+	err := d.Trigger("FSDJump", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRouteProgress(t *testing.T) {
+	d := dispatcher.NewDispatcher()
+	tr := NewTracker("")
+	tr.AddListeners(d)
+	progress := make([]ProgressEvent, 0)
+	d.OnSync(RouteProgress, func(b []byte) {
+		var e ProgressEvent
+		if err := json.Unmarshal(b, &e); err != nil {
+			t.Error(err)
+		}
+		progress = append(progress, e)
+	})
+	deviations := make([]DeviationEvent, 0)
+	d.OnSync(RouteDeviation, func(b []byte) {
+		var e DeviationEvent
+		if err := json.Unmarshal(b, &e); err != nil {
+			t.Error(err)
+		}
+		deviations = append(deviations, e)
+	})
+
+	err := d.Trigger("NavRoute", []byte(`{ "timestamp":"2021-01-01T00:00:00Z", "event":"NavRoute", "Route":[
+		{ "StarSystem":"Sol", "SystemAddress":1, "StarPos":[0,0,0] },
+		{ "StarSystem":"Alpha Centauri", "SystemAddress":2, "StarPos":[3,4,0] },
+		{ "StarSystem":"Barnard's Star", "SystemAddress":3, "StarPos":[3,4,12] },
+		{ "StarSystem":"Wolf 359", "SystemAddress":4, "StarPos":[3,4,20] } ] }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, ok := tr.Progress()
+	if !ok || p.Jumps != 3 || p.JumpsRemaining != 3 || p.DistanceRemaining != 25 {
+		t.Errorf("progress at the start %+v, want 3 jumps over 25 ly", p)
+	}
+
+	jump(t, d, time.Minute, "Alpha Centauri", 2)
+	if len(progress) != 1 || progress[0].Hop != 1 || progress[0].JumpsRemaining != 2 || progress[0].DistanceRemaining != 20 {
+		t.Errorf("progress events %+v, want one at hop 1", progress)
+	}
+
+	// leaving the route, then joining it again further along
+	jump(t, d, 2*time.Minute, "Ross 154", 9)
+	if len(deviations) != 1 || deviations[0].Expected != "Barnard's Star" || deviations[0].StarSystem != "Ross 154" {
+		t.Errorf("deviation events %+v, want one expecting Barnard's Star", deviations)
+	}
+	if p, _ := tr.Progress(); !p.Deviated || p.Complete {
+		t.Errorf("progress %+v, want deviated", p)
+	}
+	jump(t, d, 4*time.Minute, "Wolf 359", 4)
+	p, _ = tr.Progress()
+	if len(progress) != 2 || !p.Complete || p.Deviated || p.JumpsRemaining != 0 {
+		t.Errorf("progress %+v, want complete", p)
+	}
+	// two hops in the two minutes since the last jump
+	if p.AverageJumpTime != time.Minute {
+		t.Errorf("average jump time %v, want 1m", p.AverageJumpTime)
+	}
+
+	// jumps past the destination are not deviations
+	jump(t, d, 5*time.Minute, "Ross 128", 10)
+	if len(deviations) != 1 || len(progress) != 2 {
+		t.Errorf("got %d deviations and %d progress events after the destination, want 1 and 2", len(deviations), len(progress))
+	}
+	if p, _ := tr.Progress(); !p.Complete {
+		t.Errorf("progress %+v, want still complete", p)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sht/ed-journal/api"
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/event"
+	"github.com/sht/ed-journal/events"
+	"github.com/sht/ed-journal/fleet"
+	"github.com/sht/ed-journal/snapshot"
+	"github.com/sht/ed-journal/stream"
+)
+
+func serveCmd(args []string) int {
+	fs := newFlagSet("serve")
+	dir := dirFlag(fs)
+	data := dataFlag(fs)
+	addr := fs.String("addr", api.DefaultAddr, "address to listen on, keep it on a loopback address")
+	interval := fs.Duration("interval", time.Second, "how often to check the journal")
+	snapshots := fs.Duration("snapshot-interval", 5*time.Minute, "how often to snapshot the state, 0 to rebuild it from the whole journal on every start")
+	replay := fs.Int("replay", 100, "number of recent events kept for stream clients asking for a replay")
+	heartbeat := fs.Duration("heartbeat", 15*time.Second, "interval between heartbeats sent to idle stream clients")
+	nullOrigin := fs.Bool("allow-null-origin", false, "let pages opened from files connect over WebSocket, which sandboxed pages of any website can do as well")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, fmt.Errorf("unexpected argument %q", fs.Arg(0)))
+	}
+	if *replay < 0 {
+		return usageError(fs, fmt.Errorf("invalid replay %d", *replay))
+	}
+	if *heartbeat <= 0 {
+		return usageError(fs, fmt.Errorf("invalid heartbeat %s", *heartbeat))
+	}
+
+	logf := func(format string, args ...interface{}) {
+		fmt.Fprintf(os.Stderr, "ed-journal: "+format+"\n", args...)
+	}
+
+	s, err := openStore(*data)
+	if err != nil {
+		return fail(err)
+	}
+	defer s.Close()
+
+	// the fleet registry keeps its own file, as ships stored elsewhere may not
+	// be seen again for a long time
+	fleetPath := filepath.Join(*data, fleetFile)
+	registry, err := fleet.LoadRegistry(fleetPath)
+	if err != nil {
+		return fail(err)
+	}
+
+	d := dispatcher.NewDispatcher()
+	t := newTrackers(*dir)
+	t.fleet = registry
+
+	// start from the snapshot when it holds every tracker, otherwise replay
+	// the whole journal. A snapshot that failed part way may have been
+	// restored into some of the trackers, so they start over
+	var mgr *snapshot.Manager
+	var resume event.Cursor
+	if *snapshots > 0 {
+		mgr = snapshot.NewManager(filepath.Join(*data, "snapshot.json"), *snapshots)
+		t.register(mgr)
+		c, ok, err := mgr.Load()
+		if err != nil {
+			logf("snapshot not restored: %v", err)
+			t = newTrackers(*dir)
+			t.fleet = registry
+			t.register(mgr)
+		}
+		if ok {
+			resume = c
+		}
+	}
+	t.addListeners(d)
+	rec, err := newRecorder(s)
+	if err != nil {
+		return fail(err)
+	}
+	rec.AddListeners(d)
+	hub := stream.NewHub(*replay, *heartbeat)
+	hub.AllowNullOrigin = *nullOrigin
+	hub.AddListeners(d)
+	srv := api.NewServer(t.state, t.sessions, s)
+	srv.AddListeners(d)
+	srv.Handle("/events/ws", hub.WebSocketHandler())
+	srv.Handle("/events/sse", hub.SSEHandler())
+
+	w, err := event.NewWatcher(*dir, func(b []byte) {
+		var e event.Event
+		if json.Unmarshal(b, &e) != nil {
+			return
+		}
+		_ = d.Trigger(e.Event, b)
+	}, *interval)
+	if err != nil {
+		return fail(err)
+	}
+	w.OnError = func(err error) {
+		logf("%v", err)
+	}
+
+	// Status.json is checked along with the journal, so its updates are
+	// dispatched in order with the journal events
+	status := event.NewFileWatcher(filepath.Join(*dir, events.StatusFile), func(b []byte) {
+		_ = d.Trigger(events.Status, b)
+	})
+	w.OnTick = func(now time.Time) {
+		err := status.Poll()
+		if err != nil {
+			logf("status: %v", err)
+		}
+		t.missions.Check(now)
+	}
+
+	if !resume.IsZero() {
+		w.SetCursor(resume)
+	}
+	storeFailed := false
+	w.OnBatch = func(c event.Cursor) {
+		if err := rec.Err(); err != nil && !storeFailed {
+			logf("store: %v, events are no longer stored", err)
+			storeFailed = true
+		}
+		err := registry.Save(fleetPath)
+		if err != nil {
+			logf("fleet: %v", err)
+		}
+		if mgr == nil {
+			return
+		}
+		err = mgr.Checkpoint(c)
+		if err != nil {
+			logf("snapshot: %v", err)
+		}
+	}
+	if w.Cursor().IsZero() {
+		files, err := event.FindJournals(*dir)
+		if err != nil {
+			return fail(err)
+		}
+		if len(files) > 0 {
+			w.SetCursor(event.Cursor{File: filepath.Base(files[0].Path)})
+		}
+	}
+
+	// catch up with the journal before serving
+	w.Poll()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe(*addr)
+	}()
+	w.Start()
+	logf("serving on http://%s", *addr)
+
+	code := exitOK
+	select {
+	case <-interrupted():
+	case err = <-errc:
+		logf("%v", err)
+		code = exitError
+	}
+
+	w.Stop()
+	// the journal read so far ends here, so does the session in progress
+	t.sessions.Close()
+	err = registry.Save(fleetPath)
+	if err != nil {
+		logf("fleet: %v", err)
+		code = exitError
+	}
+	if mgr != nil {
+		err = mgr.Write(w.Cursor())
+		if err != nil {
+			logf("snapshot: %v", err)
+			code = exitError
+		}
+	}
+	return code
+}
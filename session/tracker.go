@@ -0,0 +1,349 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sht/ed-journal/credits"
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/event"
+	"github.com/sht/ed-journal/events"
+)
+
+const (
+	SessionEnded = "SessionEnded"
+)
+
+// Session summarises a single play session, from the game being loaded until
+// it is shut down or the journal ends
+type Session struct {
+	Start        time.Time      `json:"start"`
+	End          time.Time      `json:"end"`
+	Duration     time.Duration  `json:"duration"`
+	Commander    string         `json:"commander,omitempty"`
+	GameMode     string         `json:"gameMode,omitempty"`
+	Group        string         `json:"group,omitempty"`
+	GameVersion  string         `json:"gameVersion,omitempty"`
+	Ships        []string       `json:"ships"`
+	Systems      []string       `json:"systems"`
+	CreditsStart int            `json:"creditsStart"`
+	CreditsEnd   int            `json:"creditsEnd"`
+	CreditsDelta int            `json:"creditsDelta"`
+	Events       int            `json:"events"`
+	EventCounts  map[string]int `json:"eventCounts"`
+	Shutdown     bool           `json:"shutdown"`
+
+	loaded  bool
+	ships   map[string]bool
+	systems map[string]bool
+}
+
+// SessionEndedEvent is triggered on the dispatcher when a session ends
+type SessionEndedEvent struct {
+	event.Event
+	Session *Session `json:"Session"`
+}
+
+// Tracker splits the event stream into sessions. It is safe for concurrent use
+type Tracker struct {
+	mu       sync.RWMutex
+	d        *dispatcher.Dispatcher
+	ledger   *credits.Ledger
+	ignore   map[string]bool
+	current  *Session
+	sessions []*Session
+}
+
+// NewTracker returns a session tracker. When ledger is not nil, it is used to
+// work out the credits at the end of each session. Without a ledger they are
+// only known when the game is loaded again in the same journal file, other
+// sessions end with the credits they started with and a CreditsDelta of 0.
+// Events named in ignore are not journal lines and are not counted, nor are
+// the SessionEnded events the tracker triggers itself
+func NewTracker(ledger *credits.Ledger, ignore ...string) *Tracker {
+	t := &Tracker{
+		ledger:   ledger,
+		ignore:   map[string]bool{SessionEnded: true},
+		sessions: make([]*Session, 0),
+	}
+	for _, name := range ignore {
+		t.ignore[name] = true
+	}
+	return t
+}
+
+const SnapshotVersion = 1
+
+// Snapshot saves the ended sessions and the one running
+func (t *Tracker) Snapshot() (json.RawMessage, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return json.Marshal(t.saved())
+}
+
+func (t *Tracker) Restore(b json.RawMessage) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	err := json.Unmarshal(b, t.saved())
+	if err != nil || t.current == nil {
+		return err
+	}
+	// the running session knows whether the game was loaded and which ships
+	// and systems it has seen from what it saved
+	s := t.current
+	s.loaded = s.EventCounts[events.LoadGame] > 0
+	s.ships = make(map[string]bool, len(s.Ships))
+	for _, name := range s.Ships {
+		s.ships[name] = true
+	}
+	s.systems = make(map[string]bool, len(s.Systems))
+	for _, name := range s.Systems {
+		s.systems[name] = true
+	}
+	return nil
+}
+
+// saved points at the fields kept in snapshots
+func (t *Tracker) saved() interface{} {
+	return &struct {
+		Sessions *[]*Session `json:"sessions"`
+		Current  **Session   `json:"current"`
+	}{&t.sessions, &t.current}
+}
+
+// AddListeners subscribes the tracker to every event. SessionEnded events are
+// triggered on the same dispatcher
+func (t *Tracker) AddListeners(d *dispatcher.Dispatcher) {
+	t.mu.Lock()
+	t.d = d
+	t.mu.Unlock()
+
+	d.OnAllSync(t.handle)
+}
+
+// Sessions returns the sessions that have ended, oldest first
+func (t *Tracker) Sessions() []Session {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	sessions := make([]Session, 0, len(t.sessions))
+	for _, s := range t.sessions {
+		sessions = append(sessions, s.copy())
+	}
+	return sessions
+}
+
+// Current returns the session in progress, if any
+func (t *Tracker) Current() (Session, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.current == nil {
+		return Session{}, false
+	}
+	return t.current.copy(), true
+}
+
+// Close ends the session in progress at the time of its last event. It is
+// called when the end of a journal archive is reached
+func (t *Tracker) Close() {
+	t.mu.Lock()
+	ended := t.end()
+	t.mu.Unlock()
+
+	t.trigger(ended)
+}
+
+func (t *Tracker) handle(b []byte) {
+	var e event.Event
+	err := json.Unmarshal(b, &e)
+	if err != nil || t.ignore[e.Event] {
+		return
+	}
+
+	t.mu.Lock()
+	ended := t.apply(e, b)
+	t.mu.Unlock()
+
+	t.trigger(ended)
+}
+
+// apply updates the sessions with the event, returning the session it ended
+// if any. Must be called with the lock held
+func (t *Tracker) apply(e event.Event, b []byte) *Session {
+	var ended *Session
+
+	switch e.Event {
+	case events.Fileheader:
+		var fh events.FileheaderEvent
+		err := json.Unmarshal(b, &fh)
+		if err != nil {
+			return nil
+		}
+		// continuation parts of a journal belong to the running session
+		if fh.Part > 1 && t.current != nil {
+			break
+		}
+		ended = t.end()
+		t.begin(e.Timestamp)
+		t.current.GameVersion = fh.GameVersion
+	case events.LoadGame:
+		var lg events.LoadGameEvent
+		err := json.Unmarshal(b, &lg)
+		if err != nil {
+			return nil
+		}
+		if t.current == nil || t.current.loaded {
+			if t.current != nil && t.ledger == nil {
+				t.current.CreditsEnd = lg.Credits
+			}
+			ended = t.end()
+			t.begin(e.Timestamp)
+		}
+		s := t.current
+		s.loaded = true
+		s.Commander = lg.Commander
+		s.GameMode = lg.GameMode
+		s.Group = lg.Group
+		s.CreditsStart = lg.Credits
+		s.CreditsEnd = lg.Credits
+		if lg.ShipID != nil {
+			s.addShip(lg.Ship, *lg.ShipID)
+		}
+	}
+
+	if t.current == nil {
+		t.begin(e.Timestamp)
+	}
+	s := t.current
+	s.End = e.Timestamp
+	s.Events++
+	s.EventCounts[e.Event]++
+
+	switch e.Event {
+	case events.Loadout:
+		var lo events.LoadoutEvent
+		if json.Unmarshal(b, &lo) == nil {
+			s.addShip(lo.Ship, lo.ShipID)
+		}
+	case events.ShipyardSwap:
+		var sw events.ShipyardSwapEvent
+		if json.Unmarshal(b, &sw) == nil {
+			s.addShip(sw.ShipType, sw.ShipID)
+		}
+	case events.ShipyardNew:
+		var sn events.ShipyardNewEvent
+		if json.Unmarshal(b, &sn) == nil {
+			s.addShip(sn.ShipType, sn.NewShipID)
+		}
+	case events.Location:
+		var l events.LocationEvent
+		if json.Unmarshal(b, &l) == nil {
+			s.addSystem(l.StarSystem)
+		}
+	case events.FSDJump:
+		var j events.FSDJumpEvent
+		if json.Unmarshal(b, &j) == nil {
+			s.addSystem(j.StarSystem)
+		}
+	case events.Shutdown:
+		s.Shutdown = true
+		if ended == nil {
+			ended = t.end()
+		}
+	}
+
+	return ended
+}
+
+// begin starts a new session. Must be called with the lock held
+func (t *Tracker) begin(start time.Time) {
+	t.current = &Session{
+		Start:       start,
+		End:         start,
+		Ships:       make([]string, 0),
+		Systems:     make([]string, 0),
+		EventCounts: make(map[string]int),
+		ships:       make(map[string]bool),
+		systems:     make(map[string]bool),
+	}
+}
+
+// end finishes the current session, returning it. Must be called with the
+// lock held
+func (t *Tracker) end() *Session {
+	s := t.current
+	if s == nil {
+		return nil
+	}
+	t.current = nil
+
+	if t.ledger != nil {
+		if balance, ok := t.ledger.Balance(); ok {
+			s.CreditsEnd = balance
+		}
+	}
+	s.CreditsDelta = s.CreditsEnd - s.CreditsStart
+	s.Duration = s.End.Sub(s.Start)
+	t.sessions = append(t.sessions, s)
+	return s
+}
+
+// trigger sends a SessionEnded event for s on the dispatcher
+func (t *Tracker) trigger(s *Session) {
+	t.mu.RLock()
+	d := t.d
+	t.mu.RUnlock()
+	if s == nil || d == nil {
+		return
+	}
+
+	c := s.copy()
+	b, err := json.Marshal(SessionEndedEvent{
+		Event: event.Event{
+			Event:     SessionEnded,
+			Timestamp: s.End,
+		},
+		Session: &c,
+	})
+	if err != nil {
+		return
+	}
+	_ = d.Trigger(SessionEnded, b)
+}
+
+func (s *Session) addShip(shipType string, id int) {
+	name := fmt.Sprintf("%s (%d)", strings.ToLower(shipType), id)
+	if shipType == "" || s.ships[name] {
+		return
+	}
+	s.ships[name] = true
+	s.Ships = append(s.Ships, name)
+}
+
+func (s *Session) addSystem(system string) {
+	if system == "" || s.systems[system] {
+		return
+	}
+	s.systems[system] = true
+	s.Systems = append(s.Systems, system)
+}
+
+// copy returns a copy of the session that does not share mutable memory
+func (s *Session) copy() Session {
+	c := *s
+	c.Ships = append([]string{}, s.Ships...)
+	c.Systems = append([]string{}, s.Systems...)
+	c.EventCounts = make(map[string]int, len(s.EventCounts))
+	for k, v := range s.EventCounts {
+		c.EventCounts[k] = v
+	}
+	c.ships = nil
+	c.systems = nil
+	return c
+}
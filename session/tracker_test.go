@@ -0,0 +1,108 @@
+package session
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sht/ed-journal/credits"
+	"github.com/sht/ed-journal/dispatcher"
+)
+
+var start = time.Date(2021, 1, 1, 20, 0, 0, 0, time.UTC)
+
+// trigger sends an event at start+at. Events without a named handler are
+// still seen by the tracker, which listens to all of them
+func trigger(d *dispatcher.Dispatcher, at time.Duration, name, fields string) {
+	b := []byte(`{ "timestamp":"` + start.Add(at).Format(time.RFC3339) + `", "event":"` + name + `"` + fields + ` }`)
+	_ = d.Trigger(name, b)
+}
+
+func TestSessionBoundaries(t *testing.T) {
+	d := dispatcher.NewDispatcher()
+	tr := NewTracker(nil, "Status", "FlightStateChanged")
+	tr.AddListeners(d)
+	ended := 0
+	d.OnSync(SessionEnded, func(b []byte) {
+		var e SessionEndedEvent
+		if json.Unmarshal(b, &e) != nil || e.Session == nil {
+			t.Errorf("bad SessionEnded event %s", b)
+		}
+		ended++
+	})
+
+	// a session shut down from the game
+	trigger(d, 0, "Fileheader", `, "part":1, "gameversion":"4.0.0.100"`)
+	trigger(d, time.Minute, "LoadGame", `, "Commander":"Bob", "Ship":"Python", "ShipID":1, "GameMode":"Open", "Credits":1000`)
+	trigger(d, 2*time.Minute, "Status", `, "Flags":16842765`)
+	trigger(d, 10*time.Minute, "FSDJump", `, "StarSystem":"Sol", "SystemAddress":10477373803`)
+	trigger(d, 10*time.Minute, "FlightStateChanged", `, "From":"Docked", "To":"Supercruise"`)
+	trigger(d, time.Hour, "Shutdown", ``)
+	if _, ok := tr.Current(); ok {
+		t.Error("session still running after Shutdown")
+	}
+
+	// a session continued in a second part, then loaded again
+	trigger(d, 24*time.Hour, "Fileheader", `, "part":1`)
+	trigger(d, 24*time.Hour+time.Minute, "LoadGame", `, "Commander":"Bob", "Ship":"Python", "ShipID":1, "GameMode":"Solo", "Credits":1000`)
+	trigger(d, 25*time.Hour, "Fileheader", `, "part":2`)
+	trigger(d, 25*time.Hour+time.Minute, "LoadGame", `, "Commander":"Bob", "Ship":"Python", "ShipID":1, "GameMode":"Open", "Credits":1500`)
+
+	// the session running when the journal ends
+	trigger(d, 26*time.Hour, "Status", `, "Flags":0`)
+	tr.Close()
+
+	sessions := tr.Sessions()
+	if len(sessions) != 3 || ended != 3 {
+		t.Fatalf("got %d sessions and %d SessionEnded events, want 3", len(sessions), ended)
+	}
+
+	s := sessions[0]
+	if !s.Start.Equal(start) || s.Duration != time.Hour || !s.Shutdown || s.GameVersion != "4.0.0.100" {
+		t.Errorf("first session %+v, want one hour, shut down", s)
+	}
+	if !reflect.DeepEqual(s.Ships, []string{"python (1)"}) || !reflect.DeepEqual(s.Systems, []string{"Sol"}) {
+		t.Errorf("first session ships %v and systems %v", s.Ships, s.Systems)
+	}
+	counts := map[string]int{"Fileheader": 1, "LoadGame": 1, "FSDJump": 1, "Shutdown": 1}
+	if s.Events != 4 || !reflect.DeepEqual(s.EventCounts, counts) {
+		t.Errorf("first session counted %d events %v, want journal events only", s.Events, s.EventCounts)
+	}
+
+	s = sessions[1]
+	if !s.Start.Equal(start.Add(24*time.Hour)) || s.Shutdown || s.GameMode != "Solo" || s.EventCounts["Fileheader"] != 2 {
+		t.Errorf("second session %+v, want both parts of the journal", s)
+	}
+	if s.CreditsStart != 1000 || s.CreditsEnd != 1500 || s.CreditsDelta != 500 {
+		t.Errorf("second session credits %d to %d (%d), want 1000 to 1500", s.CreditsStart, s.CreditsEnd, s.CreditsDelta)
+	}
+
+	s = sessions[2]
+	if s.Events != 1 || !s.End.Equal(start.Add(25*time.Hour+time.Minute)) || s.CreditsDelta != 0 {
+		t.Errorf("last session %+v, want it to end with its LoadGame", s)
+	}
+}
+
+func TestSessionCredits(t *testing.T) {
+	d := dispatcher.NewDispatcher()
+	ledger := credits.NewLedger()
+	ledger.AddListeners(d)
+	tr := NewTracker(ledger)
+	tr.AddListeners(d)
+
+	trigger(d, 0, "Fileheader", `, "part":1`)
+	trigger(d, time.Minute, "LoadGame", `, "Commander":"Bob", "Credits":1000`)
+	trigger(d, 10*time.Minute, "MarketSell", `, "MarketID":1, "Type":"gold", "Count":10, "SellPrice":50, "TotalSale":500, "AvgPricePaid":40`)
+	trigger(d, 20*time.Minute, "MarketBuy", `, "MarketID":1, "Type":"food", "Count":10, "BuyPrice":20, "TotalCost":200`)
+	trigger(d, time.Hour, "Shutdown", ``)
+
+	sessions := tr.Sessions()
+	if len(sessions) != 1 {
+		t.Fatalf("got %d sessions, want 1", len(sessions))
+	}
+	s := sessions[0]
+	if s.CreditsStart != 1000 || s.CreditsEnd != 1300 || s.CreditsDelta != 300 {
+		t.Errorf("credits %d to %d (%d), want 1000 to 1300", s.CreditsStart, s.CreditsEnd, s.CreditsDelta)
+	}
+}
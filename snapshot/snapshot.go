@@ -0,0 +1,184 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sht/ed-journal/event"
+)
+
+// Version is the version of the snapshot file format
+const Version = 1
+
+// Snapshotter is implemented by trackers whose state can be saved and
+// restored
+type Snapshotter interface {
+	// Snapshot returns the state of the tracker
+	Snapshot() (json.RawMessage, error)
+	// Restore replaces the state of the tracker with a snapshot
+	Restore(b json.RawMessage) error
+}
+
+// Component is the saved state of a tracker along with the version of its
+// state format
+type Component struct {
+	Version int             `json:"version"`
+	State   json.RawMessage `json:"state"`
+}
+
+// Snapshot is the state of the trackers right after the line the cursor
+// points at
+type Snapshot struct {
+	Version    int                   `json:"version"`
+	Taken      time.Time             `json:"taken"`
+	Cursor     event.Cursor          `json:"cursor"`
+	Components map[string]*Component `json:"components"`
+}
+
+type registered struct {
+	version int
+	s       Snapshotter
+}
+
+// Manager writes snapshots of the registered trackers to a file and restores
+// them on startup. It is safe for concurrent use
+type Manager struct {
+	mu         sync.Mutex
+	path       string
+	interval   time.Duration
+	components map[string]registered
+	last       time.Time
+}
+
+// NewManager returns a manager keeping its snapshot at path and writing it at
+// most once every interval from Checkpoint
+func NewManager(path string, interval time.Duration) *Manager {
+	return &Manager{
+		path:       path,
+		interval:   interval,
+		components: make(map[string]registered),
+	}
+}
+
+// Register adds a tracker to the snapshots under name. The version must be
+// bumped whenever the tracker's state format changes, so that older
+// snapshots are not restored into it
+func (m *Manager) Register(name string, version int, s Snapshotter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.components[name] = registered{version: version, s: s}
+}
+
+// Write snapshots the trackers at cursor c. No events must be handled while
+// it runs, or the snapshot would not match the cursor
+func (m *Manager) Write(c event.Cursor) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.write(c)
+}
+
+// Checkpoint writes a snapshot at cursor c when the interval has passed
+// since the last one. It is meant to be called by the watcher between
+// batches of lines
+func (m *Manager) Checkpoint(c event.Cursor) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if time.Since(m.last) < m.interval {
+		return nil
+	}
+	return m.write(c)
+}
+
+// write must be called with the lock held
+func (m *Manager) write(c event.Cursor) error {
+	snap := Snapshot{
+		Version:    Version,
+		Taken:      time.Now().UTC(),
+		Cursor:     c,
+		Components: make(map[string]*Component, len(m.components)),
+	}
+	for name, r := range m.components {
+		b, err := r.s.Snapshot()
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		snap.Components[name] = &Component{Version: r.version, State: b}
+	}
+
+	b, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	tmp := m.path + ".tmp"
+	err = ioutil.WriteFile(tmp, b, 0644)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp, m.path)
+	if err != nil {
+		return err
+	}
+	m.last = time.Now()
+	return nil
+}
+
+// Load restores the trackers from the snapshot and returns its cursor, from
+// which the journal must be replayed. It reports false, restoring nothing,
+// when there is no snapshot or it is not compatible with the registered
+// trackers, in which case the journal must be replayed in full
+func (m *Manager) Load() (event.Cursor, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, err := ioutil.ReadFile(m.path)
+	if os.IsNotExist(err) {
+		return event.Cursor{}, false, nil
+	}
+	if err != nil {
+		return event.Cursor{}, false, err
+	}
+
+	var snap Snapshot
+	err = json.Unmarshal(b, &snap)
+	if err != nil || !m.compatible(&snap) {
+		return event.Cursor{}, false, nil
+	}
+
+	// restore in a stable order so failures are reproducible
+	names := make([]string, 0, len(m.components))
+	for name := range m.components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		err = m.components[name].s.Restore(snap.Components[name].State)
+		if err != nil {
+			return event.Cursor{}, false, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	m.last = time.Now()
+	return snap.Cursor, true, nil
+}
+
+// compatible reports whether the snapshot can be restored into every
+// registered tracker. Must be called with the lock held
+func (m *Manager) compatible(snap *Snapshot) bool {
+	if snap.Version != Version || snap.Cursor.IsZero() {
+		return false
+	}
+	for name, r := range m.components {
+		c, ok := snap.Components[name]
+		if !ok || c.Version != r.version {
+			return false
+		}
+	}
+	return true
+}
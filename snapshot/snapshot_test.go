@@ -0,0 +1,174 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sht/ed-journal/event"
+)
+
+// counter is a tracker whose state is a single number
+type counter struct {
+	n    int
+	fail bool
+}
+
+func (c *counter) Snapshot() (json.RawMessage, error) {
+	return json.Marshal(c.n)
+}
+
+func (c *counter) Restore(b json.RawMessage) error {
+	if c.fail {
+		return errors.New("restore failed")
+	}
+	return json.Unmarshal(b, &c.n)
+}
+
+var testCursor = event.Cursor{
+	File:      "Journal.2021-01-01T000000.01.log",
+	Offset:    42,
+	Timestamp: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+	Hash:      "abc",
+}
+
+// write saves a snapshot of two counters at testCursor
+func write(t *testing.T, path string) {
+	t.Helper()
+
+	m := NewManager(path, time.Hour)
+	m.Register("a", 1, &counter{n: 1})
+	m.Register("b", 1, &counter{n: 2})
+	err := m.Write(testCursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	write(t, path)
+
+	a, b := &counter{}, &counter{}
+	m := NewManager(path, time.Hour)
+	m.Register("a", 1, a)
+	m.Register("b", 1, b)
+	c, ok, err := m.Load()
+	if err != nil || !ok || c != testCursor {
+		t.Fatalf("Load() = %+v, %v, %v, want %+v", c, ok, err, testCursor)
+	}
+	if a.n != 1 || b.n != 2 {
+		t.Errorf("restored %d and %d, want 1 and 2", a.n, b.n)
+	}
+}
+
+func TestLoadIncompatible(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "snapshot.json")
+	write(t, path)
+
+	tests := []struct {
+		name     string
+		register map[string]int
+	}{
+		{"component version bumped", map[string]int{"a": 1, "b": 2}},
+		{"component added", map[string]int{"a": 1, "b": 1, "c": 1}},
+	}
+	for _, tt := range tests {
+		m := NewManager(path, time.Hour)
+		counters := make(map[string]*counter)
+		for name, version := range tt.register {
+			counters[name] = &counter{n: -1}
+			m.Register(name, version, counters[name])
+		}
+		_, ok, err := m.Load()
+		if ok || err != nil {
+			t.Errorf("%s: Load() = %v, %v, want a full replay", tt.name, ok, err)
+		}
+		for name, c := range counters {
+			if c.n != -1 {
+				t.Errorf("%s: %s restored", tt.name, name)
+			}
+		}
+	}
+
+	// a snapshot in an older file format is not restored either
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snap Snapshot
+	err = json.Unmarshal(b, &snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap.Version = Version - 1
+	b, err = json.Marshal(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(path, b, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(path, time.Hour)
+	m.Register("a", 1, &counter{})
+	m.Register("b", 1, &counter{})
+	_, ok, err := m.Load()
+	if ok || err != nil {
+		t.Errorf("Load() of an older format = %v, %v, want a full replay", ok, err)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	m := NewManager(filepath.Join(t.TempDir(), "snapshot.json"), time.Hour)
+	m.Register("a", 1, &counter{})
+	_, ok, err := m.Load()
+	if ok || err != nil {
+		t.Errorf("Load() without a snapshot = %v, %v, want a full replay", ok, err)
+	}
+}
+
+func TestLoadRestoreError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	write(t, path)
+
+	m := NewManager(path, time.Hour)
+	m.Register("a", 1, &counter{})
+	m.Register("b", 1, &counter{fail: true})
+	_, ok, err := m.Load()
+	if ok || err == nil {
+		t.Errorf("Load() = %v, %v, want an error", ok, err)
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	a := &counter{n: 1}
+	m := NewManager(path, time.Hour)
+	m.Register("a", 1, a)
+	err := m.Checkpoint(testCursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the next checkpoint within the interval is skipped
+	a.n = 2
+	next := testCursor
+	next.Offset++
+	err = m.Checkpoint(next)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := &counter{}
+	m = NewManager(path, time.Hour)
+	m.Register("a", 1, restored)
+	c, ok, err := m.Load()
+	if err != nil || !ok || c != testCursor || restored.n != 1 {
+		t.Errorf("Load() = %+v, %v, %v with %d, want the first checkpoint", c, ok, err, restored.n)
+	}
+}
@@ -0,0 +1,87 @@
+package state
+
+import (
+	"time"
+
+	"github.com/sht/ed-journal/events"
+)
+
+// State is a point-in-time view of the commander, aggregated from the journal
+type State struct {
+	Commander  string     `json:"commander"`
+	FID        string     `json:"fid"`
+	GameMode   string     `json:"gameMode,omitempty"`
+	Group      string     `json:"group,omitempty"`
+	Horizons   bool       `json:"horizons"`
+	Credits    int        `json:"credits"`
+	Loan       int        `json:"loan"`
+	Location   Location   `json:"location"`
+	Ship       Ship       `json:"ship"`
+	Rank       Ranks      `json:"rank"`
+	Progress   Ranks      `json:"progress"`
+	Reputation Reputation `json:"reputation"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+// Location describes where the commander currently is
+type Location struct {
+	StarSystem    string    `json:"starSystem"`
+	SystemAddress int       `json:"systemAddress"`
+	StarPos       []float64 `json:"starPos,omitempty"`
+	Body          string    `json:"body,omitempty"`
+	BodyID        int       `json:"bodyId,omitempty"`
+	BodyType      string    `json:"bodyType,omitempty"`
+	Station       string    `json:"station,omitempty"`
+	StationType   string    `json:"stationType,omitempty"`
+	MarketID      int       `json:"marketId,omitempty"`
+	Docked        bool      `json:"docked"`
+	Landed        bool      `json:"landed"`
+	Supercruise   bool      `json:"supercruise"`
+}
+
+// Ship describes the ship the commander is currently flying. Loadout is the
+// last loadout event received for the ship and must not be modified
+type Ship struct {
+	ID            int                  `json:"id"`
+	Type          string               `json:"type"`
+	Name          string               `json:"name,omitempty"`
+	Ident         string               `json:"ident,omitempty"`
+	FuelLevel     float64              `json:"fuelLevel"`
+	FuelCapacity  float64              `json:"fuelCapacity"`
+	CargoCapacity int                  `json:"cargoCapacity"`
+	MaxJumpRange  float64              `json:"maxJumpRange"`
+	HullValue     int                  `json:"hullValue"`
+	ModulesValue  int                  `json:"modulesValue"`
+	Rebuy         int                  `json:"rebuy"`
+	Loadout       *events.LoadoutEvent `json:"loadout,omitempty"`
+}
+
+// Ranks holds a value (rank or progress percentage) for each rank category
+type Ranks struct {
+	Combat       int `json:"combat"`
+	Trade        int `json:"trade"`
+	Explore      int `json:"explore"`
+	Empire       int `json:"empire"`
+	Federation   int `json:"federation"`
+	CQC          int `json:"cqc"`
+	Soldier      int `json:"soldier"`
+	Exobiologist int `json:"exobiologist"`
+}
+
+// Reputation holds the commander's reputation with the major superpowers
+type Reputation struct {
+	Empire      float64 `json:"empire"`
+	Federation  float64 `json:"federation"`
+	Independent float64 `json:"independent"`
+	Alliance    float64 `json:"alliance"`
+}
+
+// copy returns a copy of the state that does not share mutable memory
+func (s State) copy() State {
+	if s.Location.StarPos != nil {
+		pos := make([]float64, len(s.Location.StarPos))
+		copy(pos, s.Location.StarPos)
+		s.Location.StarPos = pos
+	}
+	return s
+}
@@ -0,0 +1,423 @@
+package state
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/sht/ed-journal/credits"
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/events"
+)
+
+// Tracker keeps the commander state up to date from journal events. It is
+// safe for concurrent use
+type Tracker struct {
+	mu     sync.RWMutex
+	ledger *credits.Ledger
+	state  State
+}
+
+// NewTracker returns a state tracker. When ledger is not nil, the credits are
+// kept up to date from its running balance, otherwise they are only updated
+// on LoadGame
+func NewTracker(ledger *credits.Ledger) *Tracker {
+	return &Tracker{
+		ledger: ledger,
+	}
+}
+
+// State returns a snapshot of the current state
+func (t *Tracker) State() State {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	s := t.state.copy()
+	if t.ledger != nil {
+		if balance, ok := t.ledger.Balance(); ok {
+			s.Credits = balance
+		}
+	}
+	return s
+}
+
+// SnapshotVersion is the version of the state format saved by Snapshot
+const SnapshotVersion = 1
+
+// Snapshot returns the current state encoded for a snapshot
+func (t *Tracker) Snapshot() (json.RawMessage, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return json.Marshal(t.state)
+}
+
+// Restore replaces the current state with one saved by Snapshot
+func (t *Tracker) Restore(b json.RawMessage) error {
+	var s State
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.state = s
+	return nil
+}
+
+// AddListeners subscribes the tracker to the events it aggregates
+func (t *Tracker) AddListeners(d *dispatcher.Dispatcher) {
+	// startup
+	{
+		d.OnSync(events.ClearSavedGame, t.clearSavedGame)
+		d.OnSync(events.Commander, t.commander)
+		d.OnSync(events.NewCommander, t.newCommander)
+		d.OnSync(events.LoadGame, t.loadGame)
+		d.OnSync(events.Loadout, t.loadout)
+		d.OnSync(events.Progress, t.progress)
+		d.OnSync(events.Rank, t.rank)
+		d.OnSync(events.Reputation, t.reputation)
+	}
+
+	// travel
+	{
+		d.OnSync(events.ApproachBody, t.approachBody)
+		d.OnSync(events.Docked, t.docked)
+		d.OnSync(events.FSDJump, t.fsdJump)
+		d.OnSync(events.LeaveBody, t.leaveBody)
+		d.OnSync(events.Liftoff, t.liftoff)
+		d.OnSync(events.Location, t.location)
+		d.OnSync(events.SupercruiseEntry, t.supercruiseEntry)
+		d.OnSync(events.SupercruiseExit, t.supercruiseExit)
+		d.OnSync(events.Touchdown, t.touchdown)
+		d.OnSync(events.Undocked, t.undocked)
+	}
+
+	// other
+	{
+		d.OnSync(events.Promotion, t.promotion)
+	}
+}
+
+// update decodes b into e and applies fn to the state while holding the lock
+func (t *Tracker) update(b []byte, e interface{}, fn func(s *State)) {
+	err := json.Unmarshal(b, e)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fn(&t.state)
+}
+
+// intValue returns the value of an optional journal field, or 0 when absent
+func intValue(i *int) int {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
+
+func (t *Tracker) clearSavedGame(b []byte) {
+	var e events.ClearSavedGameEvent
+	t.update(b, &e, func(s *State) {
+		*s = State{
+			Commander: e.Name,
+			FID:       e.FID,
+			UpdatedAt: e.Timestamp,
+		}
+	})
+}
+
+func (t *Tracker) commander(b []byte) {
+	var e events.CommanderEvent
+	t.update(b, &e, func(s *State) {
+		if s.FID != "" && s.FID != e.FID {
+			*s = State{}
+		}
+		s.Commander = e.Name
+		s.FID = e.FID
+		s.UpdatedAt = e.Timestamp
+	})
+}
+
+func (t *Tracker) newCommander(b []byte) {
+	var e events.NewCommanderEvent
+	t.update(b, &e, func(s *State) {
+		*s = State{
+			Commander: e.Name,
+			FID:       e.FID,
+			UpdatedAt: e.Timestamp,
+		}
+	})
+}
+
+func (t *Tracker) loadGame(b []byte) {
+	var e events.LoadGameEvent
+	t.update(b, &e, func(s *State) {
+		if s.FID != "" && s.FID != e.FID {
+			*s = State{}
+		}
+		s.Commander = e.Commander
+		s.FID = e.FID
+		s.GameMode = e.GameMode
+		s.Group = e.Group
+		s.Horizons = e.Horizons
+		s.Credits = e.Credits
+		s.Loan = e.Loan
+		s.Location.Landed = e.StartLanded
+		s.UpdatedAt = e.Timestamp
+
+		if e.ShipID == nil {
+			return
+		}
+		if s.Ship.ID != *e.ShipID || !strings.EqualFold(s.Ship.Type, e.Ship) {
+			s.Ship = Ship{}
+		}
+		s.Ship.ID = *e.ShipID
+		s.Ship.Type = e.Ship
+		if e.ShipName != nil {
+			s.Ship.Name = *e.ShipName
+		}
+		if e.ShipIdent != nil {
+			s.Ship.Ident = *e.ShipIdent
+		}
+		if e.FuelLevel != nil {
+			s.Ship.FuelLevel = *e.FuelLevel
+		}
+		if e.FuelCapacity != nil {
+			s.Ship.FuelCapacity = *e.FuelCapacity
+		}
+	})
+}
+
+func (t *Tracker) loadout(b []byte) {
+	e := new(events.LoadoutEvent)
+	t.update(b, e, func(s *State) {
+		s.Ship.ID = e.ShipID
+		s.Ship.Type = e.Ship
+		s.Ship.Name = e.ShipName
+		s.Ship.Ident = e.ShipIdent
+		s.Ship.FuelCapacity = e.FuelCapacity.Main
+		s.Ship.CargoCapacity = e.CargoCapacity
+		s.Ship.MaxJumpRange = e.MaxJumpRange
+		s.Ship.Rebuy = e.Rebuy
+		if e.HullValue != nil {
+			s.Ship.HullValue = *e.HullValue
+		}
+		if e.ModulesValue != nil {
+			s.Ship.ModulesValue = *e.ModulesValue
+		}
+		s.Ship.Loadout = e
+		s.UpdatedAt = e.Timestamp
+	})
+}
+
+func (t *Tracker) progress(b []byte) {
+	var e events.ProgressEvent
+	t.update(b, &e, func(s *State) {
+		s.Progress = Ranks{
+			Combat:       e.Combat,
+			Trade:        e.Trade,
+			Explore:      e.Explore,
+			Empire:       e.Empire,
+			Federation:   e.Federation,
+			CQC:          e.CQC,
+			Soldier:      intValue(e.Soldier),
+			Exobiologist: intValue(e.Exobiologist),
+		}
+		s.UpdatedAt = e.Timestamp
+	})
+}
+
+func (t *Tracker) promotion(b []byte) {
+	var e events.PromotionEvent
+	t.update(b, &e, func(s *State) {
+		promote := func(rank, progress *int, to *int) {
+			if to != nil {
+				*rank = *to
+				*progress = 0
+			}
+		}
+		promote(&s.Rank.Combat, &s.Progress.Combat, e.Combat)
+		promote(&s.Rank.Trade, &s.Progress.Trade, e.Trade)
+		promote(&s.Rank.Explore, &s.Progress.Explore, e.Explore)
+		promote(&s.Rank.Empire, &s.Progress.Empire, e.Empire)
+		promote(&s.Rank.Federation, &s.Progress.Federation, e.Federation)
+		promote(&s.Rank.CQC, &s.Progress.CQC, e.CQC)
+		promote(&s.Rank.Soldier, &s.Progress.Soldier, e.Soldier)
+		promote(&s.Rank.Exobiologist, &s.Progress.Exobiologist, e.Exobiologist)
+		s.UpdatedAt = e.Timestamp
+	})
+}
+
+func (t *Tracker) rank(b []byte) {
+	var e events.RankEvent
+	t.update(b, &e, func(s *State) {
+		s.Rank = Ranks{
+			Combat:       e.Combat,
+			Trade:        e.Trade,
+			Explore:      e.Explore,
+			Empire:       e.Empire,
+			Federation:   e.Federation,
+			CQC:          e.CQC,
+			Soldier:      intValue(e.Soldier),
+			Exobiologist: intValue(e.Exobiologist),
+		}
+		s.UpdatedAt = e.Timestamp
+	})
+}
+
+func (t *Tracker) reputation(b []byte) {
+	var e events.ReputationEvent
+	t.update(b, &e, func(s *State) {
+		s.Reputation.Empire = e.Empire
+		s.Reputation.Federation = e.Federation
+		s.Reputation.Alliance = e.Alliance
+		if e.Independent != nil {
+			s.Reputation.Independent = *e.Independent
+		}
+		s.UpdatedAt = e.Timestamp
+	})
+}
+
+func (t *Tracker) approachBody(b []byte) {
+	var e events.ApproachBodyEvent
+	t.update(b, &e, func(s *State) {
+		s.Location.StarSystem = e.StarSystem
+		s.Location.SystemAddress = e.SystemAddress
+		s.Location.Body = e.Body
+		s.Location.BodyID = e.BodyID
+		s.Location.BodyType = ""
+		s.UpdatedAt = e.Timestamp
+	})
+}
+
+func (t *Tracker) docked(b []byte) {
+	var e events.DockedEvent
+	t.update(b, &e, func(s *State) {
+		s.Location.StarSystem = e.StarSystem
+		s.Location.SystemAddress = e.SystemAddress
+		s.Location.Station = e.StationName
+		s.Location.StationType = e.StationType
+		s.Location.MarketID = e.MarketID
+		s.Location.Docked = true
+		s.Location.Landed = false
+		s.Location.Supercruise = false
+		s.UpdatedAt = e.Timestamp
+	})
+}
+
+func (t *Tracker) fsdJump(b []byte) {
+	var e events.FSDJumpEvent
+	t.update(b, &e, func(s *State) {
+		s.Location = Location{
+			StarSystem:    e.StarSystem,
+			SystemAddress: e.SystemAddress,
+			StarPos:       e.StarPos,
+			Body:          e.Body,
+			BodyID:        e.BodyID,
+			BodyType:      e.BodyType,
+			Supercruise:   true,
+		}
+		s.Ship.FuelLevel = e.FuelLevel
+		s.UpdatedAt = e.Timestamp
+	})
+}
+
+func (t *Tracker) leaveBody(b []byte) {
+	var e events.LeaveBodyEvent
+	t.update(b, &e, func(s *State) {
+		s.Location.Body = ""
+		s.Location.BodyID = 0
+		s.Location.BodyType = ""
+		s.UpdatedAt = e.Timestamp
+	})
+}
+
+func (t *Tracker) liftoff(b []byte) {
+	var e events.LiftoffEvent
+	t.update(b, &e, func(s *State) {
+		if !e.PlayerControlled {
+			return
+		}
+		s.Location.Landed = false
+		s.UpdatedAt = e.Timestamp
+	})
+}
+
+func (t *Tracker) location(b []byte) {
+	var e events.LocationEvent
+	t.update(b, &e, func(s *State) {
+		s.Location = Location{
+			StarSystem:    e.StarSystem,
+			SystemAddress: e.SystemAddress,
+			StarPos:       e.StarPos,
+			Body:          e.Body,
+			BodyID:        e.BodyID,
+			BodyType:      e.BodyType,
+			Docked:        e.Docked,
+			Landed:        s.Location.Landed && !e.Docked,
+		}
+		if e.Docked {
+			s.Location.Station = e.StationName
+			s.Location.StationType = e.StationType
+			s.Location.MarketID = e.MarketID
+		}
+		s.UpdatedAt = e.Timestamp
+	})
+}
+
+func (t *Tracker) supercruiseEntry(b []byte) {
+	var e events.SupercruiseEntryEvent
+	t.update(b, &e, func(s *State) {
+		s.Location.StarSystem = e.StarSystem
+		s.Location.SystemAddress = e.SystemAddress
+		s.Location.Station = ""
+		s.Location.StationType = ""
+		s.Location.MarketID = 0
+		s.Location.Docked = false
+		s.Location.Landed = false
+		s.Location.Supercruise = true
+		s.UpdatedAt = e.Timestamp
+	})
+}
+
+func (t *Tracker) supercruiseExit(b []byte) {
+	var e events.SupercruiseExitEvent
+	t.update(b, &e, func(s *State) {
+		s.Location.StarSystem = e.StarSystem
+		s.Location.SystemAddress = e.SystemAddress
+		s.Location.Body = e.Body
+		s.Location.BodyID = e.BodyID
+		s.Location.BodyType = e.BodyType
+		s.Location.Supercruise = false
+		s.UpdatedAt = e.Timestamp
+	})
+}
+
+func (t *Tracker) touchdown(b []byte) {
+	var e events.TouchdownEvent
+	t.update(b, &e, func(s *State) {
+		if !e.PlayerControlled {
+			return
+		}
+		s.Location.Landed = true
+		s.UpdatedAt = e.Timestamp
+	})
+}
+
+func (t *Tracker) undocked(b []byte) {
+	var e events.UndockedEvent
+	t.update(b, &e, func(s *State) {
+		s.Location.Station = ""
+		s.Location.StationType = ""
+		s.Location.MarketID = 0
+		s.Location.Docked = false
+		s.UpdatedAt = e.Timestamp
+	})
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/sht/ed-journal/credits"
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/event"
+	"github.com/sht/ed-journal/importer"
+	"github.com/sht/ed-journal/travel"
+)
+
+// eventCount is the number of times an event was written
+type eventCount struct {
+	Event string `json:"event"`
+	Count int    `json:"count"`
+}
+
+// archiveStats is what stats prints
+type archiveStats struct {
+	Files             int                      `json:"files"`
+	Events            int                      `json:"events"`
+	BadLines          int                      `json:"badLines"`
+	From              time.Time                `json:"from"`
+	To                time.Time                `json:"to"`
+	Sessions          int                      `json:"sessions"`
+	PlayTime          time.Duration            `json:"playTime"`
+	Travel            travel.Stats             `json:"travel"`
+	Credits           map[credits.Category]int `json:"credits"`
+	Discrepancies     []credits.Discrepancy    `json:"discrepancies"`
+	ExplorationUnsold int                      `json:"explorationUnsold"`
+	EventCounts       []eventCount             `json:"eventCounts"`
+}
+
+func statsCmd(args []string) int {
+	fs := newFlagSet("stats")
+	dir := dirFlag(fs)
+	format := formatFlag(fs, "table")
+	top := fs.Int("top", 20, "number of most frequent events to list, 0 for all")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, fmt.Errorf("unexpected argument %q", fs.Arg(0)))
+	}
+	if *format != "json" && *format != "table" {
+		return usageError(fs, fmt.Errorf("unknown format %q", *format))
+	}
+
+	d := dispatcher.NewDispatcher()
+	t := newTrackers(*dir)
+	t.addListeners(d)
+
+	var st archiveStats
+	counts := make(map[string]int)
+	d.OnAllSync(func(b []byte) {
+		var e event.Event
+		if json.Unmarshal(b, &e) != nil {
+			return
+		}
+		counts[e.Event]++
+		if st.From.IsZero() || e.Timestamp.Before(st.From) {
+			st.From = e.Timestamp
+		}
+		if e.Timestamp.After(st.To) {
+			st.To = e.Timestamp
+		}
+	})
+
+	results, err := importer.NewImporter(d).ImportDir(*dir)
+	if err != nil {
+		return fail(err)
+	}
+	t.sessions.Close()
+
+	code := exitOK
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", r.Path, r.Err)
+			code = exitError
+			continue
+		}
+		st.Files++
+		st.Events += r.Events
+		st.BadLines += len(r.Errors)
+	}
+	for _, s := range t.sessions.Sessions() {
+		st.Sessions++
+		st.PlayTime += s.Duration
+	}
+	st.Travel = t.travel.Lifetime()
+	st.Credits = t.credits.Totals(time.Time{}, time.Time{})
+	st.Discrepancies = t.credits.Discrepancies()
+	st.ExplorationUnsold = t.exploration.Unsold()
+
+	// synthetic events are not part of the journal
+	for _, name := range syntheticEvents {
+		delete(counts, name)
+	}
+	st.EventCounts = make([]eventCount, 0, len(counts))
+	for name, n := range counts {
+		st.EventCounts = append(st.EventCounts, eventCount{Event: name, Count: n})
+	}
+	sort.Slice(st.EventCounts, func(i, j int) bool {
+		a, b := st.EventCounts[i], st.EventCounts[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Event < b.Event
+	})
+	if *top > 0 && len(st.EventCounts) > *top {
+		st.EventCounts = st.EventCounts[:*top]
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(st)
+		if err != nil {
+			return fail(err)
+		}
+		return code
+	}
+
+	rows := [][2]interface{}{
+		{"files", st.Files},
+		{"events", st.Events},
+		{"bad lines", st.BadLines},
+		{"from", st.From},
+		{"to", st.To},
+		{"sessions", st.Sessions},
+		{"play time", st.PlayTime.Round(time.Minute).String()},
+		{"jumps", st.Travel.Jumps},
+		{"distance", st.Travel.Distance},
+		{"unique systems", st.Travel.UniqueSystems},
+		{"exploration unsold", st.ExplorationUnsold},
+	}
+	categories := make([]string, 0, len(st.Credits))
+	for c := range st.Credits {
+		categories = append(categories, string(c))
+	}
+	sort.Strings(categories)
+	for _, c := range categories {
+		rows = append(rows, [2]interface{}{"credits " + c, st.Credits[credits.Category(c)]})
+	}
+	unexplained := 0
+	for _, d := range st.Discrepancies {
+		unexplained += d.Difference
+	}
+	rows = append(rows,
+		[2]interface{}{"credit discrepancies", len(st.Discrepancies)},
+		[2]interface{}{"credits unexplained", unexplained},
+	)
+	for _, c := range st.EventCounts {
+		rows = append(rows, [2]interface{}{"event " + c.Event, c.Count})
+	}
+	err = writeRows(rows)
+	if err != nil {
+		return fail(err)
+	}
+	return code
+}
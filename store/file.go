@@ -0,0 +1,397 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	logFile   = "events.log"
+	indexFile = "events.idx"
+)
+
+// ErrClosed is returned when using a store after Close
+var ErrClosed = errors.New("store is closed")
+
+// entry is the index entry of a record: where it is in the log along with the
+// fields queries filter on
+type entry struct {
+	ID        uint64    `json:"id"`
+	Offset    int64     `json:"off"`
+	Length    int       `json:"len"`
+	Event     string    `json:"event"`
+	Timestamp time.Time `json:"ts"`
+	Commander string    `json:"cmdr,omitempty"`
+}
+
+func (e *entry) record() *Record {
+	return &Record{
+		ID:        e.ID,
+		Event:     e.Event,
+		Timestamp: e.Timestamp,
+		Commander: e.Commander,
+	}
+}
+
+// FileStore is a Store keeping records as JSON lines in an append-only log,
+// with an index of every record kept in memory and in a second file. The
+// index is rebuilt from the log when it is missing or behind
+type FileStore struct {
+	mu      sync.RWMutex
+	log     *os.File
+	index   *os.File
+	size    int64
+	entries []entry
+	byEvent map[string][]int
+	closed  bool
+}
+
+// OpenFileStore opens the store in dir, creating it if needed
+func OpenFileStore(dir string) (*FileStore, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	log, err := os.OpenFile(filepath.Join(dir, logFile), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	index, err := os.OpenFile(filepath.Join(dir, indexFile), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		log.Close()
+		return nil, err
+	}
+
+	s := &FileStore{
+		log:     log,
+		index:   index,
+		entries: make([]entry, 0),
+		byEvent: make(map[string][]int),
+	}
+	err = s.load()
+	if err != nil {
+		log.Close()
+		index.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads the index and indexes the records in the log past its end
+func (s *FileStore) load() error {
+	sc := bufio.NewScanner(s.index)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	var next int64
+	for sc.Scan() {
+		var e entry
+		err := json.Unmarshal(sc.Bytes(), &e)
+		if err != nil || e.Offset != next {
+			// a torn or inconsistent index is rebuilt from the log
+			break
+		}
+		s.add(e)
+		next = e.Offset + int64(e.Length)
+	}
+
+	info, err := s.log.Stat()
+	if err != nil {
+		return err
+	}
+	s.size = info.Size()
+	if next > s.size {
+		s.entries = s.entries[:0]
+		s.byEvent = make(map[string][]int)
+		next = 0
+	}
+
+	// rewrite the index from the entries read so far and catch up with
+	// the log
+	err = s.index.Truncate(0)
+	if err != nil {
+		return err
+	}
+	_, err = s.index.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(s.index)
+	for _, e := range s.entries {
+		err = writeEntry(w, e)
+		if err != nil {
+			return err
+		}
+	}
+
+	r := bufio.NewReader(io.NewSectionReader(s.log, next, s.size-next))
+	offset := next
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// drop a partly written last record
+			if len(line) > 0 {
+				s.size = offset
+				err = s.log.Truncate(offset)
+				if err != nil {
+					return err
+				}
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		var rec Record
+		err = json.Unmarshal(line, &rec)
+		if err != nil {
+			return fmt.Errorf("corrupt record at offset %d: %w", offset, err)
+		}
+		e := entry{
+			ID:        rec.ID,
+			Offset:    offset,
+			Length:    len(line),
+			Event:     rec.Event,
+			Timestamp: rec.Timestamp,
+			Commander: rec.Commander,
+		}
+		s.add(e)
+		err = writeEntry(w, e)
+		if err != nil {
+			return err
+		}
+		offset += int64(len(line))
+	}
+	return w.Flush()
+}
+
+// add indexes an entry in memory
+func (s *FileStore) add(e entry) {
+	s.byEvent[e.Event] = append(s.byEvent[e.Event], len(s.entries))
+	s.entries = append(s.entries, e)
+}
+
+func writeEntry(w io.Writer, e entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// Append stores the record and sets its ID
+func (s *FileStore) Append(r *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	id := uint64(1)
+	if n := len(s.entries); n > 0 {
+		id = s.entries[n-1].ID + 1
+	}
+	rec := *r
+	rec.ID = id
+	// the record must fit on a single line
+	if len(rec.Data) > 0 {
+		var buf bytes.Buffer
+		err := json.Compact(&buf, rec.Data)
+		if err != nil {
+			return err
+		}
+		rec.Data = buf.Bytes()
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	_, err = s.log.WriteAt(b, s.size)
+	if err != nil {
+		return err
+	}
+	e := entry{
+		ID:        id,
+		Offset:    s.size,
+		Length:    len(b),
+		Event:     rec.Event,
+		Timestamp: rec.Timestamp,
+		Commander: rec.Commander,
+	}
+	err = writeEntry(s.index, e)
+	if err != nil {
+		return err
+	}
+	s.size += int64(len(b))
+	s.add(e)
+	r.ID = id
+	return nil
+}
+
+// Query returns the records matching q, in the order they were appended
+func (s *FileStore) Query(q Query) ([]Record, error) {
+	records := make([]Record, 0)
+	err := s.Iterate(q, func(r Record) error {
+		records = append(records, r)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Iterate calls fn for every record matching q, in the order they were
+// appended. The store is not locked while fn runs, so fn may append
+func (s *FileStore) Iterate(q Query, fn func(r Record) error) error {
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return ErrClosed
+	}
+	matches := s.match(q)
+	s.mu.RUnlock()
+
+	for _, e := range matches {
+		r, err := s.read(e)
+		if err != nil {
+			return err
+		}
+		err = fn(*r)
+		if err == ErrStop {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Last returns the last record appended, reporting false when the store is
+// empty
+func (s *FileStore) Last() (Record, bool, error) {
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return Record{}, false, ErrClosed
+	}
+	if len(s.entries) == 0 {
+		s.mu.RUnlock()
+		return Record{}, false, nil
+	}
+	e := s.entries[len(s.entries)-1]
+	s.mu.RUnlock()
+
+	r, err := s.read(e)
+	if err != nil {
+		return Record{}, false, err
+	}
+	return *r, true, nil
+}
+
+// match returns the index entries matching q. Must be called with the lock
+// held
+func (s *FileStore) match(q Query) []entry {
+	// only look at the entries of the queried events
+	var positions []int
+	if len(q.Events) > 0 {
+		positions = make([]int, 0)
+		seen := make(map[string]bool)
+		for _, name := range q.Events {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			positions = merge(positions, s.byEvent[name])
+		}
+	}
+
+	matches := make([]entry, 0)
+	n := len(s.entries)
+	if positions != nil {
+		n = len(positions)
+	}
+	for i := 0; i < n; i++ {
+		e := &s.entries[i]
+		if positions != nil {
+			e = &s.entries[positions[i]]
+		}
+		if !q.Match(e.record()) {
+			continue
+		}
+		matches = append(matches, *e)
+		if q.Limit > 0 && len(matches) == q.Limit {
+			break
+		}
+	}
+	return matches
+}
+
+// merge merges two sorted lists of positions
+func merge(a, b []int) []int {
+	out := make([]int, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] < b[j] {
+			out = append(out, a[i])
+			i++
+		} else {
+			out = append(out, b[j])
+			j++
+		}
+	}
+	out = append(out, a[i:]...)
+	return append(out, b[j:]...)
+}
+
+// read reads the record of an index entry from the log
+func (s *FileStore) read(e entry) (*Record, error) {
+	b := make([]byte, e.Length)
+	_, err := s.log.ReadAt(b, e.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	var r Record
+	err = json.Unmarshal(b, &r)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt record %d: %w", e.ID, err)
+	}
+	return &r, nil
+}
+
+// Close syncs and closes the store files
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	err := s.log.Sync()
+	if err == nil {
+		err = s.index.Sync()
+	}
+	if cerr := s.log.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := s.index.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,203 @@
+package store
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testStart = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// fill appends n records cycling through a few events and commanders
+func fill(t *testing.T, s *FileStore, n int) {
+	t.Helper()
+
+	names := []string{"FSDJump", "Scan", "Docked"}
+	commanders := []string{"Alice", "Bob"}
+	for i := 0; i < n; i++ {
+		name := names[i%len(names)]
+		ts := testStart.Add(time.Duration(i) * time.Minute)
+		b := fmt.Sprintf(`{ "timestamp": %q, "event": %q, "N": %d }`, ts.Format(time.RFC3339), name, i)
+		r, err := NewRecord(commanders[i%len(commanders)], []byte(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = s.Append(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func open(t *testing.T, dir string) *FileStore {
+	t.Helper()
+
+	s, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func query(t *testing.T, s *FileStore, q Query) []Record {
+	t.Helper()
+
+	records, err := s.Query(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func ids(records []Record) []uint64 {
+	ids := make([]uint64, 0, len(records))
+	for _, r := range records {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func TestFileStoreQuery(t *testing.T) {
+	s := open(t, t.TempDir())
+	defer s.Close()
+	fill(t, s, 12)
+
+	tests := []struct {
+		name string
+		q    Query
+		want []uint64
+	}{
+		{"all", Query{}, []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}},
+		{"event", Query{Events: []string{"Scan"}}, []uint64{2, 5, 8, 11}},
+		{"events", Query{Events: []string{"Docked", "FSDJump"}}, []uint64{1, 3, 4, 6, 7, 9, 10, 12}},
+		{"unknown event", Query{Events: []string{"Died"}}, []uint64{}},
+		{"commander", Query{Commander: "Bob"}, []uint64{2, 4, 6, 8, 10, 12}},
+		{"event and commander", Query{Events: []string{"FSDJump"}, Commander: "Bob"}, []uint64{4, 10}},
+		{"from", Query{From: testStart.Add(9 * time.Minute)}, []uint64{10, 11, 12}},
+		{"to", Query{To: testStart.Add(2 * time.Minute)}, []uint64{1, 2}},
+		{"range", Query{From: testStart.Add(3 * time.Minute), To: testStart.Add(6 * time.Minute)}, []uint64{4, 5, 6}},
+		{"limit", Query{Events: []string{"Scan"}, Limit: 2}, []uint64{2, 5}},
+	}
+	for _, tt := range tests {
+		got := ids(query(t, s, tt.q))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFileStoreIterateStop(t *testing.T) {
+	s := open(t, t.TempDir())
+	defer s.Close()
+	fill(t, s, 5)
+
+	n := 0
+	err := s.Iterate(Query{}, func(r Record) error {
+		n++
+		if n == 2 {
+			return ErrStop
+		}
+		return nil
+	})
+	if err != nil || n != 2 {
+		t.Errorf("Iterate() = %v after %d records, want nil after 2", err, n)
+	}
+}
+
+func TestFileStoreReopen(t *testing.T) {
+	dir := t.TempDir()
+	s := open(t, dir)
+	fill(t, s, 10)
+	want := query(t, s, Query{})
+	err := s.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Append(&Record{}); err != ErrClosed {
+		t.Errorf("Append() after Close = %v, want ErrClosed", err)
+	}
+
+	index, err := ioutil.ReadFile(filepath.Join(dir, indexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		index func() error
+	}{
+		{"index", func() error {
+			return ioutil.WriteFile(filepath.Join(dir, indexFile), index, 0644)
+		}},
+		{"missing index", func() error {
+			return os.Remove(filepath.Join(dir, indexFile))
+		}},
+		{"index behind", func() error {
+			return ioutil.WriteFile(filepath.Join(dir, indexFile), index[:len(index)/2], 0644)
+		}},
+		{"torn index", func() error {
+			return ioutil.WriteFile(filepath.Join(dir, indexFile), append(index[:len(index)-1], index[:10]...), 0644)
+		}},
+		{"index ahead of the log", func() error {
+			return ioutil.WriteFile(filepath.Join(dir, indexFile), append(index, `{"id":11,"off":100000,"len":10,"event":"Scan"}`+"\n"...), 0644)
+		}},
+	}
+	for _, tt := range tests {
+		err := tt.index()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := open(t, dir)
+		got := query(t, s, Query{})
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %d records, want %d", tt.name, len(got), len(want))
+		}
+		scans := ids(query(t, s, Query{Events: []string{"Scan"}}))
+		if !reflect.DeepEqual(scans, []uint64{2, 5, 8}) {
+			t.Errorf("%s: got Scan records %v", tt.name, scans)
+		}
+		s.Close()
+
+		rebuilt, err := ioutil.ReadFile(filepath.Join(dir, indexFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(rebuilt) != string(index) {
+			t.Errorf("%s: index not rewritten", tt.name)
+		}
+	}
+}
+
+func TestFileStoreTornLog(t *testing.T) {
+	dir := t.TempDir()
+	s := open(t, dir)
+	fill(t, s, 3)
+	s.Close()
+
+	// a record cut short by a crash is dropped, and the next one takes its ID
+	f, err := os.OpenFile(filepath.Join(dir, logFile), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.WriteString(`{"id":4,"event":"Sc`)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s = open(t, dir)
+	defer s.Close()
+	last, ok, err := s.Last()
+	if err != nil || !ok || last.ID != 3 {
+		t.Fatalf("Last() = %d, %v, %v, want record 3", last.ID, ok, err)
+	}
+	fill(t, s, 1)
+	got := ids(query(t, s, Query{}))
+	if !reflect.DeepEqual(got, []uint64{1, 2, 3, 4}) {
+		t.Errorf("got %v after appending to a torn log", got)
+	}
+}
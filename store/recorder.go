@@ -0,0 +1,141 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/event"
+	"github.com/sht/ed-journal/events"
+)
+
+// Recorder appends every event triggered on a dispatcher to a store, along
+// with the commander playing at the time. It stops at the first failed
+// append, so the store never misses events between stored ones and replaying
+// the journal with ResumeAfter stores the rest. It is safe for concurrent use
+type Recorder struct {
+	mu        sync.Mutex
+	s         Store
+	ignore    map[string]bool
+	commander string
+	err       error
+	// resume is the last record stored before the journal is replayed
+	resume *Record
+}
+
+// NewRecorder returns a recorder for the store. Events named in ignore are not
+// recorded, which is meant for the events the trackers trigger themselves
+// and would trigger again when the store is replayed
+func NewRecorder(s Store, ignore ...string) *Recorder {
+	r := &Recorder{
+		s:      s,
+		ignore: make(map[string]bool, len(ignore)),
+	}
+	for _, name := range ignore {
+		r.ignore[name] = true
+	}
+	return r
+}
+
+// AddListeners subscribes the recorder to every event
+func (r *Recorder) AddListeners(d *dispatcher.Dispatcher) {
+	d.OnAllSync(r.record)
+}
+
+// ResumeAfter skips the events up to and including the last record, which is
+// meant for journals replayed into a store that already holds part of them.
+// Events with the same timestamp as last are skipped until last itself is
+// seen, as they were stored along with it
+func (r *Recorder) ResumeAfter(last Record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.resume = &last
+	r.commander = last.Commander
+}
+
+// Err returns the error that stopped the recorder, if any
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+func (r *Recorder) record(b []byte) {
+	var e event.Event
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+	if r.ignore[e.Event] {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return
+	}
+	if r.resume != nil && !r.resumed(&e, b) {
+		return
+	}
+	r.updateCommander(e.Event, b)
+	rec, err := NewRecord(r.commander, b)
+	if err == nil {
+		err = r.s.Append(rec)
+	}
+	if err != nil {
+		r.err = err
+	}
+}
+
+// resumed reports whether an event comes after the record to resume after.
+// Must be called with the lock held
+func (r *Recorder) resumed(e *event.Event, b []byte) bool {
+	last := r.resume
+	if e.Timestamp.Before(last.Timestamp) {
+		return false
+	}
+	if e.Timestamp.Equal(last.Timestamp) {
+		if e.Event == last.Event && sameJSON(b, last.Data) {
+			r.resume = nil
+		}
+		return false
+	}
+	r.resume = nil
+	return true
+}
+
+// sameJSON reports whether two encodings are the same once compacted
+func sameJSON(a, b []byte) bool {
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, a) != nil || json.Compact(&cb, b) != nil {
+		return false
+	}
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
+
+// updateCommander follows the commander being played. Must be called with the
+// lock held
+func (r *Recorder) updateCommander(name string, b []byte) {
+	switch name {
+	case events.Commander:
+		var e events.CommanderEvent
+		if json.Unmarshal(b, &e) == nil {
+			r.commander = e.Name
+		}
+	case events.LoadGame:
+		var e events.LoadGameEvent
+		if json.Unmarshal(b, &e) == nil {
+			r.commander = e.Commander
+		}
+	case events.NewCommander:
+		var e events.NewCommanderEvent
+		if json.Unmarshal(b, &e) == nil {
+			r.commander = e.Name
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/sht/ed-journal/event"
+)
+
+// ErrStop can be returned by an Iterate callback to stop iterating without
+// an error
+var ErrStop = errors.New("stop iterating")
+
+// Record is a stored event. ID is assigned by the store and increases with
+// every appended record
+type Record struct {
+	ID        uint64          `json:"id"`
+	Event     string          `json:"event"`
+	Timestamp time.Time       `json:"timestamp"`
+	Commander string          `json:"commander,omitempty"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// NewRecord returns a record for a raw journal line, played by the given
+// commander
+func NewRecord(commander string, b []byte) (*Record, error) {
+	var e event.Event
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, len(b))
+	copy(data, b)
+	return &Record{
+		Event:     e.Event,
+		Timestamp: e.Timestamp,
+		Commander: commander,
+		Data:      data,
+	}, nil
+}
+
+// Query selects records. Empty fields match every record, a zero From or To
+// leaves that side of the time range open and a zero Limit returns every
+// match
+type Query struct {
+	Events    []string
+	From      time.Time
+	To        time.Time
+	Commander string
+	Limit     int
+}
+
+// Match reports whether a record matches the query, ignoring Limit
+func (q Query) Match(r *Record) bool {
+	if len(q.Events) > 0 {
+		found := false
+		for _, name := range q.Events {
+			if name == r.Event {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.Commander != "" && q.Commander != r.Commander {
+		return false
+	}
+	if !q.From.IsZero() && r.Timestamp.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !r.Timestamp.Before(q.To) {
+		return false
+	}
+	return true
+}
+
+// Store persists events. Implementations must be safe for concurrent use
+type Store interface {
+	// Append stores the record and sets its ID
+	Append(r *Record) error
+	// Query returns the records matching q, in the order they were appended
+	Query(q Query) ([]Record, error)
+	// Iterate calls fn for every record matching q, in the order they were
+	// appended. Iterating stops at the first error returned by fn, which is
+	// returned unless it is ErrStop
+	Iterate(q Query, fn func(r Record) error) error
+	// Close releases the resources held by the store
+	Close() error
+}
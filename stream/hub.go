@@ -0,0 +1,212 @@
+package stream
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/event"
+	"github.com/sht/ed-journal/query"
+)
+
+var errInvalidReplay = errors.New("replay must be a positive number")
+
+// clientBuffer is the number of events queued for a client. Clients that fall
+// further behind are disconnected rather than slowing down the dispatcher
+const clientBuffer = 256
+
+// Hub fans the events triggered on a dispatcher out to stream clients,
+// keeping the most recent ones to replay to new clients. It is safe for
+// concurrent use
+type Hub struct {
+	mu        sync.Mutex
+	recent    [][]byte
+	next      int
+	size      int
+	heartbeat time.Duration
+	clients   map[*client]bool
+
+	// AllowNullOrigin lets pages with the null origin connect over
+	// WebSocket, such as overlays opened from a file. Sandboxed frames and
+	// data: URLs of any website have that origin too, so it must only be set
+	// when asked for
+	AllowNullOrigin bool
+}
+
+// NewHub returns a hub keeping the last replay events and sending heartbeats
+// to idle clients every heartbeat. A negative replay keeps no events and a
+// heartbeat of 0 or less sends none
+func NewHub(replay int, heartbeat time.Duration) *Hub {
+	if replay < 0 {
+		replay = 0
+	}
+	return &Hub{
+		recent:    make([][]byte, 0, replay),
+		size:      replay,
+		heartbeat: heartbeat,
+		clients:   make(map[*client]bool),
+	}
+}
+
+// heartbeats returns a channel ticking every heartbeat, which never ticks
+// when heartbeats are disabled, and a function stopping it
+func (h *Hub) heartbeats() (<-chan time.Time, func()) {
+	if h.heartbeat <= 0 {
+		return nil, func() {}
+	}
+	t := time.NewTicker(h.heartbeat)
+	return t.C, t.Stop
+}
+
+// AddListeners subscribes the hub to every event
+func (h *Hub) AddListeners(d *dispatcher.Dispatcher) {
+	d.OnAllSync(h.broadcast)
+}
+
+// Filter selects the events sent to a client
+type Filter struct {
+	// Events are the event names sent, all when empty
+	Events map[string]bool
+	// Expr further filters the events when not nil
+	Expr *query.Expr
+	// Replay is the number of recent events sent on connect
+	Replay int
+}
+
+// ParseFilter reads a filter from the query parameters of a stream request:
+// event (comma separated names), filter (a query expression) and replay
+func ParseFilter(v url.Values) (Filter, error) {
+	f := Filter{Events: make(map[string]bool)}
+	for _, name := range strings.Split(v.Get("event"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			f.Events[name] = true
+		}
+	}
+
+	var err error
+	if s := v.Get("filter"); s != "" {
+		f.Expr, err = query.Parse(s)
+		if err != nil {
+			return f, err
+		}
+	}
+	if s := v.Get("replay"); s != "" {
+		f.Replay, err = strconv.Atoi(s)
+		if err != nil || f.Replay < 0 {
+			return f, errInvalidReplay
+		}
+	}
+	return f, nil
+}
+
+// Match reports whether an event passes the filter
+func (f *Filter) Match(name string, b []byte) bool {
+	if len(f.Events) > 0 && !f.Events[name] {
+		return false
+	}
+	if f.Expr != nil {
+		ok, err := f.Expr.Match(b)
+		return err == nil && ok
+	}
+	return true
+}
+
+type client struct {
+	filter Filter
+	ch     chan []byte
+	// closed is closed when the hub drops the client
+	closed chan struct{}
+}
+
+// subscribe adds a client and returns the recent events it asked for, in
+// order. Both happen under the lock so that no event is missed or sent twice
+func (h *Hub) subscribe(f Filter) (*client, [][]byte) {
+	c := &client{
+		filter: f,
+		ch:     make(chan []byte, clientBuffer),
+		closed: make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	replay := make([][]byte, 0)
+	if f.Replay > 0 {
+		// walk the ring from the oldest event
+		for i := 0; i < len(h.recent); i++ {
+			b := h.recent[(h.next+i)%len(h.recent)]
+			var e event.Event
+			if json.Unmarshal(b, &e) == nil && f.Match(e.Event, b) {
+				replay = append(replay, b)
+			}
+		}
+		if len(replay) > f.Replay {
+			replay = replay[len(replay)-f.Replay:]
+		}
+	}
+	h.clients[c] = true
+	return c, replay
+}
+
+func (h *Hub) unsubscribe(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.drop(c)
+}
+
+// drop must be called with the lock held
+func (h *Hub) drop(c *client) {
+	if !h.clients[c] {
+		return
+	}
+	delete(h.clients, c)
+	close(c.closed)
+}
+
+// Clients returns the number of connected clients
+func (h *Hub) Clients() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.clients)
+}
+
+func (h *Hub) broadcast(b []byte) {
+	var e event.Event
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+	// the dispatcher may reuse b
+	data := make([]byte, len(b))
+	copy(data, b)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.size > 0 {
+		if len(h.recent) < h.size {
+			h.recent = append(h.recent, data)
+		} else {
+			h.recent[h.next] = data
+			h.next = (h.next + 1) % h.size
+		}
+	}
+
+	for c := range h.clients {
+		if !c.filter.Match(e.Event, data) {
+			continue
+		}
+		select {
+		case c.ch <- data:
+		default:
+			h.drop(c)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package stream
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sht/ed-journal/dispatcher"
+)
+
+// send triggers event n, named A for odd n and B for even n
+func send(d *dispatcher.Dispatcher, name string, n int) {
+	_ = d.Trigger(name, []byte(fmt.Sprintf(`{"timestamp":"2021-01-01T00:00:00Z","event":"%s","N":%d}`, name, n)))
+}
+
+func name(n int) string {
+	if n%2 == 0 {
+		return "B"
+	}
+	return "A"
+}
+
+// sseClient reads the N of the events streamed by an SSE endpoint
+type sseClient struct {
+	resp *http.Response
+	s    *bufio.Scanner
+}
+
+func connect(t *testing.T, srv *httptest.Server, query string) *sseClient {
+	t.Helper()
+
+	resp, err := http.Get(srv.URL + "?" + query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET ?%s: %s", query, resp.Status)
+	}
+	return &sseClient{resp: resp, s: bufio.NewScanner(resp.Body)}
+}
+
+// next returns the N of the next event
+func (c *sseClient) next(t *testing.T) int {
+	t.Helper()
+
+	for c.s.Scan() {
+		line := c.s.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var e struct{ N int }
+		err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e.N
+	}
+	t.Fatalf("stream ended: %v", c.s.Err())
+	return 0
+}
+
+// waitClients waits for the hub to have n clients
+func waitClients(t *testing.T, h *Hub, n int) {
+	t.Helper()
+
+	for i := 0; h.Clients() != n; i++ {
+		if i == 100 {
+			t.Fatalf("%d clients, want %d", h.Clients(), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReplayOrder(t *testing.T) {
+	d := dispatcher.NewDispatcher()
+	h := NewHub(3, 0)
+	h.AddListeners(d)
+	srv := httptest.NewServer(h.SSEHandler())
+	defer srv.Close()
+
+	// the ring wraps around, keeping 3, 4 and 5
+	for n := 1; n <= 5; n++ {
+		send(d, name(n), n)
+	}
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"replay=10", []int{3, 4, 5}},
+		{"replay=2", []int{4, 5}},
+		{"replay=10&event=A", []int{3, 5}},
+		{"replay=1&event=A", []int{5}},
+		{"replay=10&filter=N+%3C+5", []int{3, 4}},
+	}
+	for _, tt := range tests {
+		c := connect(t, srv, tt.query)
+		got := make([]int, 0)
+		for range tt.want {
+			got = append(got, c.next(t))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("?%s replayed %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	// live events follow the replayed ones
+	c := connect(t, srv, "replay=1")
+	waitClients(t, h, len(tests)+1)
+	send(d, name(6), 6)
+	if got := []int{c.next(t), c.next(t)}; !reflect.DeepEqual(got, []int{5, 6}) {
+		t.Errorf("got %v, want the replayed 5 then 6", got)
+	}
+
+	resp, err := http.Get(srv.URL + "?replay=-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("negative replay: %s, want 400", resp.Status)
+	}
+}
+
+func TestSlowClientDropped(t *testing.T) {
+	d := dispatcher.NewDispatcher()
+	h := NewHub(0, 0)
+	h.AddListeners(d)
+	srv := httptest.NewServer(h.SSEHandler())
+	defer srv.Close()
+
+	live := connect(t, srv, "event=Live")
+	waitClients(t, h, 1)
+	// a client that never reads its events
+	slow, _ := h.subscribe(Filter{})
+
+	for n := 0; n <= clientBuffer; n++ {
+		send(d, "Flood", n)
+	}
+	select {
+	case <-slow.closed:
+	default:
+		t.Fatal("slow client still connected")
+	}
+	if n := h.Clients(); n != 1 {
+		t.Errorf("%d clients, want only the streaming one", n)
+	}
+
+	// the other clients carry on
+	send(d, "Live", 1000)
+	if n := live.next(t); n != 1000 {
+		t.Errorf("streaming client got %d, want 1000", n)
+	}
+}
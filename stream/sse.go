@@ -0,0 +1,65 @@
+package stream
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+)
+
+// SSEHandler returns a handler streaming events as Server-Sent Events. Each
+// event is sent as a message whose data is the journal line, so browsers get
+// them through EventSource.onmessage. See ParseFilter for the parameters
+func (h *Hub) SSEHandler() http.Handler {
+	return http.HandlerFunc(h.serveSSE)
+}
+
+func (h *Hub) serveSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	f, err := ParseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c, replay := h.subscribe(f)
+	defer h.unsubscribe(c)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+	for _, b := range replay {
+		writeSSE(w, b)
+	}
+	flusher.Flush()
+
+	heartbeat, stop := h.heartbeats()
+	defer stop()
+	for {
+		select {
+		case b := <-c.ch:
+			writeSSE(w, b)
+		case <-heartbeat:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case <-c.closed:
+			return
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// writeSSE writes an event as a message. Journal lines have no newlines, but
+// each line of the data needs its own field
+func writeSSE(w http.ResponseWriter, b []byte) {
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
@@ -0,0 +1,314 @@
+package stream
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketGUID is appended to the client key to compute the accept key, see
+// RFC 6455 section 1.3
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// frame opcodes
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// close status codes
+const (
+	closeNormal       = 1000
+	closeProtocol     = 1002
+	closeTooBig       = 1009
+	closePolicyFailed = 1008
+)
+
+// maxClientMessage is the largest message accepted from clients, which have
+// nothing to send but control frames
+const maxClientMessage = 64 * 1024
+
+// writeTimeout bounds how long a slow client can hold up a write
+const writeTimeout = 10 * time.Second
+
+var (
+	errProtocol = errors.New("websocket protocol error")
+	errTooBig   = errors.New("websocket message too big")
+)
+
+// WebSocketHandler returns a handler streaming events over WebSocket, one
+// text message per journal line. See ParseFilter for the parameters. Only
+// clients sending no origin and pages served from the local machine may
+// connect, and pages opened from files when AllowNullOrigin is set
+func (h *Hub) WebSocketHandler() http.Handler {
+	return http.HandlerFunc(h.serveWebSocket)
+}
+
+// localOrigin reports whether a browser origin is allowed to connect
+func (h *Hub) localOrigin(origin string) bool {
+	switch origin {
+	case "":
+		return true
+	case "null":
+		return h.AllowNullOrigin
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func headerContains(r *http.Request, name, token string) bool {
+	for _, v := range r.Header[name] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (h *Hub) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet || !headerContains(r, "Connection", "upgrade") ||
+		!headerContains(r, "Upgrade", "websocket") {
+		http.Error(w, "websocket upgrade required", http.StatusUpgradeRequired)
+		return
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return
+	}
+	if !h.localOrigin(r.Header.Get("Origin")) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	f, err := ParseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket is not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	// the server's timeouts do not apply to a long lived connection
+	_ = conn.SetDeadline(time.Time{})
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	accept := base64.StdEncoding.EncodeToString(sum[:])
+	_, err = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + accept + "\r\n\r\n")
+	if err == nil {
+		err = rw.Flush()
+	}
+	if err != nil {
+		return
+	}
+
+	ws := &wsConn{conn: conn, r: rw.Reader}
+	c, replay := h.subscribe(f)
+	defer h.unsubscribe(c)
+
+	// the reader answers pings and notices when the client goes away
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		ws.readLoop()
+	}()
+
+	for _, b := range replay {
+		if ws.writeFrame(opText, b) != nil {
+			return
+		}
+	}
+
+	heartbeat, stop := h.heartbeats()
+	defer stop()
+	for {
+		select {
+		case b := <-c.ch:
+			err = ws.writeFrame(opText, b)
+		case <-heartbeat:
+			err = ws.writeFrame(opPing, nil)
+		case <-c.closed:
+			// dropped for falling behind
+			_ = ws.close(closePolicyFailed, "too slow")
+			return
+		case <-gone:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// wsConn is the server side of a WebSocket connection
+type wsConn struct {
+	wmu  sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// writeFrame writes an unfragmented, unmasked frame
+func (ws *wsConn) writeFrame(opcode byte, payload []byte) error {
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode
+	n := len(payload)
+	switch {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = append(header, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header[1] = 127
+		header = append(header, make([]byte, 8)...)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+
+	ws.wmu.Lock()
+	defer ws.wmu.Unlock()
+
+	_ = ws.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err := ws.conn.Write(append(header, payload...))
+	return err
+}
+
+// close sends a close frame with a status code and reason
+func (ws *wsConn) close(code uint16, reason string) error {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, code)
+	return ws.writeFrame(opClose, append(payload, reason...))
+}
+
+// readLoop reads client frames until the connection closes, answering pings
+// and close frames. Data messages are discarded
+func (ws *wsConn) readLoop() {
+	for {
+		opcode, payload, err := ws.readFrame()
+		if err != nil {
+			switch err {
+			case errProtocol:
+				_ = ws.close(closeProtocol, "protocol error")
+			case errTooBig:
+				_ = ws.close(closeTooBig, "message too big")
+			}
+			return
+		}
+
+		switch opcode {
+		case opPing:
+			if ws.writeFrame(opPong, payload) != nil {
+				return
+			}
+		case opClose:
+			// echo the status code, as required before closing
+			code := uint16(closeNormal)
+			if len(payload) >= 2 {
+				code = binary.BigEndian.Uint16(payload)
+			}
+			_ = ws.close(code, "")
+			return
+		}
+	}
+}
+
+// readFrame reads a client frame, unmasking its payload
+func (ws *wsConn) readFrame() (byte, []byte, error) {
+	var head [2]byte
+	_, err := io.ReadFull(ws.r, head[:])
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if head[0]&0x70 != 0 {
+		// no extensions were negotiated
+		return 0, nil, errProtocol
+	}
+	opcode := head[0] & 0x0f
+	fin := head[0]&0x80 != 0
+	masked := head[1]&0x80 != 0
+	if !masked {
+		// clients must mask their frames
+		return 0, nil, errProtocol
+	}
+	switch opcode {
+	case opContinuation, opText, opBinary:
+	case opClose, opPing, opPong:
+		if !fin || head[1]&0x7f > 125 {
+			return 0, nil, errProtocol
+		}
+	default:
+		return 0, nil, errProtocol
+	}
+
+	n := uint64(head[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		_, err = io.ReadFull(ws.r, ext[:])
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		_, err = io.ReadFull(ws.r, ext[:])
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	if n > maxClientMessage {
+		return 0, nil, errTooBig
+	}
+
+	var mask [4]byte
+	_, err = io.ReadFull(ws.r, mask[:])
+	if err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, n)
+	_, err = io.ReadFull(ws.r, payload)
+	if err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sht/ed-journal/event"
+	"github.com/sht/ed-journal/query"
+)
+
+func tailCmd(args []string) int {
+	fs := newFlagSet("tail")
+	dir := dirFlag(fs)
+	format := formatFlag(fs, "json")
+	fields := fs.String("fields", "", "comma separated fields to output")
+	cursor := fs.String("cursor", "", "file keeping the position read, to carry on from it next time")
+	interval := fs.Duration("interval", time.Second, "how often to check the journal")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	expr, err := parseExpr(fs.Args())
+	if err != nil {
+		return usageError(fs, err)
+	}
+	out, err := newWriter(*format, splitList(*fields))
+	if err != nil {
+		return usageError(fs, err)
+	}
+
+	w, err := event.NewWatcher(*dir, func(b []byte) {
+		fields, err := query.Decode(b)
+		if err != nil || !expr.Eval(fields) {
+			return
+		}
+		// tables are flushed row by row so events show up as they come
+		if out.Write(fields) == nil {
+			_ = out.Flush()
+		}
+	}, *interval)
+	if err != nil {
+		return fail(err)
+	}
+	w.OnError = func(err error) {
+		fmt.Fprintf(os.Stderr, "ed-journal: %v\n", err)
+	}
+	if *cursor != "" {
+		err = w.Resume(*cursor)
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	quit := interrupted()
+	w.Start()
+	<-quit
+	w.Stop()
+	return exitOK
+}
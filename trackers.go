@@ -0,0 +1,129 @@
+package main
+
+import (
+	"github.com/sht/ed-journal/bgs"
+	"github.com/sht/ed-journal/credits"
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/engineering"
+	"github.com/sht/ed-journal/events"
+	"github.com/sht/ed-journal/exploration"
+	"github.com/sht/ed-journal/fleet"
+	"github.com/sht/ed-journal/flight"
+	"github.com/sht/ed-journal/fuel"
+	"github.com/sht/ed-journal/loadout"
+	"github.com/sht/ed-journal/materials"
+	"github.com/sht/ed-journal/missions"
+	"github.com/sht/ed-journal/ranks"
+	"github.com/sht/ed-journal/route"
+	"github.com/sht/ed-journal/session"
+	"github.com/sht/ed-journal/snapshot"
+	"github.com/sht/ed-journal/state"
+	"github.com/sht/ed-journal/travel"
+)
+
+// syntheticEvents are the events triggered by the trackers themselves. They
+// are not stored, as replaying the store triggers them again
+var syntheticEvents = []string{
+	bgs.FactionStateChanged,
+	bgs.TickDetected,
+	flight.FlightStateChanged,
+	flight.ImpossibleTransition,
+	fuel.FuelWarning,
+	loadout.LoadoutChanged,
+	missions.MissionExpired,
+	missions.MissionExpiring,
+	route.RouteDeviation,
+	route.RouteProgress,
+	session.SessionEnded,
+}
+
+// notJournal are the events that are not journal lines: Status file updates,
+// which may be left over from the last time the game ran, and the synthetic
+// events
+var notJournal = append([]string{events.Status}, syntheticEvents...)
+
+// trackers aggregates the journal into every view the CLI offers
+type trackers struct {
+	state       *state.Tracker
+	credits     *credits.Ledger
+	sessions    *session.Tracker
+	travel      *travel.Log
+	routes      *route.Tracker
+	fuel        *fuel.Monitor
+	flight      *flight.Machine
+	fleet       *fleet.Registry
+	loadouts    *loadout.Tracker
+	engineering *engineering.Inventory
+	materials   *materials.Tracker
+	missions    *missions.Ledger
+	exploration *exploration.Estimator
+	ranks       *ranks.Forecaster
+	bgs         *bgs.History
+	ticks       *bgs.TickDetector
+}
+
+// newTrackers returns the trackers for the journal in dir
+func newTrackers(dir string) *trackers {
+	ledger := credits.NewLedger()
+	routes := route.NewTracker(dir)
+	return &trackers{
+		state:       state.NewTracker(ledger),
+		credits:     ledger,
+		sessions:    session.NewTracker(ledger, notJournal...),
+		travel:      travel.NewLog(),
+		routes:      routes,
+		fuel:        fuel.NewMonitor(routes),
+		flight:      flight.NewMachine(),
+		fleet:       fleet.NewRegistry(),
+		loadouts:    loadout.NewTracker(),
+		engineering: engineering.NewInventory(),
+		materials:   materials.NewTracker(),
+		missions:    missions.NewLedger(),
+		exploration: exploration.NewEstimator(),
+		ranks:       ranks.NewForecaster(notJournal...),
+		bgs:         bgs.NewHistory(),
+		ticks:       bgs.NewTickDetector(),
+	}
+}
+
+// addListeners subscribes the trackers. The ledger comes before the session
+// tracker and the route tracker before the fuel monitor, which read them
+func (t *trackers) addListeners(d *dispatcher.Dispatcher) {
+	t.state.AddListeners(d)
+	t.credits.AddListeners(d)
+	t.sessions.AddListeners(d)
+	t.travel.AddListeners(d)
+	t.routes.AddListeners(d)
+	t.fuel.AddListeners(d)
+	t.flight.AddListeners(d)
+	t.fleet.AddListeners(d)
+	t.loadouts.AddListeners(d)
+	t.engineering.AddListeners(d)
+	t.materials.AddListeners(d)
+	t.missions.AddListeners(d)
+	t.exploration.AddListeners(d)
+	t.ranks.AddListeners(d)
+	t.bgs.AddListeners(d)
+	t.ticks.AddListeners(d)
+}
+
+// register adds every tracker but the fleet registry, which keeps its own
+// file, to the snapshot manager, so a snapshot is only restored when it holds
+// all of them
+func (t *trackers) register(mgr *snapshot.Manager) {
+	mgr.Register("state", state.SnapshotVersion, t.state)
+	mgr.Register("credits", credits.SnapshotVersion, t.credits)
+	mgr.Register("sessions", session.SnapshotVersion, t.sessions)
+	mgr.Register("travel", travel.SnapshotVersion, t.travel)
+	mgr.Register("routes", route.SnapshotVersion, t.routes)
+	mgr.Register("fuel", fuel.SnapshotVersion, t.fuel)
+	mgr.Register("flight", flight.SnapshotVersion, t.flight)
+	mgr.Register("loadouts", loadout.SnapshotVersion, t.loadouts)
+	mgr.Register("engineering", engineering.SnapshotVersion, t.engineering)
+	mgr.Register("materials", materials.SnapshotVersion, t.materials)
+	mgr.Register("missions", missions.SnapshotVersion, t.missions)
+	mgr.Register("exploration", exploration.SnapshotVersion, t.exploration)
+	mgr.Register("ranks", ranks.SnapshotVersion, t.ranks)
+	mgr.Register("bgs", bgs.SnapshotVersion, t.bgs)
+	mgr.Register("ticks", bgs.SnapshotVersion, t.ticks)
+}
@@ -0,0 +1,267 @@
+package travel
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/events"
+)
+
+// Boost is the kind of FSD boost used for a jump
+type Boost string
+
+const (
+	NoBoost        Boost = ""
+	SynthesisBoost Boost = "synthesis"
+	NeutronBoost   Boost = "neutron"
+	WhiteDwarf     Boost = "whiteDwarf"
+)
+
+// Jump is a single hyperspace jump
+type Jump struct {
+	Timestamp     time.Time `json:"timestamp"`
+	StarSystem    string    `json:"starSystem"`
+	SystemAddress int       `json:"systemAddress"`
+	StarPos       []float64 `json:"starPos"`
+	Distance      float64   `json:"distance"`
+	FuelUsed      float64   `json:"fuelUsed"`
+	Boost         Boost     `json:"boost,omitempty"`
+	Revisit       bool      `json:"revisit"`
+}
+
+// Stats summarises the jumps made in a time range
+type Stats struct {
+	Jumps            int     `json:"jumps"`
+	Distance         float64 `json:"distance"`
+	FuelUsed         float64 `json:"fuelUsed"`
+	LongestJump      *Jump   `json:"longestJump,omitempty"`
+	NeutronBoosts    int     `json:"neutronBoosts"`
+	WhiteDwarfBoosts int     `json:"whiteDwarfBoosts"`
+	SynthesisBoosts  int     `json:"synthesisBoosts"`
+	UniqueSystems    int     `json:"uniqueSystems"`
+	Revisits         int     `json:"revisits"`
+}
+
+// Log records every hyperspace jump. It is safe for concurrent use
+type Log struct {
+	mu           sync.RWMutex
+	jumps        []Jump
+	visited      map[int]bool
+	sessionStart time.Time
+
+	// starClass is the class of the star the commander is at, used to tell
+	// neutron star and white dwarf boosts apart
+	starClass string
+	// targetClass is the class of the star being jumped to
+	targetClass string
+}
+
+func NewLog() *Log {
+	return &Log{
+		jumps:   make([]Jump, 0),
+		visited: make(map[int]bool),
+	}
+}
+
+const SnapshotVersion = 1
+
+// Snapshot saves the jumps and visited systems, along with the star classes
+// the next boost is told apart from
+func (l *Log) Snapshot() (json.RawMessage, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return json.Marshal(l.saved())
+}
+
+func (l *Log) Restore(b json.RawMessage) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.visited = make(map[int]bool)
+	return json.Unmarshal(b, l.saved())
+}
+
+// saved points at the fields kept in snapshots
+func (l *Log) saved() interface{} {
+	return &struct {
+		Jumps        *[]Jump       `json:"jumps"`
+		Visited      *map[int]bool `json:"visited"`
+		SessionStart *time.Time    `json:"sessionStart"`
+		StarClass    *string       `json:"starClass"`
+		TargetClass  *string       `json:"targetClass"`
+	}{&l.jumps, &l.visited, &l.sessionStart, &l.starClass, &l.targetClass}
+}
+
+// AddListeners subscribes the log to the jump events
+func (l *Log) AddListeners(d *dispatcher.Dispatcher) {
+	// startup
+	{
+		d.OnSync(events.LoadGame, l.loadGame)
+	}
+
+	// travel
+	{
+		d.OnSync(events.FSDJump, l.fsdJump)
+		d.OnSync(events.Location, l.location)
+		d.OnSync(events.StartJump, l.startJump)
+	}
+}
+
+// Jumps returns the jumps made in the given time range. A zero from or to
+// leaves that side of the range open
+func (l *Log) Jumps(from, to time.Time) []Jump {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	jumps := make([]Jump, 0)
+	for _, j := range l.jumps {
+		if inRange(j.Timestamp, from, to) {
+			jumps = append(jumps, j)
+		}
+	}
+	return jumps
+}
+
+// Stats returns the statistics for the jumps made in the given time range
+func (l *Log) Stats(from, to time.Time) Stats {
+	var s Stats
+	systems := make(map[int]bool)
+	for _, j := range l.Jumps(from, to) {
+		j := j
+		s.Jumps++
+		s.Distance += j.Distance
+		s.FuelUsed += j.FuelUsed
+		if s.LongestJump == nil || j.Distance > s.LongestJump.Distance {
+			s.LongestJump = &j
+		}
+		switch j.Boost {
+		case NeutronBoost:
+			s.NeutronBoosts++
+		case WhiteDwarf:
+			s.WhiteDwarfBoosts++
+		case SynthesisBoost:
+			s.SynthesisBoosts++
+		}
+		if j.Revisit {
+			s.Revisits++
+		}
+		systems[j.SystemAddress] = true
+	}
+	s.UniqueSystems = len(systems)
+	return s
+}
+
+// Session returns the statistics since the game was last loaded
+func (l *Log) Session() Stats {
+	l.mu.RLock()
+	start := l.sessionStart
+	l.mu.RUnlock()
+
+	return l.Stats(start, time.Time{})
+}
+
+// Lifetime returns the statistics for every recorded jump
+func (l *Log) Lifetime() Stats {
+	return l.Stats(time.Time{}, time.Time{})
+}
+
+func inRange(t, from, to time.Time) bool {
+	if !from.IsZero() && t.Before(from) {
+		return false
+	}
+	if !to.IsZero() && !t.Before(to) {
+		return false
+	}
+	return true
+}
+
+func (l *Log) loadGame(b []byte) {
+	var e events.LoadGameEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sessionStart = e.Timestamp
+	l.starClass = ""
+	l.targetClass = ""
+}
+
+func (l *Log) location(b []byte) {
+	var e events.LocationEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.visited[e.SystemAddress] = true
+	l.starClass = ""
+}
+
+func (l *Log) startJump(b []byte) {
+	var e events.StartJumpEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+	if e.JumpType != "Hyperspace" {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.targetClass = e.StarClass
+}
+
+func (l *Log) fsdJump(b []byte) {
+	var e events.FSDJumpEvent
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	j := Jump{
+		Timestamp:     e.Timestamp,
+		StarSystem:    e.StarSystem,
+		SystemAddress: e.SystemAddress,
+		StarPos:       e.StarPos,
+		Distance:      e.JumpDist,
+		FuelUsed:      e.FuelUsed,
+		Revisit:       l.visited[e.SystemAddress],
+	}
+	if e.BoostUsed > 0 {
+		j.Boost = boost(e.BoostUsed, l.starClass)
+	}
+
+	l.jumps = append(l.jumps, j)
+	l.visited[e.SystemAddress] = true
+	l.starClass = l.targetClass
+	l.targetClass = ""
+}
+
+// boost works out the kind of boost from the BoostUsed value: 1 to 3 are FSD
+// injections and higher values a supercharge, which the class of the star
+// jumped from tells apart
+func boost(used int, starClass string) Boost {
+	switch {
+	case used >= 1 && used <= 3:
+		return SynthesisBoost
+	case strings.HasPrefix(starClass, "D"):
+		return WhiteDwarf
+	}
+	return NeutronBoost
+}
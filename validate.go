@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sht/ed-journal/dispatcher"
+	"github.com/sht/ed-journal/event"
+	"github.com/sht/ed-journal/events"
+	"github.com/sht/ed-journal/query"
+)
+
+// problem levels
+const (
+	levelError   = "error"
+	levelWarning = "warning"
+)
+
+func validateCmd(args []string) int {
+	fs := newFlagSet("validate")
+	dir := dirFlag(fs)
+	format := formatFlag(fs, "table")
+	strict := fs.Bool("strict", false, "fail on warnings too, such as fields the event definitions do not know")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	out, err := newWriter(*format, []string{"file", "line", "event", "level", "message"})
+	if err != nil {
+		return usageError(fs, err)
+	}
+
+	// validate the journal directory unless files or directories are given
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{*dir}
+	}
+	files := make([]string, 0)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fail(err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		journals, err := event.FindJournals(path)
+		if err != nil {
+			return fail(err)
+		}
+		for _, f := range journals {
+			files = append(files, f.Path)
+		}
+	}
+
+	// the event handlers report the fields their definitions miss
+	var mismatch []string
+	events.OnMismatch = func(original, parsed []byte) {
+		mismatch = unknownFields(original, parsed)
+	}
+	d := dispatcher.NewDispatcher()
+	events.AddSyncListeners(d)
+
+	v := &validator{d: d, out: out, mismatch: &mismatch}
+	for _, path := range files {
+		err = v.file(path)
+		if err != nil {
+			v.report(path, 0, "", levelError, err.Error())
+		}
+	}
+	err = out.Flush()
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Fprintf(os.Stderr, "%d files, %d lines, %d errors, %d warnings\n", len(files), v.lines, v.errors, v.warnings)
+	if v.errors > 0 || *strict && v.warnings > 0 {
+		return exitError
+	}
+	return exitOK
+}
+
+type validator struct {
+	d        *dispatcher.Dispatcher
+	out      query.Writer
+	mismatch *[]string
+	lines    int
+	errors   int
+	warnings int
+}
+
+func (v *validator) report(path string, line int, name, level, msg string) {
+	if level == levelError {
+		v.errors++
+	} else {
+		v.warnings++
+	}
+	row := map[string]interface{}{
+		"file":    path,
+		"event":   name,
+		"level":   level,
+		"message": msg,
+	}
+	if line > 0 {
+		row["line"] = float64(line)
+	}
+	_ = v.out.Write(row)
+}
+
+// file checks every line of a journal file
+func (v *validator) file(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		b := bytes.TrimSpace(sc.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		v.lines++
+		v.line(path, line, b)
+	}
+	return sc.Err()
+}
+
+func (v *validator) line(path string, line int, b []byte) {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(b, &fields)
+	if err != nil {
+		v.report(path, line, "", levelError, "invalid JSON: "+err.Error())
+		return
+	}
+
+	var name string
+	if json.Unmarshal(fields["event"], &name) != nil || name == "" {
+		v.report(path, line, "", levelError, "missing event name")
+		return
+	}
+	var ts string
+	if json.Unmarshal(fields["timestamp"], &ts) != nil {
+		v.report(path, line, name, levelError, "missing timestamp")
+		return
+	}
+	if _, err := time.Parse(time.RFC3339, ts); err != nil {
+		v.report(path, line, name, levelError, "invalid timestamp: "+err.Error())
+		return
+	}
+
+	*v.mismatch = nil
+	err = v.d.Trigger(name, b)
+	if err != nil {
+		v.report(path, line, name, levelWarning, "unknown event")
+		return
+	}
+	if len(*v.mismatch) > 0 {
+		v.report(path, line, name, levelWarning, "fields not in the event definition: "+strings.Join(*v.mismatch, ", "))
+	}
+}
+
+// unknownFields returns the fields of an event that did not survive decoding
+// into its struct, with * standing for array indexes. Fields the struct adds
+// with their zero value are not reported
+func unknownFields(original, parsed []byte) []string {
+	var a, b interface{}
+	if json.Unmarshal(original, &a) != nil || json.Unmarshal(parsed, &b) != nil {
+		return []string{"?"}
+	}
+
+	seen := make(map[string]bool)
+	missingFields(a, b, "", seen)
+	fields := make([]string, 0, len(seen))
+	for f := range seen {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func missingFields(a, b interface{}, prefix string, seen map[string]bool) {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		bm, _ := b.(map[string]interface{})
+		for k, va := range a {
+			vb, ok := bm[k]
+			if !ok {
+				seen[prefix+k] = true
+				continue
+			}
+			missingFields(va, vb, prefix+k+".", seen)
+		}
+	case []interface{}:
+		ba, _ := b.([]interface{})
+		for i, va := range a {
+			if i < len(ba) {
+				missingFields(va, ba[i], prefix+"*.", seen)
+			}
+		}
+	}
+}